@@ -0,0 +1,364 @@
+package gosql
+
+import "fmt"
+
+// An Ast is the parsed form of a source of one or more statements.
+//
+// Nodes with a Keywords field keep the keyword tokens the parser consumed
+// for them, in source order but not those of the nodes within them, so
+// that FormatOptions.PreserveKeywords can print each as it was written.
+// Operators, literals and types keep theirs as their own tokens.
+type Ast struct {
+	Statements []*Statement
+}
+
+type AstKind uint
+
+const (
+	SelectKind AstKind = iota
+	CreateTableKind
+	InsertKind
+	UpdateKind
+	DeleteKind
+	CreateIndexKind
+	CreateViewKind
+)
+
+var astKindNames = [...]string{
+	SelectKind:      "SELECT",
+	CreateTableKind: "CREATE TABLE",
+	InsertKind:      "INSERT",
+	UpdateKind:      "UPDATE",
+	DeleteKind:      "DELETE",
+	CreateIndexKind: "CREATE INDEX",
+	CreateViewKind:  "CREATE VIEW",
+}
+
+// Kinds print as the statement they are, eg "CREATE TABLE"
+func (k AstKind) String() string {
+	if int(k) < len(astKindNames) {
+		return astKindNames[k]
+	}
+	return fmt.Sprintf("AstKind(%d)", uint(k))
+}
+
+// A Statement holds one statement node, the one given by Kind
+type Statement struct {
+	SelectStatement      *SelectStatement
+	CreateTableStatement *CreateTableStatement
+	InsertStatement      *InsertStatement
+	UpdateStatement      *UpdateStatement
+	DeleteStatement      *DeleteStatement
+	CreateIndexStatement *CreateIndexStatement
+	CreateViewStatement  *CreateViewStatement
+	Kind                 AstKind
+}
+
+type ExpressionKind uint
+
+const (
+	LiteralKind ExpressionKind = iota
+	BinaryKind
+	SubqueryKind
+	CallKind
+	BetweenKind
+	CaseKind
+	CastKind
+	UnaryKind
+	IsNullKind
+	InKind
+	LikeKind
+)
+
+// An Expression holds one expression node, the one given by Kind. A
+// literal is a single numeric, string or identifier token.
+type Expression struct {
+	Literal *Token
+	// The table an identifier literal is qualified with, eg t in t.a
+	Qualifier *Token
+	Binary    *BinaryExpression
+	// A parenthesized SELECT, eg on the right of IN
+	Subquery *SelectStatement
+	Call     *CallExpression
+	Between  *BetweenExpression
+	Case     *CaseExpression
+	Cast     *CastExpression
+	Unary    *UnaryExpression
+	IsNull   *IsNullExpression
+	In       *InExpression
+	Like     *LikeExpression
+	Kind     ExpressionKind
+}
+
+// A LikeExpression tests whether Expression matches Pattern, where % is
+// any run of characters and _ any one, or with Not whether it doesn't
+type LikeExpression struct {
+	Expression *Expression
+	Pattern    *Expression
+	Not        bool
+	Keywords   []*Token
+}
+
+// An InExpression tests whether Expression equals any of Values, or any
+// row of Subquery, or with Not whether it equals none of them
+type InExpression struct {
+	Expression *Expression
+	// Nil when there is a Subquery instead
+	Values   []*Expression
+	Subquery *SelectStatement
+	Not      bool
+	Keywords []*Token
+}
+
+// An IsNullExpression tests whether Expression is NULL, or with Not
+// whether it isn't
+type IsNullExpression struct {
+	Expression *Expression
+	Not        bool
+	Keywords   []*Token
+}
+
+// A UnaryExpression applies the prefix operator Op, NOT or a sign, to
+// Expression
+type UnaryExpression struct {
+	Op         *Token
+	Expression *Expression
+}
+
+// A CastExpression converts Expression to Type, as in a::int
+type CastExpression struct {
+	Expression *Expression
+	Type       *DataType
+}
+
+// A CaseExpression is the Result of the first When whose Condition holds,
+// or Else if none do. A simple CASE has an Operand that each Condition is
+// compared to for equality, a searched CASE doesn't.
+type CaseExpression struct {
+	Operand *Expression
+	Whens   []*WhenClause
+	// Nil without ELSE
+	Else     *Expression
+	Keywords []*Token
+}
+
+type WhenClause struct {
+	Condition *Expression
+	Result    *Expression
+}
+
+// A BetweenExpression tests whether Expression is within Low and High
+// inclusive, or with Not whether it is outside them
+type BetweenExpression struct {
+	Expression *Expression
+	Low        *Expression
+	High       *Expression
+	Not        bool
+	Keywords   []*Token
+}
+
+// A CallExpression calls the function Name with Args, or with * as in
+// count(*)
+type CallExpression struct {
+	Name     *Token
+	Args     []*Expression
+	Asterisk bool
+}
+
+// A BinaryExpression applies the operator Op, a keyword or symbol
+// token, to A and B
+type BinaryExpression struct {
+	A  *Expression
+	B  *Expression
+	Op *Token
+}
+
+// A SelectItem is one projected item, either * or an expression
+type SelectItem struct {
+	Expression *Expression
+	Asterisk   bool
+	// The name given with AS (or without, as in `SELECT a x`), nil if none
+	As       *Token
+	Keywords []*Token
+}
+
+type SelectStatement struct {
+	// Set by SELECT DISTINCT to drop duplicate rows
+	Distinct bool
+	Items    []*SelectItem
+	// Nil without a FROM clause
+	From *TableReference
+	// Nil without a WHERE clause
+	Where *Expression
+	// Empty without a GROUP BY clause
+	GroupBy []*GroupingElement
+	// Nil without a HAVING clause, which may appear without GROUP BY
+	Having *Expression
+	// Empty without an ORDER BY clause
+	OrderBy []*OrderTerm
+	// Integer numeric tokens, nil without a LIMIT or OFFSET clause
+	Limit    *Token
+	Offset   *Token
+	Keywords []*Token
+}
+
+type GroupingKind uint
+
+const (
+	ExpressionGroupingKind GroupingKind = iota
+	RollupGroupingKind
+	CubeGroupingKind
+	SetsGroupingKind
+)
+
+// A GroupingElement is an item of a GROUP BY clause, which stands for
+// the sets of expressions given by Kind:
+//   - an expression, the one set holding it
+//   - ROLLUP (a, b), the sets (a, b), (a) and ()
+//   - CUBE (a, b), every subset: (a, b), (a), (b) and ()
+//   - GROUPING SETS ((a, b), (c), ()), the sets it lists
+//
+// GROUP BY groups rows by each combination of one set from every element.
+type GroupingElement struct {
+	Expression *Expression
+	// The expressions of a ROLLUP or CUBE
+	Expressions []*Expression
+	// The sets of a GROUPING SETS, where () is an empty set
+	Sets     [][]*Expression
+	Kind     GroupingKind
+	Keywords []*Token
+}
+
+type TableKind uint
+
+const (
+	NamedTableKind TableKind = iota
+	JoinTableKind
+	SubqueryTableKind
+)
+
+// A TableReference is a source of rows in a FROM clause, the one given by
+// Kind: a table by name, the join of two others or a parenthesized SELECT
+type TableReference struct {
+	Name     *Token
+	Join     *JoinClause
+	Subquery *SelectStatement
+	// The name given to a named table or subquery with AS or without, nil
+	// if none
+	As *Token
+	// Set by LATERAL before a subquery, which lets it refer to the columns
+	// of the FROM items to its left
+	Lateral  bool
+	Kind     TableKind
+	Keywords []*Token
+}
+
+type JoinKind uint
+
+const (
+	InnerJoinKind JoinKind = iota
+	LeftJoinKind
+	RightJoinKind
+	FullJoinKind
+	CrossJoinKind
+	// The cross join of two items of a FROM list, as in FROM a, b
+	CommaJoinKind
+)
+
+type JoinClause struct {
+	Left  *TableReference
+	Right *TableReference
+	// Nil for cross and comma joins, which pair every row of Left with
+	// every row of Right
+	On       *Expression
+	Kind     JoinKind
+	Keywords []*Token
+}
+
+type ConstraintKind uint
+
+const (
+	PrimaryKeyConstraint ConstraintKind = iota
+	NotNullConstraint
+	UniqueConstraint
+)
+
+// A DataType is a column type keyword, eg int or text, along with any
+// size it is given, as in varchar(255) or numeric(10, 2)
+type DataType struct {
+	Name *Token
+	// Integer numeric tokens, empty without a size
+	Params []*Token
+}
+
+type ColumnDefinition struct {
+	Name     *Token
+	Datatype *DataType
+	// In the order they were declared, each at most once
+	Constraints []ConstraintKind
+	// Nil without a DEFAULT clause
+	Default  *Expression
+	Keywords []*Token
+}
+
+type CreateTableStatement struct {
+	Name     *Token
+	Cols     []*ColumnDefinition
+	Keywords []*Token
+}
+
+type CreateIndexStatement struct {
+	Name  *Token
+	Table *Token
+	// At least one, in the order they were written
+	Cols     []*Token
+	Unique   bool
+	Keywords []*Token
+}
+
+// A CreateViewStatement names Query so it can be selected from like a
+// table
+type CreateViewStatement struct {
+	Name  *Token
+	Query *SelectStatement
+	// Set by CREATE OR REPLACE VIEW to replace the view if it exists
+	OrReplace bool
+	Keywords  []*Token
+}
+
+type InsertStatement struct {
+	Table *Token
+	// One row of values per parenthesized tuple, all of the same length
+	Values   [][]*Expression
+	Keywords []*Token
+}
+
+type UpdateStatement struct {
+	Table *Token
+	// At least one, in the order they were written
+	Set []*Assignment
+	// Nil without a WHERE clause, updating every row
+	Where    *Expression
+	Keywords []*Token
+}
+
+type DeleteStatement struct {
+	Table *Token
+	// Nil without a WHERE clause, deleting every row
+	Where    *Expression
+	Keywords []*Token
+}
+
+// An Assignment sets Column to Value, as in SET a = a + 1
+type Assignment struct {
+	Column *Token
+	Value  *Expression
+}
+
+// An OrderTerm is one expression of an ORDER BY clause
+type OrderTerm struct {
+	Expression *Expression
+	// False for ascending order, the default
+	Desc     bool
+	Keywords []*Token
+}
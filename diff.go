@@ -0,0 +1,106 @@
+package gosql
+
+type DiffKind uint
+
+const (
+	InsertDiff DiffKind = iota
+	DeleteDiff
+	ReplaceDiff
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case InsertDiff:
+		return "insert"
+	case DeleteDiff:
+		return "delete"
+	case ReplaceDiff:
+		return "replace"
+	}
+	return "unknown"
+}
+
+// A TokenDiff is one change between two token streams: the tokens A of
+// the first replaced by the tokens B of the second. A is empty for an
+// insert and B for a delete. AIndex and BIndex are where the change is
+// in each stream, counting only tokens other than whitespace and
+// comments, so an insert's AIndex is the token of the first stream it
+// goes before.
+type TokenDiff struct {
+	Kind   DiffKind
+	A      []*Token
+	B      []*Token
+	AIndex int
+	BIndex int
+}
+
+// DiffTokens aligns two token streams, ignoring whitespace and comments,
+// and returns the changes that turn a into b. Tokens are the same if
+// their values and kinds are, wherever they are. Identical streams give
+// no changes.
+func DiffTokens(a, b []*Token) []TokenDiff {
+	a = significantTokens(a)
+	b = significantTokens(b)
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i].equals(b[j]):
+				common[i][j] = common[i+1][j+1] + 1
+			case common[i+1][j] >= common[i][j+1]:
+				common[i][j] = common[i+1][j]
+			default:
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var diffs []TokenDiff
+	var change *TokenDiff
+	// End the change in progress, if there is one
+	flush := func() {
+		if change == nil {
+			return
+		}
+		switch {
+		case len(change.A) == 0:
+			change.Kind = InsertDiff
+		case len(change.B) == 0:
+			change.Kind = DeleteDiff
+		default:
+			change.Kind = ReplaceDiff
+		}
+		diffs = append(diffs, *change)
+		change = nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i].equals(b[j]) {
+			flush()
+			i++
+			j++
+			continue
+		}
+
+		if change == nil {
+			change = &TokenDiff{AIndex: i, BIndex: j}
+		}
+		if j >= len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]) {
+			change.A = append(change.A, a[i])
+			i++
+		} else {
+			change.B = append(change.B, b[j])
+			j++
+		}
+	}
+	flush()
+
+	return diffs
+}
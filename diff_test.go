@@ -0,0 +1,103 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTokens(t *testing.T) {
+	// Each diff as its kind, indexes and the values on each side
+	type diff struct {
+		kind   DiffKind
+		aIndex int
+		bIndex int
+		a      []string
+		b      []string
+	}
+
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		diffs []diff
+	}{
+		{
+			name:  "identical but for whitespace, comments and case",
+			a:     "SELECT a, b FROM t WHERE a = 1;",
+			b:     "select a,\n  b -- columns\nfrom t where a = 1;",
+			diffs: []diff{},
+		},
+		{
+			name: "added predicate",
+			a:    "SELECT a FROM t WHERE a = 1;",
+			b:    "SELECT a FROM t WHERE a = 1 AND b <> 'x';",
+			diffs: []diff{
+				{kind: InsertDiff, aIndex: 8, bIndex: 8, b: []string{"and", "b", "<>", "x"}},
+			},
+		},
+		{
+			name: "renamed column",
+			a:    "SELECT name, age FROM users WHERE name = 'x';",
+			b:    "SELECT full_name, age FROM users WHERE full_name = 'x';",
+			diffs: []diff{
+				{kind: ReplaceDiff, aIndex: 1, bIndex: 1, a: []string{"name"}, b: []string{"full_name"}},
+				{kind: ReplaceDiff, aIndex: 7, bIndex: 7, a: []string{"name"}, b: []string{"full_name"}},
+			},
+		},
+		{
+			name: "removed column",
+			a:    "SELECT a, b, c FROM t;",
+			b:    "SELECT a, c FROM t;",
+			diffs: []diff{
+				{kind: DeleteDiff, aIndex: 3, bIndex: 3, a: []string{"b", ","}},
+			},
+		},
+		{
+			name: "string and identifier with the same value",
+			a:    "SELECT 'a';",
+			b:    "SELECT a;",
+			diffs: []diff{
+				{kind: ReplaceDiff, aIndex: 1, bIndex: 1, a: []string{"a"}, b: []string{"a"}},
+			},
+		},
+	}
+
+	values := func(tokens []*Token) []string {
+		if len(tokens) == 0 {
+			return nil
+		}
+		values := []string{}
+		for _, token := range tokens {
+			values = append(values, token.Value)
+		}
+		return values
+	}
+
+	for _, test := range tests {
+		a, err := LexWithOptions(test.a, LexOptions{PreserveWhitespace: true, PreserveComments: true})
+		assert.Nil(t, err, test.name)
+		b, err := LexWithOptions(test.b, LexOptions{PreserveWhitespace: true, PreserveComments: true})
+		assert.Nil(t, err, test.name)
+
+		diffs := []diff{}
+		for _, d := range DiffTokens(a, b) {
+			diffs = append(diffs, diff{kind: d.Kind, aIndex: d.AIndex, bIndex: d.BIndex, a: values(d.A), b: values(d.B)})
+		}
+		assert.Equal(t, test.diffs, diffs, test.name)
+	}
+}
+
+// Diffs keep the tokens themselves, so callers can report their locations
+func TestDiffTokens_locations(t *testing.T) {
+	a, err := Lex("SELECT a FROM t;")
+	assert.Nil(t, err)
+	b, err := Lex("SELECT a\nFROM u;")
+	assert.Nil(t, err)
+
+	diffs := DiffTokens(a, b)
+	if assert.Equal(t, 1, len(diffs)) {
+		assert.Equal(t, Location{Line: 0, Col: 14, Offset: 14}, diffs[0].A[0].Loc)
+		assert.Equal(t, Location{Line: 1, Col: 5, Offset: 14}, diffs[0].B[0].Loc)
+	}
+}
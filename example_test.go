@@ -0,0 +1,39 @@
+package gosql_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gosql"
+)
+
+func TestLex_public(t *testing.T) {
+	tokens, err := gosql.Lex("SELECT id FROM users;")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(tokens))
+	assert.Equal(t, string(gosql.SelectKeyword), tokens[0].Value)
+	assert.Equal(t, gosql.KeywordKind, tokens[0].Kind)
+	assert.Equal(t, "users", tokens[3].Value)
+	assert.Equal(t, gosql.IdentifierKind, tokens[3].Kind)
+
+	_, err = gosql.Lex("select ^")
+	var lexErr *gosql.LexError
+	assert.ErrorAs(t, err, &lexErr)
+}
+
+func ExampleLex() {
+	tokens, err := gosql.Lex("select name from users")
+	if err != nil {
+		panic(err)
+	}
+	for _, token := range tokens {
+		fmt.Println(token.Loc.Col, token.Value)
+	}
+	// Output:
+	// 0 select
+	// 7 name
+	// 12 from
+	// 17 users
+}
@@ -0,0 +1,710 @@
+package gosql
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type KeywordCase uint
+
+const (
+	UpperKeywords KeywordCase = iota
+	LowerKeywords
+	// Each keyword as it was written in the source, from the keyword
+	// tokens the AST keeps. Keywords without one, eg the INNER added to a
+	// bare JOIN or those of a hand built AST, are upper cased.
+	PreserveKeywords
+)
+
+// FormatOptions control how an AST is rendered back to SQL. The zero
+// value upper cases keywords and puts each statement on one line.
+type FormatOptions struct {
+	KeywordCase KeywordCase
+	// The line width SELECT statements are wrapped to, or zero to never
+	// wrap them. A statement that doesn't fit starts each clause on a new
+	// line. A clause that still doesn't fit puts its items, joins, or the
+	// operands of the AND or OR it is a chain of, on indented lines of
+	// their own. Subqueries are always on one line.
+	Width int
+	// What wrapped lines are indented by, two spaces if empty
+	Indent string
+}
+
+func (a *Ast) String() string {
+	return a.Format(FormatOptions{})
+}
+
+// Format renders every statement, each ending in a semicolon, one per line
+func (a *Ast) Format(opts FormatOptions) string {
+	statements := make([]string, 0, len(a.Statements))
+	for _, stmt := range a.Statements {
+		statements = append(statements, stmt.Format(opts)+";")
+	}
+	return strings.Join(statements, "\n")
+}
+
+func (s *Statement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *Statement) Format(opts FormatOptions) string {
+	switch s.Kind {
+	case SelectKind:
+		return s.SelectStatement.Format(opts)
+	case CreateTableKind:
+		return s.CreateTableStatement.Format(opts)
+	case InsertKind:
+		return s.InsertStatement.Format(opts)
+	case UpdateKind:
+		return s.UpdateStatement.Format(opts)
+	case DeleteKind:
+		return s.DeleteStatement.Format(opts)
+	case CreateIndexKind:
+		return s.CreateIndexStatement.Format(opts)
+	case CreateViewKind:
+		return s.CreateViewStatement.Format(opts)
+	}
+	return ""
+}
+
+func (s *SelectStatement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *SelectStatement) Format(opts FormatOptions) string {
+	// Nothing within the clauses wraps, subqueries included
+	inline := opts
+	inline.Width = 0
+	clauses := s.clauses(inline)
+
+	lines := make([]string, 0, len(clauses))
+	for _, c := range clauses {
+		lines = append(lines, c.line())
+	}
+	if formatted := strings.Join(lines, " "); opts.fits(formatted) {
+		return formatted
+	}
+
+	lines = lines[:0]
+	for _, c := range clauses {
+		lines = append(lines, c.wrap(opts)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// A clause of a SELECT statement: its keywords and the parts after them,
+// joined by sep. When wrapped, the parts of a list each go on a line of
+// their own after the keywords, and otherwise the first part stays with
+// the keywords and the rest start lines of their own, eg AND b.
+type clause struct {
+	head  string
+	parts []string
+	sep   string
+	list  bool
+}
+
+func (c clause) line() string {
+	if len(c.parts) == 0 {
+		return c.head
+	}
+	return c.head + " " + strings.Join(c.parts, c.sep)
+}
+
+func (c clause) wrap(opts FormatOptions) []string {
+	if line := c.line(); opts.fits(line) || len(c.parts) < 2 {
+		return []string{line}
+	}
+
+	indent := opts.indent()
+	if c.list {
+		lines := []string{c.head}
+		for i, part := range c.parts {
+			if i < len(c.parts)-1 {
+				part += strings.TrimRight(c.sep, " ")
+			}
+			lines = append(lines, indent+part)
+		}
+		return lines
+	}
+
+	lines := []string{c.head + " " + c.parts[0]}
+	for _, part := range c.parts[1:] {
+		lines = append(lines, indent+strings.TrimLeft(c.sep, " ")+part)
+	}
+	return lines
+}
+
+func (s *SelectStatement) clauses(opts FormatOptions) []clause {
+	kw := opts.keywords(s.Keywords)
+	head := kw(SelectKeyword)
+	if s.Distinct {
+		head += " " + kw(DistinctKeyword)
+	}
+	items := make([]string, 0, len(s.Items))
+	for _, item := range s.Items {
+		if item.Asterisk {
+			items = append(items, string(AsteriskSymbol))
+			continue
+		}
+		formatted := item.Expression.Format(opts)
+		if item.As != nil {
+			formatted += " " + opts.keywords(item.Keywords)(AsKeyword) + " " + quoteIdentifier(item.As.Value)
+		}
+		items = append(items, formatted)
+	}
+	clauses := []clause{{head: head, parts: items, sep: ", ", list: true}}
+
+	if s.From != nil {
+		clauses = append(clauses, clause{
+			head:  kw(FromKeyword),
+			parts: fromParts(s.From, opts),
+			sep:   " ",
+		})
+	}
+
+	if s.Where != nil {
+		clauses = append(clauses, predicateClause(kw(WhereKeyword), s.Where, opts))
+	}
+
+	if len(s.GroupBy) > 0 {
+		elements := make([]string, 0, len(s.GroupBy))
+		for _, element := range s.GroupBy {
+			elements = append(elements, element.Format(opts))
+		}
+		head := kw(GroupKeyword) + " " + kw(ByKeyword)
+		clauses = append(clauses, clause{head: head, parts: elements, sep: ", ", list: true})
+	}
+
+	if s.Having != nil {
+		clauses = append(clauses, predicateClause(kw(HavingKeyword), s.Having, opts))
+	}
+
+	if len(s.OrderBy) > 0 {
+		terms := make([]string, 0, len(s.OrderBy))
+		for _, term := range s.OrderBy {
+			formatted := term.Expression.Format(opts)
+			if term.Desc {
+				formatted += " " + opts.keywords(term.Keywords)(DescKeyword)
+			}
+			terms = append(terms, formatted)
+		}
+		head := kw(OrderKeyword) + " " + kw(ByKeyword)
+		clauses = append(clauses, clause{head: head, parts: terms, sep: ", ", list: true})
+	}
+
+	if s.Limit != nil {
+		clauses = append(clauses, clause{head: kw(LimitKeyword), parts: []string{s.Limit.Value}})
+	}
+
+	if s.Offset != nil {
+		clauses = append(clauses, clause{head: kw(OffsetKeyword), parts: []string{s.Offset.Value}})
+	}
+
+	return clauses
+}
+
+// The first table of a FROM clause and then each join. Joins nest on their
+// left, so the first table is the innermost. The part before a comma join
+// ends in the comma.
+func fromParts(t *TableReference, opts FormatOptions) []string {
+	if t.Kind != JoinTableKind {
+		return []string{t.Format(opts)}
+	}
+
+	parts := fromParts(t.Join.Left, opts)
+	if t.Join.Kind == CommaJoinKind {
+		parts[len(parts)-1] += ","
+	}
+	return append(parts, t.Join.Format(opts))
+}
+
+// A WHERE or HAVING clause whose parts are the operands of the chain of
+// ANDs or ORs its predicate is, if it is one
+func predicateClause(head string, e *Expression, opts FormatOptions) clause {
+	if e.Kind == BinaryKind {
+		if op, ok := e.Binary.Op.Keyword(); ok && (op == AndKeyword || op == OrKeyword) {
+			return clause{head: head, parts: chainOperands(e, op, opts), sep: " "}
+		}
+	}
+	return clause{head: head, parts: []string{e.Format(opts)}}
+}
+
+// The operands of a chain of op, each after the first starting with its
+// operator, eg a, AND b and AND c for a AND b AND c
+func chainOperands(e *Expression, op Keyword, opts FormatOptions) []string {
+	b := opts.keywordToken(e.Binary.Op) + " " + e.Binary.formatOperand(e.Binary.B, true, opts)
+	if e.Binary.A.Kind == BinaryKind {
+		if left, _ := e.Binary.A.Binary.Op.Keyword(); left == op {
+			return append(chainOperands(e.Binary.A, op, opts), b)
+		}
+	}
+	return []string{e.Binary.formatOperand(e.Binary.A, false, opts), b}
+}
+
+func (t *TableReference) String() string {
+	return t.Format(FormatOptions{})
+}
+
+func (t *TableReference) Format(opts FormatOptions) string {
+	kw := opts.keywords(t.Keywords)
+	switch t.Kind {
+	case NamedTableKind:
+		formatted := quoteIdentifier(t.Name.Value)
+		if t.As != nil {
+			formatted += " " + kw(AsKeyword) + " " + quoteIdentifier(t.As.Value)
+		}
+		return formatted
+	case SubqueryTableKind:
+		formatted := "(" + t.Subquery.Format(opts) + ")"
+		if t.Lateral {
+			formatted = kw(LateralKeyword) + " " + formatted
+		}
+		if t.As != nil {
+			formatted += " " + kw(AsKeyword) + " " + quoteIdentifier(t.As.Value)
+		}
+		return formatted
+	case JoinTableKind:
+		if t.Join.Kind == CommaJoinKind {
+			return t.Join.Left.Format(opts) + ", " + t.Join.Format(opts)
+		}
+		return t.Join.Left.Format(opts) + " " + t.Join.Format(opts)
+	}
+	return ""
+}
+
+// Format renders the join without its left side, eg INNER JOIN t ON a = b.
+// A comma join is only its right side.
+func (j *JoinClause) Format(opts FormatOptions) string {
+	if j.Kind == CommaJoinKind {
+		return j.Right.Format(opts)
+	}
+
+	kw := opts.keywords(j.Keywords)
+	kind := map[JoinKind]Keyword{
+		InnerJoinKind: InnerKeyword,
+		LeftJoinKind:  LeftKeyword,
+		RightJoinKind: RightKeyword,
+		FullJoinKind:  FullKeyword,
+		CrossJoinKind: CrossKeyword,
+	}[j.Kind]
+
+	formatted := kw(kind) + " " + kw(JoinKeyword) + " " + j.Right.Format(opts)
+	if j.On != nil {
+		formatted += " " + kw(OnKeyword) + " " + j.On.Format(opts)
+	}
+	return formatted
+}
+
+func (g *GroupingElement) String() string {
+	return g.Format(FormatOptions{})
+}
+
+func (g *GroupingElement) Format(opts FormatOptions) string {
+	kw := opts.keywords(g.Keywords)
+	list := func(exps []*Expression) string {
+		formatted := make([]string, 0, len(exps))
+		for _, exp := range exps {
+			formatted = append(formatted, exp.Format(opts))
+		}
+		return "(" + strings.Join(formatted, ", ") + ")"
+	}
+
+	switch g.Kind {
+	case ExpressionGroupingKind:
+		return g.Expression.Format(opts)
+	case RollupGroupingKind:
+		return kw(RollupKeyword) + " " + list(g.Expressions)
+	case CubeGroupingKind:
+		return kw(CubeKeyword) + " " + list(g.Expressions)
+	case SetsGroupingKind:
+		sets := make([]string, 0, len(g.Sets))
+		for _, set := range g.Sets {
+			sets = append(sets, list(set))
+		}
+		return kw(GroupingKeyword) + " " + kw(SetsKeyword) + " (" + strings.Join(sets, ", ") + ")"
+	}
+	return ""
+}
+
+func (s *CreateTableStatement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *CreateTableStatement) Format(opts FormatOptions) string {
+	cols := make([]string, 0, len(s.Cols))
+	for _, col := range s.Cols {
+		colKw := opts.keywords(col.Keywords)
+		formatted := quoteIdentifier(col.Name.Value) + " " + col.Datatype.Format(opts)
+		if col.Default != nil {
+			formatted += " " + colKw(DefaultKeyword) + " " + col.Default.Format(opts)
+		}
+		for _, constraint := range col.Constraints {
+			for _, keyword := range constraintKeywords[constraint] {
+				formatted += " " + colKw(keyword)
+			}
+		}
+		cols = append(cols, formatted)
+	}
+
+	kw := opts.keywords(s.Keywords)
+	return kw(CreateKeyword) + " " + kw(TableKeyword) + " " +
+		quoteIdentifier(s.Name.Value) + " (" + strings.Join(cols, ", ") + ")"
+}
+
+func (s *CreateIndexStatement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *CreateIndexStatement) Format(opts FormatOptions) string {
+	kw := opts.keywords(s.Keywords)
+	cols := make([]string, 0, len(s.Cols))
+	for _, col := range s.Cols {
+		cols = append(cols, quoteIdentifier(col.Value))
+	}
+
+	formatted := kw(CreateKeyword) + " "
+	if s.Unique {
+		formatted += kw(UniqueKeyword) + " "
+	}
+	return formatted + kw(IndexKeyword) + " " + quoteIdentifier(s.Name.Value) + " " +
+		kw(OnKeyword) + " " + quoteIdentifier(s.Table.Value) + " (" + strings.Join(cols, ", ") + ")"
+}
+
+func (s *CreateViewStatement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *CreateViewStatement) Format(opts FormatOptions) string {
+	kw := opts.keywords(s.Keywords)
+	formatted := kw(CreateKeyword) + " "
+	if s.OrReplace {
+		formatted += kw(OrKeyword) + " " + kw(ReplaceKeyword) + " "
+	}
+	return formatted + kw(ViewKeyword) + " " + quoteIdentifier(s.Name.Value) + " " +
+		kw(AsKeyword) + " " + s.Query.Format(opts)
+}
+
+func (t *DataType) String() string {
+	return t.Format(FormatOptions{})
+}
+
+func (t *DataType) Format(opts FormatOptions) string {
+	formatted := opts.keywordToken(t.Name)
+	if len(t.Params) > 0 {
+		params := make([]string, 0, len(t.Params))
+		for _, param := range t.Params {
+			params = append(params, param.Value)
+		}
+		formatted += "(" + strings.Join(params, ", ") + ")"
+	}
+	return formatted
+}
+
+func (s *InsertStatement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *InsertStatement) Format(opts FormatOptions) string {
+	kw := opts.keywords(s.Keywords)
+	rows := make([]string, 0, len(s.Values))
+	for _, row := range s.Values {
+		values := make([]string, 0, len(row))
+		for _, value := range row {
+			values = append(values, value.Format(opts))
+		}
+		rows = append(rows, "("+strings.Join(values, ", ")+")")
+	}
+
+	return kw(InsertKeyword) + " " + kw(IntoKeyword) + " " +
+		quoteIdentifier(s.Table.Value) + " " + kw(ValuesKeyword) +
+		" " + strings.Join(rows, ", ")
+}
+
+func (s *UpdateStatement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *UpdateStatement) Format(opts FormatOptions) string {
+	kw := opts.keywords(s.Keywords)
+	set := make([]string, 0, len(s.Set))
+	for _, assignment := range s.Set {
+		set = append(set, quoteIdentifier(assignment.Column.Value)+" = "+assignment.Value.Format(opts))
+	}
+
+	formatted := kw(UpdateKeyword) + " " + quoteIdentifier(s.Table.Value) + " " +
+		kw(SetKeyword) + " " + strings.Join(set, ", ")
+	if s.Where != nil {
+		formatted += " " + kw(WhereKeyword) + " " + s.Where.Format(opts)
+	}
+	return formatted
+}
+
+func (s *DeleteStatement) String() string {
+	return s.Format(FormatOptions{})
+}
+
+func (s *DeleteStatement) Format(opts FormatOptions) string {
+	kw := opts.keywords(s.Keywords)
+	formatted := kw(DeleteKeyword) + " " + kw(FromKeyword) + " " + quoteIdentifier(s.Table.Value)
+	if s.Where != nil {
+		formatted += " " + kw(WhereKeyword) + " " + s.Where.Format(opts)
+	}
+	return formatted
+}
+
+func (e *Expression) String() string {
+	return e.Format(FormatOptions{})
+}
+
+// Format renders the expression with only the parentheses its operators'
+// precedence requires, so eg ((a)) becomes a
+func (e *Expression) Format(opts FormatOptions) string {
+	switch e.Kind {
+	case LiteralKind:
+		if e.Qualifier != nil {
+			return quoteIdentifier(e.Qualifier.Value) + "." + formatLiteral(e.Literal)
+		}
+		if _, ok := e.Literal.Keyword(); ok {
+			return opts.keywordToken(e.Literal)
+		}
+		return formatLiteral(e.Literal)
+	case SubqueryKind:
+		return "(" + e.Subquery.Format(opts) + ")"
+	case CallKind:
+		if e.Call.Asterisk {
+			return quoteIdentifier(e.Call.Name.Value) + "(*)"
+		}
+		args := make([]string, 0, len(e.Call.Args))
+		for _, arg := range e.Call.Args {
+			args = append(args, arg.Format(opts))
+		}
+		return quoteIdentifier(e.Call.Name.Value) + "(" + strings.Join(args, ", ") + ")"
+	case BinaryKind:
+		a := e.Binary.formatOperand(e.Binary.A, false, opts)
+		b := e.Binary.formatOperand(e.Binary.B, true, opts)
+
+		op := e.Binary.Op.Value
+		if e.Binary.Op.Kind == KeywordKind {
+			op = opts.keywordToken(e.Binary.Op)
+		}
+		return a + " " + op + " " + b
+	case UnaryKind:
+		formatted := e.Unary.Expression.Format(opts)
+		if e.Unary.Op.Kind == SymbolKind {
+			// A sign applies to an operand alone, and two in a row would
+			// lex back as a comment
+			if precedenceOf(e.Unary.Expression) != ^uint(0) || strings.HasPrefix(formatted, "-") || strings.HasPrefix(formatted, "+") {
+				formatted = "(" + formatted + ")"
+			}
+			return e.Unary.Op.Value + formatted
+		}
+		if precedenceOf(e.Unary.Expression) <= notPrecedence {
+			formatted = "(" + formatted + ")"
+		}
+		return opts.keywordToken(e.Unary.Op) + " " + formatted
+	case CaseKind:
+		kw := opts.keywords(e.Case.Keywords)
+		var b strings.Builder
+		b.WriteString(kw(CaseKeyword))
+		if e.Case.Operand != nil {
+			b.WriteString(" " + e.Case.Operand.Format(opts))
+		}
+		for _, when := range e.Case.Whens {
+			b.WriteString(" " + kw(WhenKeyword) + " " + when.Condition.Format(opts) +
+				" " + kw(ThenKeyword) + " " + when.Result.Format(opts))
+		}
+		if e.Case.Else != nil {
+			b.WriteString(" " + kw(ElseKeyword) + " " + e.Case.Else.Format(opts))
+		}
+		b.WriteString(" " + kw(EndKeyword))
+		return b.String()
+	case CastKind:
+		formatted := e.Cast.Expression.Format(opts)
+		if precedenceOf(e.Cast.Expression) != ^uint(0) {
+			formatted = "(" + formatted + ")"
+		}
+		return formatted + string(DoubleColonSymbol) + e.Cast.Type.Format(opts)
+	case IsNullKind:
+		formatted := e.IsNull.Expression.Format(opts)
+		if precedenceOf(e.IsNull.Expression) <= betweenPrecedence {
+			formatted = "(" + formatted + ")"
+		}
+		kw := opts.keywords(e.IsNull.Keywords)
+		formatted += " " + kw(IsKeyword)
+		if e.IsNull.Not {
+			formatted += " " + kw(NotKeyword)
+		}
+		return formatted + " " + kw(NullKeyword)
+	case BetweenKind:
+		operand := func(e *Expression) string {
+			if precedenceOf(e) <= betweenPrecedence {
+				return "(" + e.Format(opts) + ")"
+			}
+			return e.Format(opts)
+		}
+
+		kw := opts.keywords(e.Between.Keywords)
+		formatted := operand(e.Between.Expression) + " "
+		if e.Between.Not {
+			formatted += kw(NotKeyword) + " "
+		}
+		return formatted + kw(BetweenKeyword) + " " + operand(e.Between.Low) + " " +
+			kw(AndKeyword) + " " + operand(e.Between.High)
+	case InKind:
+		formatted := e.In.Expression.Format(opts)
+		if precedenceOf(e.In.Expression) <= betweenPrecedence {
+			formatted = "(" + formatted + ")"
+		}
+		kw := opts.keywords(e.In.Keywords)
+		formatted += " "
+		if e.In.Not {
+			formatted += kw(NotKeyword) + " "
+		}
+		formatted += kw(InKeyword) + " "
+
+		if e.In.Subquery != nil {
+			return formatted + "(" + e.In.Subquery.Format(opts) + ")"
+		}
+		values := make([]string, 0, len(e.In.Values))
+		for _, value := range e.In.Values {
+			values = append(values, value.Format(opts))
+		}
+		return formatted + "(" + strings.Join(values, ", ") + ")"
+	case LikeKind:
+		operand := func(e *Expression) string {
+			if precedenceOf(e) <= betweenPrecedence {
+				return "(" + e.Format(opts) + ")"
+			}
+			return e.Format(opts)
+		}
+
+		kw := opts.keywords(e.Like.Keywords)
+		formatted := operand(e.Like.Expression) + " "
+		if e.Like.Not {
+			formatted += kw(NotKeyword) + " "
+		}
+		return formatted + kw(LikeKeyword) + " " + operand(e.Like.Pattern)
+	}
+	return ""
+}
+
+// Format one side of a binary expression, in parentheses if its operator
+// binds less tightly. Operators of equal precedence apply left to right,
+// so one on the right needs parentheses too.
+func (e *BinaryExpression) formatOperand(operand *Expression, right bool, opts FormatOptions) string {
+	precedence := BinaryOperatorPrecedence[e.Op.Value]
+	formatted := operand.Format(opts)
+	if !right && precedenceOf(operand) < precedence {
+		return "(" + formatted + ")"
+	}
+	if right && precedenceOf(operand) <= precedence && !(operand.Kind == UnaryKind && precedence <= notPrecedence) {
+		return "(" + formatted + ")"
+	}
+	return formatted
+}
+
+// How tightly an expression's operator binds, to decide whether it needs
+// parentheses as the operand of another. Expressions without an operator
+// never do.
+func precedenceOf(e *Expression) uint {
+	switch e.Kind {
+	case BinaryKind:
+		return BinaryOperatorPrecedence[e.Binary.Op.Value]
+	case BetweenKind, IsNullKind, InKind, LikeKind:
+		return betweenPrecedence
+	case UnaryKind:
+		// A sign binds tighter than any operator
+		if e.Unary.Op.Kind == KeywordKind {
+			return notPrecedence
+		}
+	}
+	return ^uint(0)
+}
+
+// Whether a line is short enough to be left unwrapped
+func (o FormatOptions) fits(line string) bool {
+	return o.Width <= 0 || utf8.RuneCountInString(line) <= o.Width
+}
+
+func (o FormatOptions) indent() string {
+	if o.Indent == "" {
+		return "  "
+	}
+	return o.Indent
+}
+
+func (o FormatOptions) keyword(k Keyword) string {
+	if o.KeywordCase == LowerKeywords {
+		return strings.ToLower(string(k))
+	}
+	return k.String()
+}
+
+// A function writing the keywords of a node with the given keyword
+// tokens. With PreserveKeywords each keyword is the first of the tokens
+// for it that hasn't been written yet, so repeats like the WHENs of a CASE
+// are matched in order.
+func (o FormatOptions) keywords(tokens []*Token) func(Keyword) string {
+	if o.KeywordCase != PreserveKeywords {
+		return o.keyword
+	}
+
+	written := make([]bool, len(tokens))
+	return func(k Keyword) string {
+		for i, token := range tokens {
+			if !written[i] && Keyword(token.Value) == k && token.Raw != "" {
+				written[i] = true
+				return token.Raw
+			}
+		}
+		return o.keyword(k)
+	}
+}
+
+// A keyword token held by a node, eg an operator or NULL
+func (o FormatOptions) keywordToken(t *Token) string {
+	if o.KeywordCase == PreserveKeywords && t.Raw != "" {
+		return t.Raw
+	}
+	return o.keyword(Keyword(t.Value))
+}
+
+func formatLiteral(t *Token) string {
+	switch t.Kind {
+	case StringKind:
+		return "'" + strings.ReplaceAll(t.Value, "'", "''") + "'"
+	case IdentifierKind:
+		return quoteIdentifier(t.Value)
+	case BytesKind:
+		return "X'" + t.Value + "'"
+	}
+	return t.Value
+}
+
+// Double quote an identifier unless it would lex back to the same value
+// unquoted: all lower case, starting with a letter and not a keyword
+func quoteIdentifier(value string) string {
+	if !needsQuotes(value) {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+func needsQuotes(value string) bool {
+	if r, _ := utf8.DecodeRuneInString(value); !unicode.IsLetter(r) {
+		return true
+	}
+	for _, r := range value {
+		if !isIdentifierRune(r) || unicode.ToLower(r) != r {
+			return true
+		}
+	}
+
+	for _, keyword := range keywords {
+		if string(keyword) == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAst_String(t *testing.T) {
+	tests := []struct {
+		source    string
+		formatted string
+	}{
+		{
+			source:    "select * from users",
+			formatted: "SELECT * FROM users;",
+		},
+		{
+			source:    "select a x, b as \"Y\", 'it''s' from t where ((a = 1)) and (b = 2 or c = 3)",
+			formatted: "SELECT a AS x, b AS \"Y\", 'it''s' FROM t WHERE a = 1 AND (b = 2 OR c = 3);",
+		},
+		{
+			source:    "select a - (b - c), (a - b) - c, a * (b + c) from t",
+			formatted: "SELECT a - (b - c), a - b - c, a * (b + c) FROM t;",
+		},
+		{
+			source:    "create table \"Users\" (id int, \"select\" text, \"a\"\"b\" bool)",
+			formatted: "CREATE TABLE \"Users\" (id INT, \"select\" TEXT, \"a\"\"b\" BOOL);",
+		},
+		{
+			source:    "insert into users values (1, 'a', 2.5e3, \"1st\");",
+			formatted: "INSERT INTO users VALUES (1, 'a', 2.5e3, \"1st\");",
+		},
+		{
+			source:    "select a from t order by a asc, b desc",
+			formatted: "SELECT a FROM t ORDER BY a, b DESC;",
+		},
+		{
+			source:    "select * from a join b on a.id = b.id left outer join c x on c.id = a.id",
+			formatted: "SELECT * FROM a INNER JOIN b ON a.id = b.id LEFT JOIN c AS x ON c.id = a.id;",
+		},
+		{
+			source:    "select a from t where not (a = 1) and (not b) or not (c or d) and x = (not y)",
+			formatted: "SELECT a FROM t WHERE NOT a = 1 AND NOT b OR NOT (c OR d) AND x = (NOT y);",
+		},
+		{
+			source:    "select -a * b, - (a * b), a - -1, - (-a), +(+1), -(a = b) from t",
+			formatted: "SELECT -a * b, -(a * b), a - -1, -(-a), +(+1), -(a = b) FROM t;",
+		},
+		{
+			source:    "select * from a, b join c on b.id = c.id, lateral (select x from t where x = a.id) as s",
+			formatted: "SELECT * FROM a, b INNER JOIN c ON b.id = c.id, LATERAL (SELECT x FROM t WHERE x = a.id) AS s;",
+		},
+		{
+			source:    "select a, b from t group by c, rollup (a, b), cube (a), grouping sets ((a, b), a, ())",
+			formatted: "SELECT a, b FROM t GROUP BY c, ROLLUP (a, b), CUBE (a), GROUPING SETS ((a, b), (a), ());",
+		},
+		{
+			source:    "select a from t where (a) is null and (b = c) is not null",
+			formatted: "SELECT a FROM t WHERE a IS NULL AND (b = c) IS NOT NULL;",
+		},
+		{
+			source:    "select 1; select 2",
+			formatted: "SELECT 1;\nSELECT 2;",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		statements, err := ParseProgram(tokens)
+		assert.Nil(t, err, test.source)
+		ast := &Ast{Statements: statements}
+		assert.Equal(t, test.formatted, ast.String(), test.source)
+	}
+}
+
+func TestAst_Format_keywordCase(t *testing.T) {
+	tokens, err := Lex("SELECT a AS x FROM t WHERE a = 1 OR b = 2;")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "select a as x from t where a = 1 or b = 2;", ast.Format(FormatOptions{KeywordCase: LowerKeywords}))
+	assert.Equal(t, "SELECT a AS x FROM t WHERE a = 1 OR b = 2;", ast.Format(FormatOptions{KeywordCase: UpperKeywords}))
+}
+
+func TestAst_Format_preserveKeywords(t *testing.T) {
+	tests := []struct {
+		source    string
+		formatted string
+	}{
+		{
+			source:    "Select Distinct a As x From t Where a = 1 and b Is Not Null Or c not between 1 AND 2 order by a Desc limit 1;",
+			formatted: "Select Distinct a As x From t Where a = 1 and b Is Not Null Or c not between 1 AND 2 order by a Desc limit 1;",
+		},
+		{
+			// The INNER and AS the printer adds are upper cased, OUTER and
+			// ASC are dropped
+			source:    "select * from a join b bb On a.id = bb.id Left Outer Join c on true order by a Asc;",
+			formatted: "select * from a INNER join b AS bb On a.id = bb.id Left Join c on true order by a;",
+		},
+		{
+			source:    "select case a When 1 then 'x' WHEN 2 Then 'y' else Null END, a in (1), b Not Like 'x%' from t group by Rollup (a), grouping Sets ((a));",
+			formatted: "select case a When 1 then 'x' WHEN 2 Then 'y' else Null END, a in (1), b Not Like 'x%' from t group by Rollup (a), grouping Sets ((a));",
+		},
+		{
+			// DEFAULT is printed before the constraints but keeps its case
+			source:    "Create Table t (a Int not null Default 1 Primary KEY);",
+			formatted: "Create Table t (a Int Default 1 not null Primary KEY);",
+		},
+		{
+			source:    "create or Replace view v As select a::Text from t; Create unique Index i on t (a); insert Into t values (1); Update t Set a = 1; delete From t where Not a;",
+			formatted: "create or Replace view v As select a::Text from t;\nCreate unique Index i on t (a);\ninsert Into t values (1);\nUpdate t Set a = 1;\ndelete From t where Not a;",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, test.formatted, ast.Format(FormatOptions{KeywordCase: PreserveKeywords}), test.source)
+	}
+
+	// A hand built AST has no keyword tokens to take the case of
+	ast := &Ast{Statements: []*Statement{{
+		Kind: DeleteKind,
+		DeleteStatement: &DeleteStatement{
+			Table: &Token{Value: "t", Kind: IdentifierKind},
+		},
+	}}}
+	assert.Equal(t, "DELETE FROM t;", ast.Format(FormatOptions{KeywordCase: PreserveKeywords}))
+}
+
+func TestAst_Format_width(t *testing.T) {
+	tokens, err := Lex(`select u.id, u.name, count(o.id) as orders, sum(o.total) as spent
+		from users as u inner join orders as o on o.user_id = u.id left join addresses as a on a.user_id = u.id
+		where u.active = 1 and (a.country = 'NZ' or a.country is null) and o.total > 0
+		group by u.id, u.name order by spent desc limit 10;
+		select a from t where b = 1;`)
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		opts      FormatOptions
+		formatted string
+	}{
+		{
+			opts: FormatOptions{},
+			formatted: "SELECT u.id, u.name, count(o.id) AS orders, sum(o.total) AS spent FROM users AS u INNER JOIN orders AS o ON o.user_id = u.id LEFT JOIN addresses AS a ON a.user_id = u.id WHERE u.active = 1 AND (a.country = 'NZ' OR a.country IS NULL) AND o.total > 0 GROUP BY u.id, u.name ORDER BY spent DESC LIMIT 10;\n" +
+				"SELECT a FROM t WHERE b = 1;",
+		},
+		{
+			opts: FormatOptions{Width: 60},
+			formatted: `SELECT
+  u.id,
+  u.name,
+  count(o.id) AS orders,
+  sum(o.total) AS spent
+FROM users AS u
+  INNER JOIN orders AS o ON o.user_id = u.id
+  LEFT JOIN addresses AS a ON a.user_id = u.id
+WHERE u.active = 1
+  AND (a.country = 'NZ' OR a.country IS NULL)
+  AND o.total > 0
+GROUP BY u.id, u.name
+ORDER BY spent DESC
+LIMIT 10;
+SELECT a FROM t WHERE b = 1;`,
+		},
+		{
+			// Only the clauses that don't fit are wrapped
+			opts: FormatOptions{Width: 80, Indent: "\t", KeywordCase: LowerKeywords},
+			formatted: `select u.id, u.name, count(o.id) as orders, sum(o.total) as spent
+from users as u
+	inner join orders as o on o.user_id = u.id
+	left join addresses as a on a.user_id = u.id
+where u.active = 1 and (a.country = 'NZ' or a.country is null) and o.total > 0
+group by u.id, u.name
+order by spent desc
+limit 10;
+select a from t where b = 1;`,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.formatted, ast.Format(test.opts), test.opts.Width)
+	}
+}
+
+// Serializing a parsed query and lexing it again gives the same tokens
+func TestAst_String_roundTrip(t *testing.T) {
+	tests := []string{
+		"SELECT * FROM users;",
+		"SELECT DISTINCT a, b FROM t;",
+		"SELECT '123'::INT, (a + b)::TEXT, a::FLOAT::INT FROM t;",
+		"SELECT CASE WHEN a = 1 THEN 'x' WHEN a = 2 THEN 'y' ELSE 'z' END AS c, CASE a WHEN 1 THEN b END FROM t;",
+		"SELECT a FROM t WHERE a BETWEEN 1 AND b + 2 AND c NOT BETWEEN 'a' AND 'z';",
+		"SELECT a FROM t WHERE (a BETWEEN 1 AND 2) BETWEEN (b = c) AND (d OR e);",
+		"SELECT a FROM t WHERE NOT a = 1 AND NOT (b OR c) AND (NOT d) = e;",
+		"SELECT a FROM t WHERE a IS NULL OR b + 1 IS NOT NULL OR (c = d) IS NULL;",
+		"SELECT count(*), sum(abs(x)) AS total, now(), coalesce(a, 'b') FROM t GROUP BY lower(name);",
+		"SELECT a, b, count(*) FROM t GROUP BY a, ROLLUP (b, c), CUBE (d), GROUPING SETS ((a, b), (c), ());",
+		"SELECT * FROM (SELECT a FROM t) AS sub WHERE sub.a IN (SELECT b FROM u);",
+		"SELECT a FROM t WHERE name LIKE 'a%' AND name NOT LIKE b || '%' OR (a = b) LIKE (c LIKE d);",
+		"SELECT a FROM t WHERE a IN (1, 2, 3) AND b NOT IN ('x') OR (c = d) IN (e) AND f NOT IN (SELECT g FROM u);",
+		"SELECT a, b AS \"B\", 'x y' AS c FROM t WHERE a + b * c = d;",
+		"SELECT (a + b) * c, a || 'it''s' FROM t WHERE a <> 1 AND (b >= 2 OR c != 3);",
+		"CREATE TABLE users (id INT, name TEXT, \"from\" VARCHAR);",
+		"CREATE TABLE users (id INT PRIMARY KEY NOT NULL, email TEXT UNIQUE NOT NULL, name TEXT);",
+		"CREATE TABLE jobs (id INT DEFAULT 0 PRIMARY KEY, status TEXT DEFAULT 'pending' NOT NULL);",
+		"CREATE TABLE prices (code CHAR(3), amount NUMERIC(10, 2), label VARCHAR(255));",
+		"CREATE INDEX users_name ON users (name, \"select\");",
+		"CREATE UNIQUE INDEX \"Index\" ON \"on\" (a);",
+		"CREATE VIEW adults AS SELECT name FROM users WHERE age >= 18;",
+		"CREATE OR REPLACE VIEW \"View\" AS SELECT a, count(*) FROM t GROUP BY a;",
+		"INSERT INTO users VALUES (105, 'George', 1.5);",
+		"INSERT INTO files VALUES (1, X'1F3A', X'');",
+		"INSERT INTO users VALUES (1, 'a'), (2, 'b'), (3, 'c');",
+		"INSERT INTO users VALUES (1, NULL);",
+		"UPDATE users SET a = 1, \"B\" = a + 1 WHERE id = 5;",
+		"UPDATE users SET a = (SELECT max(b) FROM t);",
+		"DELETE FROM users WHERE id = 1 OR name IS NULL;",
+		"DELETE FROM \"Users\";",
+		"SELECT a FROM t WHERE a = 1 LIMIT 10 OFFSET 5;",
+		"SELECT a, b FROM t ORDER BY a DESC, b + 1 LIMIT 1;",
+		"SELECT a, b FROM t WHERE c = 1 GROUP BY a, b HAVING a > 1 ORDER BY a;",
+		"SELECT u.name, s.\"Score\" FROM users AS u LEFT JOIN scores AS s ON u.id = s.id CROSS JOIN c;",
+		"SELECT 1;\nSELECT \"café\" FROM \"Users\";",
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test)
+		assert.Nil(t, err, test)
+		ast, err := Parse(tokens)
+		assert.Nil(t, err, test)
+
+		// The tests' keywords are upper case, as they are printed by default
+		assert.Equal(t, ast.String(), ast.Format(FormatOptions{KeywordCase: PreserveKeywords}), test)
+
+		for _, opts := range []FormatOptions{{KeywordCase: UpperKeywords}, {KeywordCase: LowerKeywords}, {Width: 20}} {
+			formatted := ast.Format(opts)
+			again, err := Lex(formatted)
+			assert.Nil(t, err, formatted)
+
+			assert.Equal(t, len(tokens), len(again), formatted)
+			for i := range again {
+				assert.True(t, tokens[i].equals(again[i]), formatted)
+			}
+		}
+	}
+}
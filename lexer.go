@@ -1,40 +1,314 @@
 package gosql
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Location struct {
-	Line uint
-	Col  uint
+	Line uint `json:"line"`
+	Col  uint `json:"col"`
+	// The number of bytes before the location in the whole source
+	Offset uint `json:"offset"`
 }
 
 type Keyword string
 
 const (
-	SelectKeyword Keyword = "select"
-	FromKeyword   Keyword = "from"
-	AsKeyword     Keyword = "as"
-	TableKeyword  Keyword = "table"
-	CreateKeyword Keyword = "create"
-	InsertKeyword Keyword = "insert"
-	IntoKeyword   Keyword = "into"
-	ValuesKeyword Keyword = "values"
-	IntKeyword    Keyword = "int"
-	TextKeyword   Keyword = "text"
+	SelectKeyword    Keyword = "select"
+	FromKeyword      Keyword = "from"
+	AsKeyword        Keyword = "as"
+	TableKeyword     Keyword = "table"
+	CreateKeyword    Keyword = "create"
+	InsertKeyword    Keyword = "insert"
+	IntoKeyword      Keyword = "into"
+	ValuesKeyword    Keyword = "values"
+	IntKeyword       Keyword = "int"
+	TextKeyword      Keyword = "text"
+	UpdateKeyword    Keyword = "update"
+	DeleteKeyword    Keyword = "delete"
+	SetKeyword       Keyword = "set"
+	LateralKeyword   Keyword = "lateral"
+	WhereKeyword     Keyword = "where"
+	AndKeyword       Keyword = "and"
+	OrKeyword        Keyword = "or"
+	NotKeyword       Keyword = "not"
+	InKeyword        Keyword = "in"
+	IsKeyword        Keyword = "is"
+	LikeKeyword      Keyword = "like"
+	NullKeyword      Keyword = "null"
+	JoinKeyword      Keyword = "join"
+	InnerKeyword     Keyword = "inner"
+	LeftKeyword      Keyword = "left"
+	RightKeyword     Keyword = "right"
+	FullKeyword      Keyword = "full"
+	OuterKeyword     Keyword = "outer"
+	OnKeyword        Keyword = "on"
+	GroupingKeyword  Keyword = "grouping"
+	SetsKeyword      Keyword = "sets"
+	RollupKeyword    Keyword = "rollup"
+	CubeKeyword      Keyword = "cube"
+	FloatKeyword     Keyword = "float"
+	DoubleKeyword    Keyword = "double"
+	RealKeyword      Keyword = "real"
+	BooleanKeyword   Keyword = "boolean"
+	BoolKeyword      Keyword = "bool"
+	VarcharKeyword   Keyword = "varchar"
+	CharKeyword      Keyword = "char"
+	BigintKeyword    Keyword = "bigint"
+	SmallintKeyword  Keyword = "smallint"
+	TimestampKeyword Keyword = "timestamp"
+	ReplaceKeyword   Keyword = "replace"
+	ViewKeyword      Keyword = "view"
+	LimitKeyword     Keyword = "limit"
+	OffsetKeyword    Keyword = "offset"
+	OrderKeyword     Keyword = "order"
+	ByKeyword        Keyword = "by"
+	AscKeyword       Keyword = "asc"
+	DescKeyword      Keyword = "desc"
+	CrossKeyword     Keyword = "cross"
+	GroupKeyword     Keyword = "group"
+	HavingKeyword    Keyword = "having"
+	DistinctKeyword  Keyword = "distinct"
+	BetweenKeyword   Keyword = "between"
+	CaseKeyword      Keyword = "case"
+	WhenKeyword      Keyword = "when"
+	ThenKeyword      Keyword = "then"
+	ElseKeyword      Keyword = "else"
+	EndKeyword       Keyword = "end"
+	PrimaryKeyword   Keyword = "primary"
+	KeyKeyword       Keyword = "key"
+	UniqueKeyword    Keyword = "unique"
+	DefaultKeyword   Keyword = "default"
+	NumericKeyword   Keyword = "numeric"
+	// Not in keywords, so index still names columns. The parser only
+	// expects it after CREATE [UNIQUE].
+	IndexKeyword Keyword = "index"
 )
 
+// Keywords print in upper case, as they're conventionally written
+func (k Keyword) String() string {
+	return strings.ToUpper(string(k))
+}
+
+// Every keyword the lexer recognizes
+var keywords = []Keyword{
+	SelectKeyword,
+	FromKeyword,
+	AsKeyword,
+	TableKeyword,
+	CreateKeyword,
+	InsertKeyword,
+	IntoKeyword,
+	ValuesKeyword,
+	IntKeyword,
+	TextKeyword,
+	UpdateKeyword,
+	DeleteKeyword,
+	SetKeyword,
+	LateralKeyword,
+	WhereKeyword,
+	AndKeyword,
+	OrKeyword,
+	NotKeyword,
+	InKeyword,
+	IsKeyword,
+	LikeKeyword,
+	NullKeyword,
+	JoinKeyword,
+	InnerKeyword,
+	LeftKeyword,
+	RightKeyword,
+	FullKeyword,
+	OuterKeyword,
+	OnKeyword,
+	GroupingKeyword,
+	SetsKeyword,
+	RollupKeyword,
+	CubeKeyword,
+	FloatKeyword,
+	DoubleKeyword,
+	RealKeyword,
+	BooleanKeyword,
+	BoolKeyword,
+	VarcharKeyword,
+	CharKeyword,
+	BigintKeyword,
+	SmallintKeyword,
+	TimestampKeyword,
+	ReplaceKeyword,
+	ViewKeyword,
+	LimitKeyword,
+	OffsetKeyword,
+	OrderKeyword,
+	ByKeyword,
+	AscKeyword,
+	DescKeyword,
+	CrossKeyword,
+	GroupKeyword,
+	HavingKeyword,
+	DistinctKeyword,
+	BetweenKeyword,
+	CaseKeyword,
+	WhenKeyword,
+	ThenKeyword,
+	ElseKeyword,
+	EndKeyword,
+	PrimaryKeyword,
+	KeyKeyword,
+	UniqueKeyword,
+	DefaultKeyword,
+	NumericKeyword,
+}
+
+// Every keyword by its lower case value, to resolve lexed keywords
+var keywordValues = func() map[string]Keyword {
+	values := map[string]Keyword{}
+	for _, k := range keywords {
+		values[string(k)] = k
+	}
+	return values
+}()
+
+var keywordTrie = newTrie(func() []string {
+	var options []string
+	for _, k := range keywords {
+		options = append(options, string(k))
+	}
+	return options
+}())
+
+// Keywords SQL allows as plain identifiers, eg a column named text
+var nonReservedKeywords = map[Keyword]bool{
+	TextKeyword:    true,
+	BoolKeyword:    true,
+	SetsKeyword:    true,
+	ReplaceKeyword: true,
+	ViewKeyword:    true,
+}
+
+// IsReservedKeyword reports whether s, in any case, is a keyword that
+// can't be used as an unquoted identifier
+func IsReservedKeyword(s string) bool {
+	k := Keyword(strings.ToLower(s))
+	for _, keyword := range keywords {
+		if keyword == k {
+			return !nonReservedKeywords[k]
+		}
+	}
+	return false
+}
+
 type Symbol string
 
 const (
-	SemicolonSymbol  Symbol = ";"
-	AsteriskSymbol   Symbol = "*"
-	CommaSymbol      Symbol = ","
-	LeftParenSymbol  Symbol = "("
-	RightParenSymbol Symbol = ")"
+	SemicolonSymbol          Symbol = ";"
+	AsteriskSymbol           Symbol = "*"
+	CommaSymbol              Symbol = ","
+	LeftParenSymbol          Symbol = "("
+	RightParenSymbol         Symbol = ")"
+	EqualsSymbol             Symbol = "="
+	ConcatSymbol             Symbol = "||"
+	PlusSymbol               Symbol = "+"
+	DotSymbol                Symbol = "."
+	MinusSymbol              Symbol = "-"
+	SlashSymbol              Symbol = "/"
+	LessThanSymbol           Symbol = "<"
+	GreaterThanSymbol        Symbol = ">"
+	LessThanOrEqualSymbol    Symbol = "<="
+	GreaterThanOrEqualSymbol Symbol = ">="
+	NotEqualSymbol           Symbol = "<>"
+	BangEqualSymbol          Symbol = "!="
+	DoubleColonSymbol        Symbol = "::"
+	// A range, as in arr[1..3]
+	RangeSymbol      Symbol = ".."
+	AmpersandSymbol  Symbol = "&"
+	PipeSymbol       Symbol = "|"
+	TildeSymbol      Symbol = "~"
+	ShiftLeftSymbol  Symbol = "<<"
+	ShiftRightSymbol Symbol = ">>"
 )
 
+func (s Symbol) String() string {
+	return string(s)
+}
+
+// Every symbol the lexer recognizes
+var symbols = []Symbol{
+	CommaSymbol,
+	LeftParenSymbol,
+	RightParenSymbol,
+	SemicolonSymbol,
+	AsteriskSymbol,
+	EqualsSymbol,
+	ConcatSymbol,
+	PlusSymbol,
+	DotSymbol,
+	MinusSymbol,
+	SlashSymbol,
+	LessThanSymbol,
+	GreaterThanSymbol,
+	LessThanOrEqualSymbol,
+	GreaterThanOrEqualSymbol,
+	NotEqualSymbol,
+	BangEqualSymbol,
+	DoubleColonSymbol,
+	RangeSymbol,
+	AmpersandSymbol,
+	PipeSymbol,
+	TildeSymbol,
+	ShiftLeftSymbol,
+	ShiftRightSymbol,
+}
+
+// This language would be cooler with .map
+var symbolTrie = newTrie(func() []string {
+	var options []string
+	for _, s := range symbols {
+		options = append(options, string(s))
+	}
+	return options
+}())
+
+// Symbols only some dialects have
+const (
+	// MySQL's equality that treats NULLs as equal to each other
+	NullSafeEqualSymbol Symbol = "<=>"
+	// Postgres' JSON field access, giving JSON and text respectively
+	ArrowSymbol       Symbol = "->"
+	DoubleArrowSymbol Symbol = "->>"
+	// Postgres' bitwise exclusive or
+	HashSymbol Symbol = "#"
+	// Postgres' array subscripts, as in a[1]. T-SQL quotes identifiers
+	// with brackets instead.
+	LeftBracketSymbol  Symbol = "["
+	RightBracketSymbol Symbol = "]"
+)
+
+// The symbols each dialect has besides the common ones
+var dialectSymbols = map[Dialect][]Symbol{
+	MySQLDialect:    {NullSafeEqualSymbol},
+	PostgresDialect: {ArrowSymbol, DoubleArrowSymbol, HashSymbol, LeftBracketSymbol, RightBracketSymbol},
+}
+
+// Tries of all the symbols of each dialect in dialectSymbols
+var dialectSymbolTries = func() map[Dialect]*trie {
+	tries := map[Dialect]*trie{}
+	for dialect, extra := range dialectSymbols {
+		var options []string
+		for _, s := range append(append([]Symbol{}, symbols...), extra...) {
+			options = append(options, string(s))
+		}
+		tries[dialect] = newTrie(options)
+	}
+	return tries
+}()
+
 type TokenKind uint
 
 const (
@@ -43,12 +317,75 @@ const (
 	IdentifierKind
 	StringKind
 	NumericKind
+	// Marks the end of input, only emitted when LexOptions.EmitEOF is set
+	EOFKind
+	// A character that could not be lexed, only emitted by lexAll
+	IllegalKind
+	// A run of whitespace, only emitted when LexOptions.PreserveWhitespace
+	// is set
+	WhitespaceKind
+	// A line or block comment, only emitted when LexOptions.PreserveComments
+	// is set
+	CommentKind
+	// A binary string like X'1F3A', whose Value is the hex digits
+	BytesKind
 )
 
+var tokenKindNames = [...]string{
+	KeywordKind:    "Keyword",
+	SymbolKind:     "Symbol",
+	IdentifierKind: "Identifier",
+	StringKind:     "String",
+	NumericKind:    "Numeric",
+	EOFKind:        "EOF",
+	IllegalKind:    "Illegal",
+	WhitespaceKind: "Whitespace",
+	CommentKind:    "Comment",
+	BytesKind:      "Bytes",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return fmt.Sprintf("TokenKind(%d)", uint(k))
+}
+
+// Kinds marshal to their names, eg in JSON {"kind":"Keyword"}
+func (k TokenKind) MarshalText() ([]byte, error) {
+	if int(k) >= len(tokenKindNames) {
+		return nil, fmt.Errorf("Unknown token kind %d", uint(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *TokenKind) UnmarshalText(text []byte) error {
+	for kind, name := range tokenKindNames {
+		if name == string(text) {
+			*k = TokenKind(kind)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown token kind %q", text)
+}
+
 type Token struct {
-	Value string
-	Kind  TokenKind
-	Loc   Location
+	Value string `json:"value"`
+	// The source text of the token as written, where Value may be
+	// normalized (eg lower cased keywords and identifiers)
+	Raw  string    `json:"raw"`
+	Kind TokenKind `json:"kind"`
+	Loc  Location  `json:"loc"`
+	// The comments directly before the token, when LexOptions.AttachComments
+	// is set
+	LeadingComments []string `json:"leadingComments,omitempty"`
+	// The location just past the token's last character
+	EndLoc Location `json:"endLoc"`
+	// The keyword a keyword token is, resolved once when it's lexed
+	keyword Keyword
+	// Why the source can't be lexed, for the IllegalKind tokens lexers
+	// return for malformed source
+	invalid string
 }
 
 type Cursor struct {
@@ -56,139 +393,369 @@ type Cursor struct {
 	Loc     Location
 }
 
+// UnmarshalJSON decodes a token encoded by json.Marshal, resolving its
+// keyword as lexing it would
+func (t *Token) UnmarshalJSON(b []byte) error {
+	// The same fields without this method
+	type token Token
+	if err := json.Unmarshal(b, (*token)(t)); err != nil {
+		return err
+	}
+	if t.Kind == KeywordKind {
+		t.keyword = keywordValues[t.Value]
+	}
+	return nil
+}
+
+// Whether the tokens have the same value and kind, wherever they are
 func (t *Token) equals(other *Token) bool {
 	return t.Value == other.Value && t.Kind == other.Kind
 }
 
+// Whether the tokens are equal and also start at the same location
+func (t *Token) equalsAt(other *Token) bool {
+	return t.equals(other) && t.Loc == other.Loc
+}
+
+// Whether the token is a /* block */ comment rather than a -- line comment
+func (t *Token) IsBlockComment() bool {
+	return t.Kind == CommentKind && strings.HasPrefix(t.Value, "/*")
+}
+
+// Whether the token is an N'national' string rather than a plain one. Only
+// tokens from a Tokenizer, which have their Raw text, can tell.
+func (t *Token) IsNationalString() bool {
+	return t.Kind == StringKind && (strings.HasPrefix(t.Raw, "N") || strings.HasPrefix(t.Raw, "n"))
+}
+
+// A TokenCategory is a coarse grouping of tokens, eg for syntax
+// highlighting
+type TokenCategory uint
+
+const (
+	// EOF and illegal tokens
+	OtherCategory TokenCategory = iota
+	KeywordCategory
+	IdentifierCategory
+	// Strings and numbers
+	LiteralCategory
+	// Symbols that compute something, eg + or <=
+	OperatorCategory
+	// Symbols that only structure a statement, eg ( or ;
+	PunctuationCategory
+	CommentCategory
+	WhitespaceCategory
+)
+
+var tokenCategoryNames = [...]string{
+	OtherCategory:       "Other",
+	KeywordCategory:     "Keyword",
+	IdentifierCategory:  "Identifier",
+	LiteralCategory:     "Literal",
+	OperatorCategory:    "Operator",
+	PunctuationCategory: "Punctuation",
+	CommentCategory:     "Comment",
+	WhitespaceCategory:  "Whitespace",
+}
+
+func (c TokenCategory) String() string {
+	if int(c) < len(tokenCategoryNames) {
+		return tokenCategoryNames[c]
+	}
+	return fmt.Sprintf("TokenCategory(%d)", uint(c))
+}
+
+var punctuationSymbols = map[string]bool{
+	string(SemicolonSymbol):  true,
+	string(CommaSymbol):      true,
+	string(LeftParenSymbol):  true,
+	string(RightParenSymbol): true,
+	string(DotSymbol):        true,
+}
+
+// Keyword returns the token's keyword, or false if it isn't a keyword
+func (t *Token) Keyword() (Keyword, bool) {
+	if t.Kind != KeywordKind {
+		return "", false
+	}
+	// A token that was built rather than lexed may not be resolved
+	if t.keyword == "" {
+		return Keyword(t.Value), true
+	}
+	return t.keyword, true
+}
+
+// Symbol returns the token's symbol, or false if it isn't a symbol
+func (t *Token) Symbol() (Symbol, bool) {
+	if t.Kind != SymbolKind {
+		return "", false
+	}
+	return Symbol(t.Value), true
+}
+
+// Category groups the token by what it is, eg all strings and numbers are
+// literals. Keywords like AND are keywords even though they're operators.
+func (t *Token) Category() TokenCategory {
+	switch t.Kind {
+	case KeywordKind:
+		return KeywordCategory
+	case IdentifierKind:
+		return IdentifierCategory
+	case StringKind, NumericKind, BytesKind:
+		return LiteralCategory
+	case SymbolKind:
+		if punctuationSymbols[t.Value] {
+			return PunctuationCategory
+		}
+		return OperatorCategory
+	case CommentKind:
+		return CommentCategory
+	case WhitespaceKind:
+		return WhitespaceCategory
+	}
+	return OtherCategory
+}
+
+// A LexError reports where in the source lexing failed and why
+type LexError struct {
+	Loc     Location
+	Message string
+}
+
+func (e *LexError) Error() string {
+	return fmt.Sprintf("%s at %d:%d", e.Message, e.Loc.Line, e.Loc.Col)
+}
+
 // A lexer takes a string and a cursor and attempts to
 // parse a token. If successful, returns a new token and
 // a new cursor.
 type lexer func(string, Cursor) (*Token, Cursor, bool)
 
-// Main lexing loop
-func lex(source string) ([]*Token, error) {
-	tokens := []*Token{}
-	cur := Cursor{}
-	lexers := []lexer{lexKeyword, lexSymbol, lexString, lexNumeric, lexIdentifier}
+// The SQL dialect to lex. Dialects differ in how identifiers are quoted:
+// ANSI and Postgres use double quotes, MySQL uses backticks (and double
+// quotes delimit strings), and T-SQL also accepts square brackets.
+type Dialect uint
 
-lex:
-	for cur.Pointer < uint(len(source)) {
-		for _, l := range lexers {
-			if token, newCursor, ok := l(source, cur); ok {
-				cur = newCursor
-				// Omit nil tokens for valid, but empty syntax like newlines
-				if token != nil {
-					tokens = append(tokens, token)
-				}
-				continue lex
+const (
+	ANSIDialect Dialect = iota
+	PostgresDialect
+	MySQLDialect
+	TSQLDialect
+)
+
+// LexOptions configures optional lexer behavior. The zero value lexes
+// exactly like Lex.
+type LexOptions struct {
+	Dialect Dialect
+
+	// Accept a leading + as part of a standalone numeric literal, so the
+	// token's Value is eg "+5". Normally the sign is left to the parser.
+	// A + directly following an operand (an identifier, a literal or a
+	// closing paren) is always the arithmetic operator, so `a+5` still
+	// lexes as three tokens.
+	AllowSignedNumerics bool
+
+	// Append a final EOFKind token located at the end of the source, so
+	// parsers can look ahead without checking for the end of the slice.
+	EmitEOF bool
+
+	// The number of columns a tab advances to, like an editor's tab
+	// stops. Defaults to 1 when zero, so a tab counts as one column.
+	TabWidth uint
+
+	// Emit each run of whitespace as a WhitespaceKind token carrying its
+	// exact text, rather than discarding it
+	PreserveWhitespace bool
+
+	// Emit comments as CommentKind tokens carrying their raw text,
+	// delimiters included, rather than discarding them
+	PreserveComments bool
+
+	// Attach the text of each comment, delimiters included, to the next
+	// token other than whitespace as one of its LeadingComments, instead
+	// of emitting or discarding it. Comments after the last token go on
+	// the EOFKind token when EmitEOF is set, and are otherwise left in the
+	// Tokenizer's TrailingComments.
+	AttachComments bool
+
+	// The longest token, in bytes, to lex before giving up with a LexError
+	// at the token's start. This bounds the work an unterminated string
+	// or comment can cause. Comments count even when discarded, but runs
+	// of whitespace never do. Zero means no limit.
+	MaxTokenLength uint
+}
+
+// Lex the whole source into a slice of tokens using the default options
+func Lex(source string) ([]*Token, error) {
+	return LexWithOptions(source, LexOptions{})
+}
+
+// LexWithOptions lexes the whole source into a slice of tokens, as
+// configured by opts
+func LexWithOptions(source string, opts LexOptions) ([]*Token, error) {
+	return collectTokens(context.Background(), NewTokenizer(source, opts))
+}
+
+// LexContext lexes the whole source like Lex, but gives up with ctx's
+// error once ctx is done. ctx is checked every contextCheckInterval
+// tokens, so a huge source can't hold up a cancelled caller for long.
+func LexContext(ctx context.Context, source string) ([]*Token, error) {
+	return collectTokens(ctx, NewTokenizer(source, LexOptions{}))
+}
+
+// How many tokens are lexed between checks of the context
+const contextCheckInterval = 256
+
+// Drain a tokenizer into a slice of tokens, stopping if ctx is done
+func collectTokens(ctx context.Context, t *Tokenizer) ([]*Token, error) {
+	tokens := []*Token{}
+	for i := 0; ; i++ {
+		if i%contextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
 			}
 		}
-		hint := ""
-		if len(tokens) > 0 {
-			hint = " after " + tokens[len(tokens)-1].Value
+
+		token, err := t.Next()
+		if err == io.EOF {
+			return tokens, nil
+		}
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("Unable to lex token %s at %d:%d", hint, cur.Loc.Line, cur.Loc.Col)
+		tokens = append(tokens, token)
 	}
-	return tokens, nil
 }
 
-// Attempt to lex an identifier: a double-quoted string, or a group of  characters starting
-// with an alphabetical character and possibly containing numbers, underscores, or $. For
-// this toy implementation, only ASCII characters are supported.
+// LexReader lexes the whole of r into a slice of tokens using the default
+// options, reading it in chunks as it goes
+func LexReader(r io.Reader) ([]*Token, error) {
+	return collectTokens(context.Background(), NewReaderTokenizer(r, LexOptions{}))
+}
+
+// Lex the whole source without stopping at the first error. Each
+// character that can't be lexed becomes an IllegalKind token and lexing
+// resumes after it, so every problem is reported in one pass.
+func lexAll(source string) ([]*Token, []*LexError) {
+	tokens := []*Token{}
+	var errs []*LexError
+	t := NewTokenizer(source, LexOptions{})
+	for {
+		token, err := t.Next()
+		if err == io.EOF {
+			return tokens, errs
+		}
+		if lexErr, ok := err.(*LexError); ok {
+			errs = append(errs, lexErr)
+			token = t.skipIllegal()
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// Attempt to lex an identifier: a double-quoted string, or a group of characters starting
+// with a letter and possibly containing letters, digits, underscores, or $. Letters and
+// digits may be any Unicode ones, and the column advances once per rune.
 func lexIdentifier(source string, ic Cursor) (*Token, Cursor, bool) {
+	if ic.Pointer >= uint(len(source)) {
+		return nil, ic, false
+	}
+
 	// Double-quoted identifier
-	if token, newCursor, ok := lexCharacterDelimited(source, ic, '"'); ok {
+	if token, newCursor, ok := lexCharacterDelimited(source, ic, '"', IdentifierKind); ok {
 		return token, newCursor, true
 	}
 
 	cur := ic
 
-	c := source[cur.Pointer]
-	// Must start with an alphabetical character
-	isAlphabetical := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
-	if !isAlphabetical {
+	r, size := utf8.DecodeRuneInString(source[cur.Pointer:])
+	// Must start with a letter
+	if !unicode.IsLetter(r) {
 		return nil, ic, false
 	}
-	cur.Pointer++
+	cur.Pointer += uint(size)
 	cur.Loc.Col++
 
-	value := []byte{c}
-	for ; cur.Pointer < uint(len(source)); cur.Pointer++ {
-		c = source[cur.Pointer]
-		isAlphaNumeric := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c > '0' && c <= '9')
-		if isAlphaNumeric || c == '$' || c == '_' {
-			value = append(value, c)
-			cur.Loc.Col++
-			continue
+	for cur.Pointer < uint(len(source)) {
+		r, size = utf8.DecodeRuneInString(source[cur.Pointer:])
+		if !isIdentifierRune(r) {
+			break
 		}
-		break
+		cur.Pointer += uint(size)
+		cur.Loc.Col++
 	}
 
-	if len(value) == 0 {
-		return nil, ic, false
-	}
+	cur.Loc.Offset = ic.Loc.Offset + (cur.Pointer - ic.Pointer)
+
 	return &Token{
-		Value: strings.ToLower(string(value)),
-		Kind:  IdentifierKind,
-		Loc:   ic.Loc,
+		Value:  strings.ToLower(source[ic.Pointer:cur.Pointer]),
+		Kind:   IdentifierKind,
+		Loc:    ic.Loc,
+		EndLoc: cur.Loc,
 	}, cur, true
 }
 
 func lexKeyword(source string, ic Cursor) (*Token, Cursor, bool) {
-	cur := ic
-	keywords := []Keyword{
-		SelectKeyword,
-		FromKeyword,
-		AsKeyword,
-		TableKeyword,
-		CreateKeyword,
-		InsertKeyword,
-		IntoKeyword,
-		ValuesKeyword,
-		IntKeyword,
-		TextKeyword,
+	if ic.Pointer >= uint(len(source)) {
+		return nil, ic, false
 	}
 
-	var options []string
-	for _, k := range keywords {
-		options = append(options, string(k))
-	}
+	cur := ic
 
-	match := longestMatch(source, ic, options)
+	match := keywordTrie.longestMatch(source, ic)
 	if match == "" {
 		return nil, ic, false
 	}
 
 	cur.Pointer = ic.Pointer + uint(len(match))
 	cur.Loc.Col = ic.Loc.Col + uint(len(match))
+	cur.Loc.Offset = ic.Loc.Offset + uint(len(match))
+
+	// A keyword must end on a word boundary, otherwise it's only the prefix
+	// of an identifier (eg `updated` or `setting`)
+	if r, _ := utf8.DecodeRuneInString(source[cur.Pointer:]); isIdentifierRune(r) {
+		return nil, ic, false
+	}
 
 	return &Token{
-		Value: match,
-		Kind:  KeywordKind,
-		Loc:   ic.Loc,
+		Value:   match,
+		Kind:    KeywordKind,
+		Loc:     ic.Loc,
+		EndLoc:  cur.Loc,
+		keyword: keywordValues[match],
 	}, cur, true
 }
 
 // Attempt to lex a number from the source at the given cursor
 func lexNumeric(source string, ic Cursor) (*Token, Cursor, bool) {
+	if ic.Pointer >= uint(len(source)) {
+		return nil, ic, false
+	}
+
 	cur := ic
 	periodFound := false
 	expMarkerFound := false
+	digitFound := false
 
 	for ; cur.Pointer < uint(len(source)); cur.Pointer++ {
 		c := source[cur.Pointer]
-		cur.Loc.Col++
 
 		isDigit := c >= '0' && c <= '9'
 		isPeriod := c == '.'
 		isExpMarker := c == 'e'
 
+		// A range ends the number before it, so 1..3 isn't 1. then .3
+		if isPeriod && cur.Pointer+1 < uint(len(source)) && source[cur.Pointer+1] == '.' {
+			break
+		}
+
 		// First glyph must be a digit or a period or this isn't a number and we're done
 		if cur.Pointer == ic.Pointer {
 			if !isDigit && !isPeriod {
 				return nil, ic, false
 			}
 			periodFound = isPeriod
+			digitFound = isDigit
 			continue
 		}
 
@@ -203,7 +770,8 @@ func lexNumeric(source string, ic Cursor) (*Token, Cursor, bool) {
 
 		// There can only be one expMarker
 		if isExpMarker {
-			if expMarkerFound {
+			// and must come after at least one digit
+			if expMarkerFound || !digitFound {
 				return nil, ic, false
 			}
 			// No periods allowed after expMarker
@@ -217,7 +785,6 @@ func lexNumeric(source string, ic Cursor) (*Token, Cursor, bool) {
 			cNext := source[cur.Pointer+1]
 			if cNext == '-' || cNext == '+' {
 				cur.Pointer++
-				cur.Loc.Col++
 			}
 
 			continue
@@ -227,158 +794,399 @@ func lexNumeric(source string, ic Cursor) (*Token, Cursor, bool) {
 		if !isDigit {
 			break
 		}
+		digitFound = true
 	}
 
-	// No characters accumulated
-	if cur.Pointer == ic.Pointer {
+	// No digits accumulated, eg a lone period
+	if !digitFound {
 		return nil, ic, false
 	}
 
+	// Numbers never span lines
+	cur.Loc.Col = ic.Loc.Col + (cur.Pointer - ic.Pointer)
+	cur.Loc.Offset = ic.Loc.Offset + (cur.Pointer - ic.Pointer)
+
 	return &Token{
-		Value: source[ic.Pointer:cur.Pointer],
-		Loc:   ic.Loc,
-		Kind:  NumericKind,
+		Value:  source[ic.Pointer:cur.Pointer],
+		Loc:    ic.Loc,
+		EndLoc: cur.Loc,
+		Kind:   NumericKind,
 	}, cur, true
 }
 
+// Attempt to lex a number preceded by a + sign, keeping the sign in the value
+func lexSignedNumeric(source string, ic Cursor) (*Token, Cursor, bool) {
+	if ic.Pointer >= uint(len(source)) || source[ic.Pointer] != '+' {
+		return nil, ic, false
+	}
+
+	cur := ic
+	cur.Pointer++
+	cur.Loc.Col++
+	cur.Loc.Offset++
+
+	token, cur, ok := lexNumeric(source, cur)
+	if !ok {
+		return nil, ic, false
+	}
+	token.Value = "+" + token.Value
+	token.Loc = ic.Loc
+	return token, cur, true
+}
+
+// Whether the last token lexed (nil if none) can be the left operand of a binary operator
+func followsOperand(last *Token) bool {
+	if last == nil {
+		return false
+	}
+	switch last.Kind {
+	case IdentifierKind, StringKind, NumericKind, BytesKind:
+		return true
+	case SymbolKind:
+		symbol, _ := last.Symbol()
+		return symbol == RightParenSymbol
+	}
+	return false
+}
+
+// MySQL quotes identifiers with backticks
+func lexBacktickIdentifier(source string, ic Cursor) (*Token, Cursor, bool) {
+	return lexCharacterDelimited(source, ic, '`', IdentifierKind)
+}
+
+// T-SQL quotes identifiers with square brackets
+func lexBracketIdentifier(source string, ic Cursor) (*Token, Cursor, bool) {
+	return lexEnclosed(source, ic, '[', ']', IdentifierKind)
+}
+
+// MySQL also delimits strings with double quotes
+func lexDoubleQuotedString(source string, ic Cursor) (*Token, Cursor, bool) {
+	return lexCharacterDelimited(source, ic, '"', StringKind)
+}
+
 // Strings start and end with a single apostrophe, and may contain one apostrophe if followed by another to escape it
 func lexString(source string, ic Cursor) (*Token, Cursor, bool) {
-	return lexCharacterDelimited(source, ic, '\'')
+	return lexCharacterDelimited(source, ic, '\'', StringKind)
 }
 
-// Lex a sequence of characters delimited by delimiter.
-// Handles escaping of delimiter by doubling it (eg 'here''s an escaped apostrophe')
-func lexCharacterDelimited(source string, ic Cursor, delimiter byte) (*Token, Cursor, bool) {
-	cur := ic
+// Binary strings are an X followed by a string of hex digits, two per
+// byte, as in X'1F3A'
+func lexBytes(source string, ic Cursor) (*Token, Cursor, bool) {
+	token, cur, ok := lexPrefixedString(source, ic, 'x')
+	if !ok || token.invalid != "" {
+		return token, cur, ok
+	}
 
-	if len(source[cur.Pointer:]) == 0 {
+	for i := 0; i < len(token.Value); i++ {
+		if !isHexDigit(token.Value[i]) {
+			return invalidToken(source, ic, cur, fmt.Sprintf("Invalid hex digit %q in binary string", token.Value[i])), cur, true
+		}
+	}
+	if len(token.Value)%2 != 0 {
+		return invalidToken(source, ic, cur, "Binary string must have an even number of hex digits"), cur, true
+	}
+
+	token.Kind = BytesKind
+	return token, cur, true
+}
+
+// National strings are an N followed by a string, as in N'text'. They lex
+// as ordinary strings.
+func lexNationalString(source string, ic Cursor) (*Token, Cursor, bool) {
+	return lexPrefixedString(source, ic, 'n')
+}
+
+// Lex a string directly preceded by the letter prefix, in either case
+func lexPrefixedString(source string, ic Cursor, prefix byte) (*Token, Cursor, bool) {
+	if ic.Pointer+1 >= uint(len(source)) || (source[ic.Pointer]|0x20) != prefix {
 		return nil, ic, false
 	}
 
-	if source[cur.Pointer] != delimiter {
+	quote := ic
+	quote.Pointer++
+	quote.Loc.Col++
+	quote.Loc.Offset++
+	token, cur, ok := lexString(source, quote)
+	if !ok {
+		return nil, ic, false
+	}
+	token.Loc = ic.Loc
+	if token.invalid != "" {
+		token.Value = source[ic.Pointer:cur.Pointer]
+	}
+	return token, cur, true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// A lexer returns an invalid token for source that can only be its kind
+// of token but is malformed, like X'1G'. Its Value is the malformed
+// source from ic to cur. Rather than trying the other lexers the
+// Tokenizer reports message as a LexError at the token.
+func invalidToken(source string, ic, cur Cursor, message string) *Token {
+	return &Token{
+		Value:   source[ic.Pointer:cur.Pointer],
+		Kind:    IllegalKind,
+		Loc:     ic.Loc,
+		EndLoc:  cur.Loc,
+		invalid: message,
+	}
+}
+
+// Lex a sequence of characters delimited by delimiter as a token of kind.
+// Handles escaping of delimiter by doubling it (eg 'here''s an escaped apostrophe')
+func lexCharacterDelimited(source string, ic Cursor, delimiter byte, kind TokenKind) (*Token, Cursor, bool) {
+	return lexEnclosed(source, ic, delimiter, delimiter, kind)
+}
+
+// Lex a sequence of characters between open and close as a token of
+// kind, where close is escaped by doubling it (eg [a]]b] for T-SQL
+// identifiers). Without a close the token is invalid from open onwards.
+func lexEnclosed(source string, ic Cursor, open, close byte, kind TokenKind) (*Token, Cursor, bool) {
+	if ic.Pointer >= uint(len(source)) || source[ic.Pointer] != open {
 		return nil, ic, false
 	}
 
+	cur := ic
+
 	// Found the starting delimiter, advance and look for the next one
 	cur.Loc.Col++
 	cur.Pointer++
 
+	// The value is sliced straight from the source unless an escaped
+	// delimiter forces a copy without the doubling
+	start := cur.Pointer
 	var value []byte
+	escaped := false
 	for ; cur.Pointer < uint(len(source)); cur.Pointer++ {
 		c := source[cur.Pointer]
 
-		if c == delimiter {
-			if cur.Pointer+1 >= uint(len(source)) || source[cur.Pointer+1] != delimiter {
-				return &Token{
-					Value: string(value),
-					Loc:   ic.Loc,
-					Kind:  StringKind,
-				}, cur, true
+		if c == close {
+			if cur.Pointer+1 >= uint(len(source)) || source[cur.Pointer+1] != close {
+				end := cur.Pointer
+				// Move past the closing delimiter
+				cur.Pointer++
+				cur.Loc.Col++
+				cur.Loc.Offset = ic.Loc.Offset + (cur.Pointer - ic.Pointer)
+
+				token := &Token{
+					Value:  source[start:end],
+					Loc:    ic.Loc,
+					EndLoc: cur.Loc,
+					Kind:   kind,
+				}
+				if escaped {
+					token.Value = string(value)
+				}
+				return token, cur, true
 			}
-			// The delimiter was escaped, add it as a literal and continue
-			value = append(value, delimiter)
-			// Skip the second one
-			cur.Loc.Col++
+			// The delimiter was escaped, keep one of the pair and skip the second
+			if !escaped {
+				escaped = true
+				value = append(value, source[start:cur.Pointer]...)
+			}
+			value = append(value, close)
+			cur.Loc.Col += 2
 			cur.Pointer++
+			continue
 		}
 
-		value = append(value, c)
-		cur.Loc.Col++
+		if escaped {
+			value = append(value, c)
+		}
+		// Strings may span lines
+		if endsLine(source, cur.Pointer) {
+			cur.Loc.Line++
+			cur.Loc.Col = 0
+			continue
+		}
+		if c == '\r' {
+			continue
+		}
+		// Count runes, not the continuation bytes of multi-byte ones
+		if utf8.RuneStart(c) {
+			cur.Loc.Col++
+		}
 	}
 
-	return nil, ic, false
+	if kind == IdentifierKind {
+		return invalidToken(source, ic, cur, "Unterminated quoted identifier"), cur, true
+	}
+	return invalidToken(source, ic, cur, "Unterminated string"), cur, true
 }
 
-// Symbols are elements of a fixed set of strings. Also discards whitespace.
-func lexSymbol(source string, ic Cursor) (*Token, Cursor, bool) {
-	c := source[ic.Pointer]
-	cur := ic
-	cur.Pointer++
-	cur.Loc.Col++
-
-	// Syntax that should be discarded
-	switch c {
-	case '\n':
-		cur.Loc.Line++
-		cur.Loc.Col = 0
-		fallthrough
-	case '\t':
-		fallthrough
-	case ' ':
-		return nil, cur, true
+// The lexers to try, in order, at each position in the source
+func (o LexOptions) lexers() []lexer {
+	// Numbers go before symbols so a leading period isn't lexed as a dot
+	symbols := lexSymbol
+	if t, ok := dialectSymbolTries[o.Dialect]; ok {
+		symbols = func(source string, ic Cursor) (*Token, Cursor, bool) {
+			return lexSymbolIn(t, source, ic)
+		}
 	}
 
-	// Syntax that should be maintained
-	symbols := []Symbol{
-		CommaSymbol,
-		LeftParenSymbol,
-		RightParenSymbol,
-		SemicolonSymbol,
-		AsteriskSymbol,
+	lexers := []lexer{o.lexWhitespace, o.lexComment, lexKeyword, lexNumeric, symbols, lexString, lexBytes, lexNationalString}
+	switch o.Dialect {
+	case MySQLDialect:
+		lexers = append(lexers, lexDoubleQuotedString, lexBacktickIdentifier)
+	case TSQLDialect:
+		lexers = append(lexers, lexBracketIdentifier)
 	}
+	return append(lexers, lexIdentifier)
+}
 
-	// This language would be cooler with .map
-	var options []string
-	for _, s := range symbols {
-		options = append(options, string(s))
+// Whitespace is valid syntax that is discarded unless PreserveWhitespace
+// is set, in which case a run of it becomes one token. Tabs advance the
+// column to the next multiple of TabWidth, like an editor displays them.
+func (o LexOptions) lexWhitespace(source string, ic Cursor) (*Token, Cursor, bool) {
+	if ic.Pointer >= uint(len(source)) {
+		return nil, ic, false
 	}
 
-	// `cur` has been advanced, so use the original `ic` for this
-	match := longestMatch(source, ic, options)
-	// Unknown character
-	if match == "" {
-		return nil, ic, false
+	cur := ic
+	tabWidth := o.TabWidth
+	if tabWidth == 0 {
+		tabWidth = 1
 	}
 
-	cur.Pointer = ic.Pointer + uint(len(match))
-	cur.Loc.Col = ic.Loc.Col + uint(len(match))
+whitespace:
+	for ; cur.Pointer < uint(len(source)); cur.Pointer++ {
+		switch source[cur.Pointer] {
+		case '\n', '\r':
+			// The \r of a \r\n pair leaves the location for the \n to advance
+			if endsLine(source, cur.Pointer) {
+				cur.Loc.Line++
+				cur.Loc.Col = 0
+			}
+		case '\t':
+			cur.Loc.Col = (cur.Loc.Col/tabWidth + 1) * tabWidth
+		case ' ':
+			cur.Loc.Col++
+		default:
+			break whitespace
+		}
+	}
 
+	if cur.Pointer == ic.Pointer {
+		return nil, ic, false
+	}
+	cur.Loc.Offset = ic.Loc.Offset + (cur.Pointer - ic.Pointer)
+	if !o.PreserveWhitespace {
+		return nil, cur, true
+	}
 	return &Token{
-		Value: match,
-		Loc:   ic.Loc,
-		Kind:  SymbolKind,
+		Value:  source[ic.Pointer:cur.Pointer],
+		Kind:   WhitespaceKind,
+		Loc:    ic.Loc,
+		EndLoc: cur.Loc,
 	}, cur, true
 }
 
-// Iterate through a source string starting at the given cursor to find
-// the longest matching substring among the provided options (empty if
-// no match).
-func longestMatch(source string, ic Cursor, options []string) string {
-	var value []byte
-	var match string
-	skip := map[string]bool{}
+// Comments are either -- to the end of the line, or /* a block */ which
+// may span lines. Like whitespace they are discarded unless
+// PreserveComments or AttachComments is set.
+func (o LexOptions) lexComment(source string, ic Cursor) (*Token, Cursor, bool) {
+	if ic.Pointer >= uint(len(source)) {
+		return nil, ic, false
+	}
 
 	cur := ic
 
-	for cur.Pointer < uint(len(source)) {
-		value = append(value, strings.ToLower(string(source[cur.Pointer]))...)
-		cur.Pointer++
-	match:
-		for _, option := range options {
-			if skip[option] {
-				continue match
+	switch {
+	case strings.HasPrefix(source[ic.Pointer:], "--"):
+		for cur.Pointer < uint(len(source)) && source[cur.Pointer] != '\n' && source[cur.Pointer] != '\r' {
+			if utf8.RuneStart(source[cur.Pointer]) {
+				cur.Loc.Col++
+			}
+			cur.Pointer++
+		}
+	case strings.HasPrefix(source[ic.Pointer:], "/*"):
+		cur.Pointer += 2
+		cur.Loc.Col += 2
+		for {
+			if cur.Pointer >= uint(len(source)) {
+				return invalidToken(source, ic, cur, "Unterminated comment"), cur, true
 			}
-			if option == string(value) {
-				skip[option] = true
-				// The original has a check that this value is longer than
-				// any match we've seen so far, but that seems unnecessary
-				// given that we're adding characters one at a time so
-				// any time we find an option it will always be the longest.
-				match = option
-				continue
+			if strings.HasPrefix(source[cur.Pointer:], "*/") {
+				cur.Pointer += 2
+				cur.Loc.Col += 2
+				break
 			}
 
-			sharesPrefix := string(value) == option[:cur.Pointer-ic.Pointer]
-			tooLong := len(value) > len(option)
-			if tooLong || !sharesPrefix {
-				skip[option] = true
+			if endsLine(source, cur.Pointer) {
+				cur.Loc.Line++
+				cur.Loc.Col = 0
+			} else if source[cur.Pointer] != '\r' && utf8.RuneStart(source[cur.Pointer]) {
+				cur.Loc.Col++
 			}
+			cur.Pointer++
 		}
+	default:
+		return nil, ic, false
+	}
+	cur.Loc.Offset = ic.Loc.Offset + (cur.Pointer - ic.Pointer)
 
-		if len(skip) == len(options) {
-			break
-		}
+	if !o.PreserveComments && !o.AttachComments {
+		return nil, cur, true
+	}
+	return &Token{
+		Value:  source[ic.Pointer:cur.Pointer],
+		Kind:   CommentKind,
+		Loc:    ic.Loc,
+		EndLoc: cur.Loc,
+	}, cur, true
+}
+
+// Symbols are elements of a fixed set of strings
+func lexSymbol(source string, ic Cursor) (*Token, Cursor, bool) {
+	return lexSymbolIn(symbolTrie, source, ic)
+}
+
+// Lex the longest of the symbols in symbols at the cursor
+func lexSymbolIn(symbols *trie, source string, ic Cursor) (*Token, Cursor, bool) {
+	if ic.Pointer >= uint(len(source)) {
+		return nil, ic, false
+	}
+
+	cur := ic
+
+	match := symbols.longestMatch(source, ic)
+	// Unknown character
+	if match == "" {
+		return nil, ic, false
+	}
+
+	cur.Pointer = ic.Pointer + uint(len(match))
+	cur.Loc.Col = ic.Loc.Col + uint(len(match))
+	cur.Loc.Offset = ic.Loc.Offset + uint(len(match))
+
+	return &Token{
+		Value:  match,
+		Loc:    ic.Loc,
+		EndLoc: cur.Loc,
+		Kind:   SymbolKind,
+	}, cur, true
+}
+
+// Whether the character at pointer ends a line: a \n or a lone \r. A
+// \r\n pair ends at its \n so that it only counts as one newline.
+func endsLine(source string, pointer uint) bool {
+	switch source[pointer] {
+	case '\n':
+		return true
+	case '\r':
+		return pointer+1 >= uint(len(source)) || source[pointer+1] != '\n'
 	}
+	return false
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
 
-	return match
+// Characters that may follow the first character of an unquoted identifier
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '$' || r == '_'
 }
@@ -1,8 +1,13 @@
 package gosql
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -67,7 +72,7 @@ func TestToken_lexNumeric(t *testing.T) {
 		},
 		{
 			number: false,
-			value:  "1..",
+			value:  "1.2.3",
 		},
 		{
 			number: false,
@@ -77,6 +82,18 @@ func TestToken_lexNumeric(t *testing.T) {
 			number: false,
 			value:  " 1",
 		},
+		{
+			number: false,
+			value:  ".",
+		},
+		{
+			number: false,
+			value:  ".id",
+		},
+		{
+			number: false,
+			value:  ".e5",
+		},
 	}
 
 	for _, test := range tests {
@@ -130,14 +147,42 @@ func TestToken_lexString(t *testing.T) {
 
 	for _, test := range tests {
 		tok, _, ok := lexString(test.value, Cursor{})
+		// An unterminated string lexes as an invalid token
+		ok = ok && tok.Kind == StringKind
 		assert.Equal(t, test.string, ok, test.value)
 		if ok {
 			test.value = strings.TrimSpace(test.value)
-			assert.Equal(t, test.value[1:len(test.value)-1], tok.Value, test.value)
+			unescaped := strings.ReplaceAll(test.value[1:len(test.value)-1], "''", "'")
+			assert.Equal(t, unescaped, tok.Value, test.value)
 		}
 	}
 }
 
+func TestToken_lexEnclosed_escapes(t *testing.T) {
+	tests := []struct {
+		source string
+		open   byte
+		close  byte
+		value  string
+		endLoc Location
+	}{
+		{source: "'it''s'", open: '\'', close: '\'', value: "it's", endLoc: Location{Col: 7, Offset: 7}},
+		{source: "''''", open: '\'', close: '\'', value: "'", endLoc: Location{Col: 4, Offset: 4}},
+		{source: "''", open: '\'', close: '\'', value: "", endLoc: Location{Col: 2, Offset: 2}},
+		{source: "'a''''b' c", open: '\'', close: '\'', value: "a''b", endLoc: Location{Col: 8, Offset: 8}},
+		{source: "'é''\nx'", open: '\'', close: '\'', value: "é'\nx", endLoc: Location{Line: 1, Col: 2, Offset: 8}},
+		{source: `"say ""hi"""`, open: '"', close: '"', value: `say "hi"`, endLoc: Location{Col: 12, Offset: 12}},
+		{source: "[x]]y]", open: '[', close: ']', value: "x]y", endLoc: Location{Col: 6, Offset: 6}},
+	}
+
+	for _, test := range tests {
+		tok, _, ok := lexEnclosed(test.source, Cursor{}, test.open, test.close, StringKind)
+		assert.True(t, ok, test.source)
+		assert.Equal(t, test.value, tok.Value, test.source)
+		assert.Equal(t, test.endLoc, tok.EndLoc, test.source)
+	}
+}
+
 func TestToken_lexSymbol(t *testing.T) {
 	tests := []struct {
 		symbol bool
@@ -151,6 +196,26 @@ func TestToken_lexSymbol(t *testing.T) {
 			symbol: true,
 			value:  "||",
 		},
+		{
+			symbol: true,
+			value:  "<=",
+		},
+		{
+			symbol: true,
+			value:  "<>",
+		},
+		{
+			symbol: true,
+			value:  "!=",
+		},
+		{
+			symbol: true,
+			value:  "/",
+		},
+		{
+			symbol: false,
+			value:  "!",
+		},
 	}
 
 	for _, test := range tests {
@@ -163,6 +228,106 @@ func TestToken_lexSymbol(t *testing.T) {
 	}
 }
 
+func TestLex_operators(t *testing.T) {
+	tokens, err := Lex("a<=b <> c != d-1/2>=e")
+	assert.Nil(t, err)
+	assertTokens(t, []Token{
+		{Value: "a", Kind: IdentifierKind},
+		{Value: string(LessThanOrEqualSymbol), Kind: SymbolKind},
+		{Value: "b", Kind: IdentifierKind},
+		{Value: string(NotEqualSymbol), Kind: SymbolKind},
+		{Value: "c", Kind: IdentifierKind},
+		{Value: string(BangEqualSymbol), Kind: SymbolKind},
+		{Value: "d", Kind: IdentifierKind},
+		{Value: string(MinusSymbol), Kind: SymbolKind},
+		{Value: "1", Kind: NumericKind},
+		{Value: string(SlashSymbol), Kind: SymbolKind},
+		{Value: "2", Kind: NumericKind},
+		{Value: string(GreaterThanOrEqualSymbol), Kind: SymbolKind},
+		{Value: "e", Kind: IdentifierKind},
+	}, tokens, "operators")
+}
+
+func TestLex_cast(t *testing.T) {
+	tokens, err := Lex("'123'::int, a::text")
+	assert.Nil(t, err)
+	assertTokens(t, []Token{
+		{Value: "123", Kind: StringKind},
+		{Value: string(DoubleColonSymbol), Kind: SymbolKind},
+		{Value: string(IntKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "a", Kind: IdentifierKind},
+		{Value: string(DoubleColonSymbol), Kind: SymbolKind},
+		{Value: string(TextKeyword), Kind: KeywordKind},
+	}, tokens, "cast")
+
+	// There are no named parameters, so a single colon isn't a symbol
+	_, err = Lex("a : b")
+	var lexErr *LexError
+	if assert.True(t, errors.As(err, &lexErr)) {
+		assert.Equal(t, Location{Line: 0, Col: 2, Offset: 2}, lexErr.Loc)
+	}
+}
+
+func TestLex_range(t *testing.T) {
+	tests := []struct {
+		input  string
+		tokens []Token
+	}{
+		{
+			input: "1..3",
+			tokens: []Token{
+				{Value: "1", Kind: NumericKind},
+				{Value: string(RangeSymbol), Kind: SymbolKind},
+				{Value: "3", Kind: NumericKind},
+			},
+		},
+		{
+			input: "1.5",
+			tokens: []Token{
+				{Value: "1.5", Kind: NumericKind},
+			},
+		},
+		{
+			input: "1.5..2.",
+			tokens: []Token{
+				{Value: "1.5", Kind: NumericKind},
+				{Value: string(RangeSymbol), Kind: SymbolKind},
+				{Value: "2.", Kind: NumericKind},
+			},
+		},
+		{
+			input: "a..b",
+			tokens: []Token{
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(RangeSymbol), Kind: SymbolKind},
+				{Value: "b", Kind: IdentifierKind},
+			},
+		},
+		{
+			input: "..3",
+			tokens: []Token{
+				{Value: string(RangeSymbol), Kind: SymbolKind},
+				{Value: "3", Kind: NumericKind},
+			},
+		},
+		{
+			input: "1...3",
+			tokens: []Token{
+				{Value: "1", Kind: NumericKind},
+				{Value: string(RangeSymbol), Kind: SymbolKind},
+				{Value: ".3", Kind: NumericKind},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		assertTokens(t, test.tokens, tokens, test.input)
+	}
+}
+
 func TestToken_lexIdentifier(t *testing.T) {
 	tests := []struct {
 		Identifier bool
@@ -204,6 +369,26 @@ func TestToken_lexIdentifier(t *testing.T) {
 			input:      `"userName"`,
 			value:      "userName",
 		},
+		{
+			Identifier: true,
+			input:      "a0",
+			value:      "a0",
+		},
+		{
+			Identifier: true,
+			input:      "café",
+			value:      "café",
+		},
+		{
+			Identifier: true,
+			input:      "Ünïcode_1",
+			value:      "ünïcode_1",
+		},
+		{
+			Identifier: true,
+			input:      "用户名",
+			value:      "用户名",
+		},
 		// false tests
 		{
 			Identifier: false,
@@ -221,10 +406,16 @@ func TestToken_lexIdentifier(t *testing.T) {
 			Identifier: false,
 			input:      " abc",
 		},
+		{
+			Identifier: false,
+			input:      "\xffabc",
+		},
 	}
 
 	for _, test := range tests {
 		tok, _, ok := lexIdentifier(test.input, Cursor{})
+		// An unterminated quoted identifier lexes as an invalid token
+		ok = ok && tok.Kind == IdentifierKind
 		assert.Equal(t, test.Identifier, ok, test.input)
 		if ok {
 			assert.Equal(t, test.value, tok.Value, test.input)
@@ -257,6 +448,10 @@ func TestToken_lexKeyword(t *testing.T) {
 			keyword: true,
 			value:   "into",
 		},
+		{
+			keyword: true,
+			value:   "LATERAL",
+		},
 		// false tests
 		{
 			keyword: false,
@@ -266,6 +461,18 @@ func TestToken_lexKeyword(t *testing.T) {
 			keyword: false,
 			value:   "flubbrety",
 		},
+		{
+			keyword: false,
+			value:   "updated",
+		},
+		{
+			keyword: false,
+			value:   "setting",
+		},
+		{
+			keyword: false,
+			value:   "laterally",
+		},
 	}
 
 	for _, test := range tests {
@@ -288,14 +495,19 @@ func TestLex(t *testing.T) {
 			input: "select a",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(SelectKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 0, Line: 0, Offset: 0},
+					Value:   string(SelectKeyword),
+					Raw:     "select",
+					Kind:    KeywordKind,
+					keyword: SelectKeyword,
+					EndLoc:  Location{Col: 6, Line: 0, Offset: 6},
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: "a",
-					Kind:  IdentifierKind,
+					Loc:    Location{Col: 7, Line: 0, Offset: 7},
+					Value:  "a",
+					Raw:    "a",
+					Kind:   IdentifierKind,
+					EndLoc: Location{Col: 8, Line: 0, Offset: 8},
 				},
 			},
 		},
@@ -303,12 +515,12 @@ func TestLex(t *testing.T) {
 		// 	input: "select true",
 		// 	Tokens: []Token{
 		// 		{
-		// 			Loc:   Location{Col: 0, Line: 0},
+		// 			Loc:   Location{Col: 0, Line: 0, Offset: 0},
 		// 			Value: string(SelectKeyword),
 		// 			Kind:  KeywordKind,
 		// 		},
 		// 		{
-		// 			Loc:   Location{Col: 7, Line: 0},
+		// 			Loc:   Location{Col: 7, Line: 0, Offset: 7},
 		// 			Value: "true",
 		// 			Kind:  BoolKind,
 		// 		},
@@ -318,14 +530,19 @@ func TestLex(t *testing.T) {
 			input: "select 1",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(SelectKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 0, Line: 0, Offset: 0},
+					Value:   string(SelectKeyword),
+					Raw:     "select",
+					Kind:    KeywordKind,
+					keyword: SelectKeyword,
+					EndLoc:  Location{Col: 6, Line: 0, Offset: 6},
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: "1",
-					Kind:  NumericKind,
+					Loc:    Location{Col: 7, Line: 0, Offset: 7},
+					Value:  "1",
+					Raw:    "1",
+					Kind:   NumericKind,
+					EndLoc: Location{Col: 8, Line: 0, Offset: 8},
 				},
 			},
 			err: nil,
@@ -334,27 +551,27 @@ func TestLex(t *testing.T) {
 		// 	input: "select 'foo' || 'bar';",
 		// 	Tokens: []Token{
 		// 		{
-		// 			Loc:   Location{Col: 0, Line: 0},
+		// 			Loc:   Location{Col: 0, Line: 0, Offset: 0},
 		// 			Value: string(SelectKeyword),
 		// 			Kind:  KeywordKind,
 		// 		},
 		// 		{
-		// 			Loc:   Location{Col: 7, Line: 0},
+		// 			Loc:   Location{Col: 7, Line: 0, Offset: 7},
 		// 			Value: "foo",
 		// 			Kind:  StringKind,
 		// 		},
 		// 		{
-		// 			Loc:   Location{Col: 13, Line: 0},
+		// 			Loc:   Location{Col: 13, Line: 0, Offset: 13},
 		// 			Value: string(ConcatSymbol),
 		// 			Kind:  SymbolKind,
 		// 		},
 		// 		{
-		// 			Loc:   Location{Col: 16, Line: 0},
+		// 			Loc:   Location{Col: 16, Line: 0, Offset: 16},
 		// 			Value: "bar",
 		// 			Kind:  StringKind,
 		// 		},
 		// 		{
-		// 			Loc:   Location{Col: 21, Line: 0},
+		// 			Loc:   Location{Col: 21, Line: 0, Offset: 21},
 		// 			Value: string(SemicolonSymbol),
 		// 			Kind:  SymbolKind,
 		// 		},
@@ -365,54 +582,78 @@ func TestLex(t *testing.T) {
 			input: "CREATE TABLE u (id INT, name TEXT)",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(CreateKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 0, Line: 0, Offset: 0},
+					Value:   string(CreateKeyword),
+					Raw:     "CREATE",
+					Kind:    KeywordKind,
+					keyword: CreateKeyword,
+					EndLoc:  Location{Col: 6, Line: 0, Offset: 6},
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: string(TableKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 7, Line: 0, Offset: 7},
+					Value:   string(TableKeyword),
+					Raw:     "TABLE",
+					Kind:    KeywordKind,
+					keyword: TableKeyword,
+					EndLoc:  Location{Col: 12, Line: 0, Offset: 12},
 				},
 				{
-					Loc:   Location{Col: 13, Line: 0},
-					Value: "u",
-					Kind:  IdentifierKind,
+					Loc:    Location{Col: 13, Line: 0, Offset: 13},
+					Value:  "u",
+					Raw:    "u",
+					Kind:   IdentifierKind,
+					EndLoc: Location{Col: 14, Line: 0, Offset: 14},
 				},
 				{
-					Loc:   Location{Col: 15, Line: 0},
-					Value: "(",
-					Kind:  SymbolKind,
+					Loc:    Location{Col: 15, Line: 0, Offset: 15},
+					Value:  "(",
+					Raw:    "(",
+					Kind:   SymbolKind,
+					EndLoc: Location{Col: 16, Line: 0, Offset: 16},
 				},
 				{
-					Loc:   Location{Col: 16, Line: 0},
-					Value: "id",
-					Kind:  IdentifierKind,
+					Loc:    Location{Col: 16, Line: 0, Offset: 16},
+					Value:  "id",
+					Raw:    "id",
+					Kind:   IdentifierKind,
+					EndLoc: Location{Col: 18, Line: 0, Offset: 18},
 				},
 				{
-					Loc:   Location{Col: 19, Line: 0},
-					Value: "int",
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 19, Line: 0, Offset: 19},
+					Value:   "int",
+					Raw:     "INT",
+					Kind:    KeywordKind,
+					keyword: IntKeyword,
+					EndLoc:  Location{Col: 22, Line: 0, Offset: 22},
 				},
 				{
-					Loc:   Location{Col: 22, Line: 0},
-					Value: ",",
-					Kind:  SymbolKind,
+					Loc:    Location{Col: 22, Line: 0, Offset: 22},
+					Value:  ",",
+					Raw:    ",",
+					Kind:   SymbolKind,
+					EndLoc: Location{Col: 23, Line: 0, Offset: 23},
 				},
 				{
-					Loc:   Location{Col: 24, Line: 0},
-					Value: "name",
-					Kind:  IdentifierKind,
+					Loc:    Location{Col: 24, Line: 0, Offset: 24},
+					Value:  "name",
+					Raw:    "name",
+					Kind:   IdentifierKind,
+					EndLoc: Location{Col: 28, Line: 0, Offset: 28},
 				},
 				{
-					Loc:   Location{Col: 29, Line: 0},
-					Value: "text",
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 29, Line: 0, Offset: 29},
+					Value:   "text",
+					Raw:     "TEXT",
+					Kind:    KeywordKind,
+					keyword: TextKeyword,
+					EndLoc:  Location{Col: 33, Line: 0, Offset: 33},
 				},
 				{
-					Loc:   Location{Col: 33, Line: 0},
-					Value: ")",
-					Kind:  SymbolKind,
+					Loc:    Location{Col: 33, Line: 0, Offset: 33},
+					Value:  ")",
+					Raw:    ")",
+					Kind:   SymbolKind,
+					EndLoc: Location{Col: 34, Line: 0, Offset: 34},
 				},
 			},
 		},
@@ -420,49 +661,70 @@ func TestLex(t *testing.T) {
 			input: "insert into users Values (105, 233)",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(InsertKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 0, Line: 0, Offset: 0},
+					Value:   string(InsertKeyword),
+					Raw:     "insert",
+					Kind:    KeywordKind,
+					keyword: InsertKeyword,
+					EndLoc:  Location{Col: 6, Line: 0, Offset: 6},
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: string(IntoKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 7, Line: 0, Offset: 7},
+					Value:   string(IntoKeyword),
+					Raw:     "into",
+					Kind:    KeywordKind,
+					keyword: IntoKeyword,
+					EndLoc:  Location{Col: 11, Line: 0, Offset: 11},
 				},
 				{
-					Loc:   Location{Col: 12, Line: 0},
-					Value: "users",
-					Kind:  IdentifierKind,
+					Loc:    Location{Col: 12, Line: 0, Offset: 12},
+					Value:  "users",
+					Raw:    "users",
+					Kind:   IdentifierKind,
+					EndLoc: Location{Col: 17, Line: 0, Offset: 17},
 				},
 				{
-					Loc:   Location{Col: 18, Line: 0},
-					Value: string(ValuesKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 18, Line: 0, Offset: 18},
+					Value:   string(ValuesKeyword),
+					Raw:     "Values",
+					Kind:    KeywordKind,
+					keyword: ValuesKeyword,
+					EndLoc:  Location{Col: 24, Line: 0, Offset: 24},
 				},
 				{
-					Loc:   Location{Col: 25, Line: 0},
-					Value: "(",
-					Kind:  SymbolKind,
+					Loc:    Location{Col: 25, Line: 0, Offset: 25},
+					Value:  "(",
+					Raw:    "(",
+					Kind:   SymbolKind,
+					EndLoc: Location{Col: 26, Line: 0, Offset: 26},
 				},
 				{
-					Loc:   Location{Col: 26, Line: 0},
-					Value: "105",
-					Kind:  NumericKind,
+					Loc:    Location{Col: 26, Line: 0, Offset: 26},
+					Value:  "105",
+					Raw:    "105",
+					Kind:   NumericKind,
+					EndLoc: Location{Col: 29, Line: 0, Offset: 29},
 				},
 				{
-					Loc:   Location{Col: 30, Line: 0},
-					Value: ",",
-					Kind:  SymbolKind,
+					Loc:    Location{Col: 29, Line: 0, Offset: 29},
+					Value:  ",",
+					Raw:    ",",
+					Kind:   SymbolKind,
+					EndLoc: Location{Col: 30, Line: 0, Offset: 30},
 				},
 				{
-					Loc:   Location{Col: 32, Line: 0},
-					Value: "233",
-					Kind:  NumericKind,
+					Loc:    Location{Col: 31, Line: 0, Offset: 31},
+					Value:  "233",
+					Raw:    "233",
+					Kind:   NumericKind,
+					EndLoc: Location{Col: 34, Line: 0, Offset: 34},
 				},
 				{
-					Loc:   Location{Col: 36, Line: 0},
-					Value: ")",
-					Kind:  SymbolKind,
+					Loc:    Location{Col: 34, Line: 0, Offset: 34},
+					Value:  ")",
+					Raw:    ")",
+					Kind:   SymbolKind,
+					EndLoc: Location{Col: 35, Line: 0, Offset: 35},
 				},
 			},
 			err: nil,
@@ -471,29 +733,41 @@ func TestLex(t *testing.T) {
 			input: "SELECT id FROM users;",
 			Tokens: []Token{
 				{
-					Loc:   Location{Col: 0, Line: 0},
-					Value: string(SelectKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 0, Line: 0, Offset: 0},
+					Value:   string(SelectKeyword),
+					Raw:     "SELECT",
+					Kind:    KeywordKind,
+					keyword: SelectKeyword,
+					EndLoc:  Location{Col: 6, Line: 0, Offset: 6},
 				},
 				{
-					Loc:   Location{Col: 7, Line: 0},
-					Value: "id",
-					Kind:  IdentifierKind,
+					Loc:    Location{Col: 7, Line: 0, Offset: 7},
+					Value:  "id",
+					Raw:    "id",
+					Kind:   IdentifierKind,
+					EndLoc: Location{Col: 9, Line: 0, Offset: 9},
 				},
 				{
-					Loc:   Location{Col: 10, Line: 0},
-					Value: string(FromKeyword),
-					Kind:  KeywordKind,
+					Loc:     Location{Col: 10, Line: 0, Offset: 10},
+					Value:   string(FromKeyword),
+					Raw:     "FROM",
+					Kind:    KeywordKind,
+					keyword: FromKeyword,
+					EndLoc:  Location{Col: 14, Line: 0, Offset: 14},
 				},
 				{
-					Loc:   Location{Col: 15, Line: 0},
-					Value: "users",
-					Kind:  IdentifierKind,
+					Loc:    Location{Col: 15, Line: 0, Offset: 15},
+					Value:  "users",
+					Raw:    "users",
+					Kind:   IdentifierKind,
+					EndLoc: Location{Col: 20, Line: 0, Offset: 20},
 				},
 				{
-					Loc:   Location{Col: 20, Line: 0},
-					Value: ";",
-					Kind:  SymbolKind,
+					Loc:    Location{Col: 20, Line: 0, Offset: 20},
+					Value:  ";",
+					Raw:    ";",
+					Kind:   SymbolKind,
+					EndLoc: Location{Col: 21, Line: 0, Offset: 21},
 				},
 			},
 			err: nil,
@@ -501,7 +775,7 @@ func TestLex(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		tokens, err := lex(test.input)
+		tokens, err := Lex(test.input)
 		assert.Equal(t, test.err, err, test.input)
 		assert.Equal(t, len(test.Tokens), len(tokens), test.input)
 
@@ -510,3 +784,1865 @@ func TestLex(t *testing.T) {
 		}
 	}
 }
+
+func TestLex_dml(t *testing.T) {
+	tests := []struct {
+		input  string
+		Tokens []Token
+	}{
+		{
+			input: "UPDATE t SET a = 1",
+			Tokens: []Token{
+				{Value: string(UpdateKeyword), Kind: KeywordKind},
+				{Value: "t", Kind: IdentifierKind},
+				{Value: string(SetKeyword), Kind: KeywordKind},
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(EqualsSymbol), Kind: SymbolKind},
+				{Value: "1", Kind: NumericKind},
+			},
+		},
+		{
+			input: "DELETE FROM t;",
+			Tokens: []Token{
+				{Value: string(DeleteKeyword), Kind: KeywordKind},
+				{Value: string(FromKeyword), Kind: KeywordKind},
+				{Value: "t", Kind: IdentifierKind},
+				{Value: string(SemicolonSymbol), Kind: SymbolKind},
+			},
+		},
+		{
+			input: "update updated set setting = 2",
+			Tokens: []Token{
+				{Value: string(UpdateKeyword), Kind: KeywordKind},
+				{Value: "updated", Kind: IdentifierKind},
+				{Value: string(SetKeyword), Kind: KeywordKind},
+				{Value: "setting", Kind: IdentifierKind},
+				{Value: string(EqualsSymbol), Kind: SymbolKind},
+				{Value: "2", Kind: NumericKind},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		assertTokens(t, test.Tokens, tokens, test.input)
+	}
+}
+
+// Compare only the value and kind of each token, ignoring locations
+func assertTokens(t *testing.T, expected []Token, actual []*Token, msg string) {
+	assert.Equal(t, len(expected), len(actual), msg)
+	for i := 0; i < len(expected) && i < len(actual); i++ {
+		assert.Equal(t, expected[i].Value, actual[i].Value, msg)
+		assert.Equal(t, expected[i].Kind, actual[i].Kind, msg)
+	}
+}
+
+func TestLex_signedNumerics(t *testing.T) {
+	tests := []struct {
+		input   string
+		options LexOptions
+		Tokens  []Token
+	}{
+		{
+			input:   "+5",
+			options: LexOptions{AllowSignedNumerics: true},
+			Tokens: []Token{
+				{Value: "+5", Kind: NumericKind},
+			},
+		},
+		{
+			input: "+5",
+			Tokens: []Token{
+				{Value: string(PlusSymbol), Kind: SymbolKind},
+				{Value: "5", Kind: NumericKind},
+			},
+		},
+		{
+			input:   "select +1.5e3, (+2)",
+			options: LexOptions{AllowSignedNumerics: true},
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "+1.5e3", Kind: NumericKind},
+				{Value: string(CommaSymbol), Kind: SymbolKind},
+				{Value: string(LeftParenSymbol), Kind: SymbolKind},
+				{Value: "+2", Kind: NumericKind},
+				{Value: string(RightParenSymbol), Kind: SymbolKind},
+			},
+		},
+		{
+			input:   "a+5",
+			options: LexOptions{AllowSignedNumerics: true},
+			Tokens: []Token{
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(PlusSymbol), Kind: SymbolKind},
+				{Value: "5", Kind: NumericKind},
+			},
+		},
+		{
+			input: "a+5",
+			Tokens: []Token{
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(PlusSymbol), Kind: SymbolKind},
+				{Value: "5", Kind: NumericKind},
+			},
+		},
+		{
+			input:   "(1)+5",
+			options: LexOptions{AllowSignedNumerics: true},
+			Tokens: []Token{
+				{Value: string(LeftParenSymbol), Kind: SymbolKind},
+				{Value: "1", Kind: NumericKind},
+				{Value: string(RightParenSymbol), Kind: SymbolKind},
+				{Value: string(PlusSymbol), Kind: SymbolKind},
+				{Value: "5", Kind: NumericKind},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, test.options)
+		assert.Nil(t, err, test.input)
+		assertTokens(t, test.Tokens, tokens, test.input)
+	}
+}
+
+func TestLex_predicates(t *testing.T) {
+	tests := []struct {
+		input  string
+		Tokens []Token
+	}{
+		{
+			input: "WHERE a = 1 AND b IS NOT NULL",
+			Tokens: []Token{
+				{Value: string(WhereKeyword), Kind: KeywordKind},
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(EqualsSymbol), Kind: SymbolKind},
+				{Value: "1", Kind: NumericKind},
+				{Value: string(AndKeyword), Kind: KeywordKind},
+				{Value: "b", Kind: IdentifierKind},
+				{Value: string(IsKeyword), Kind: KeywordKind},
+				{Value: string(NotKeyword), Kind: KeywordKind},
+				{Value: string(NullKeyword), Kind: KeywordKind},
+			},
+		},
+		{
+			input: "where ordinal or instruction in (isbn) and nothing like 'x'",
+			Tokens: []Token{
+				{Value: string(WhereKeyword), Kind: KeywordKind},
+				{Value: "ordinal", Kind: IdentifierKind},
+				{Value: string(OrKeyword), Kind: KeywordKind},
+				{Value: "instruction", Kind: IdentifierKind},
+				{Value: string(InKeyword), Kind: KeywordKind},
+				{Value: string(LeftParenSymbol), Kind: SymbolKind},
+				{Value: "isbn", Kind: IdentifierKind},
+				{Value: string(RightParenSymbol), Kind: SymbolKind},
+				{Value: string(AndKeyword), Kind: KeywordKind},
+				{Value: "nothing", Kind: IdentifierKind},
+				{Value: string(LikeKeyword), Kind: KeywordKind},
+				{Value: "x", Kind: StringKind},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		assertTokens(t, test.Tokens, tokens, test.input)
+	}
+}
+
+func TestLex_joins(t *testing.T) {
+	tests := []struct {
+		input  string
+		Tokens []Token
+	}{
+		{
+			input: "a LEFT OUTER JOIN b ON a.id = b.id",
+			Tokens: []Token{
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(LeftKeyword), Kind: KeywordKind},
+				{Value: string(OuterKeyword), Kind: KeywordKind},
+				{Value: string(JoinKeyword), Kind: KeywordKind},
+				{Value: "b", Kind: IdentifierKind},
+				{Value: string(OnKeyword), Kind: KeywordKind},
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(DotSymbol), Kind: SymbolKind},
+				{Value: "id", Kind: IdentifierKind},
+				{Value: string(EqualsSymbol), Kind: SymbolKind},
+				{Value: "b", Kind: IdentifierKind},
+				{Value: string(DotSymbol), Kind: SymbolKind},
+				{Value: "id", Kind: IdentifierKind},
+			},
+		},
+		{
+			input: "inner join joined on onfield",
+			Tokens: []Token{
+				{Value: string(InnerKeyword), Kind: KeywordKind},
+				{Value: string(JoinKeyword), Kind: KeywordKind},
+				{Value: "joined", Kind: IdentifierKind},
+				{Value: string(OnKeyword), Kind: KeywordKind},
+				{Value: "onfield", Kind: IdentifierKind},
+			},
+		},
+		{
+			input: "select .5, t.a",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: ".5", Kind: NumericKind},
+				{Value: string(CommaSymbol), Kind: SymbolKind},
+				{Value: "t", Kind: IdentifierKind},
+				{Value: string(DotSymbol), Kind: SymbolKind},
+				{Value: "a", Kind: IdentifierKind},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		assertTokens(t, test.Tokens, tokens, test.input)
+	}
+}
+
+func TestLex_groupingSets(t *testing.T) {
+	input := "rollup (a, b) grouping sets (cube) cubes"
+	expected := []Token{
+		{Value: string(RollupKeyword), Kind: KeywordKind},
+		{Value: string(LeftParenSymbol), Kind: SymbolKind},
+		{Value: "a", Kind: IdentifierKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "b", Kind: IdentifierKind},
+		{Value: string(RightParenSymbol), Kind: SymbolKind},
+		{Value: string(GroupingKeyword), Kind: KeywordKind},
+		{Value: string(SetsKeyword), Kind: KeywordKind},
+		{Value: string(LeftParenSymbol), Kind: SymbolKind},
+		{Value: string(CubeKeyword), Kind: KeywordKind},
+		{Value: string(RightParenSymbol), Kind: SymbolKind},
+		{Value: "cubes", Kind: IdentifierKind},
+	}
+
+	tokens, err := Lex(input)
+	assert.Nil(t, err, input)
+	assertTokens(t, expected, tokens, input)
+}
+
+func TestLex_types(t *testing.T) {
+	input := "CREATE TABLE t (a FLOAT, b DOUBLE, c REAL, d BOOLEAN, e BOOL, f VARCHAR(255), g CHAR(1), h BIGINT, i SMALLINT, j TIMESTAMP, character INT)"
+	expected := []Token{
+		{Value: string(CreateKeyword), Kind: KeywordKind},
+		{Value: string(TableKeyword), Kind: KeywordKind},
+		{Value: "t", Kind: IdentifierKind},
+		{Value: string(LeftParenSymbol), Kind: SymbolKind},
+		{Value: "a", Kind: IdentifierKind},
+		{Value: string(FloatKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "b", Kind: IdentifierKind},
+		{Value: string(DoubleKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "c", Kind: IdentifierKind},
+		{Value: string(RealKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "d", Kind: IdentifierKind},
+		{Value: string(BooleanKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "e", Kind: IdentifierKind},
+		{Value: string(BoolKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "f", Kind: IdentifierKind},
+		{Value: string(VarcharKeyword), Kind: KeywordKind},
+		{Value: string(LeftParenSymbol), Kind: SymbolKind},
+		{Value: "255", Kind: NumericKind},
+		{Value: string(RightParenSymbol), Kind: SymbolKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "g", Kind: IdentifierKind},
+		{Value: string(CharKeyword), Kind: KeywordKind},
+		{Value: string(LeftParenSymbol), Kind: SymbolKind},
+		{Value: "1", Kind: NumericKind},
+		{Value: string(RightParenSymbol), Kind: SymbolKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "h", Kind: IdentifierKind},
+		{Value: string(BigintKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "i", Kind: IdentifierKind},
+		{Value: string(SmallintKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "j", Kind: IdentifierKind},
+		{Value: string(TimestampKeyword), Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+		{Value: "character", Kind: IdentifierKind},
+		{Value: string(IntKeyword), Kind: KeywordKind},
+		{Value: string(RightParenSymbol), Kind: SymbolKind},
+	}
+
+	tokens, err := Lex(input)
+	assert.Nil(t, err, input)
+	assertTokens(t, expected, tokens, input)
+}
+
+func TestLex_eof(t *testing.T) {
+	tests := []struct {
+		input   string
+		options LexOptions
+		Tokens  []Token
+	}{
+		{
+			input:   "select a\n  ",
+			options: LexOptions{EmitEOF: true},
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: "a", Raw: "a", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 7, Offset: 7}, EndLoc: Location{Line: 0, Col: 8, Offset: 8}},
+				{Kind: EOFKind, Loc: Location{Line: 1, Col: 2, Offset: 11}, EndLoc: Location{Line: 1, Col: 2, Offset: 11}},
+			},
+		},
+		{
+			input:   "",
+			options: LexOptions{EmitEOF: true},
+			Tokens: []Token{
+				{Kind: EOFKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 0, Offset: 0}},
+			},
+		},
+		{
+			input: "select a",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: "a", Raw: "a", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 7, Offset: 7}, EndLoc: Location{Line: 0, Col: 8, Offset: 8}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, test.options)
+		assert.Nil(t, err, test.input)
+		assert.Equal(t, len(test.Tokens), len(tokens), test.input)
+		for i, tok := range tokens {
+			assert.Equal(t, &test.Tokens[i], tok, test.input)
+		}
+	}
+}
+
+func TestLex_bytes(t *testing.T) {
+	tests := []struct {
+		input  string
+		Tokens []Token
+		err    string
+		loc    Location
+	}{
+		{
+			input: "x'1f3A' X''",
+			Tokens: []Token{
+				{Value: "1f3A", Raw: "x'1f3A'", Kind: BytesKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 7, Offset: 7}},
+				{Value: "", Raw: "X''", Kind: BytesKind, Loc: Location{Line: 0, Col: 8, Offset: 8}, EndLoc: Location{Line: 0, Col: 11, Offset: 11}},
+			},
+		},
+		{
+			// Only directly followed by a quote
+			input: "x 'a'",
+			Tokens: []Token{
+				{Value: "x", Raw: "x", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 1, Offset: 1}},
+				{Value: "a", Raw: "'a'", Kind: StringKind, Loc: Location{Line: 0, Col: 2, Offset: 2}, EndLoc: Location{Line: 0, Col: 5, Offset: 5}},
+			},
+		},
+		{
+			input: "select X'1F3'",
+			err:   "Binary string must have an even number of hex digits",
+			loc:   Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			input: "select\n  X'1G'",
+			err:   "Invalid hex digit 'G' in binary string",
+			loc:   Location{Line: 1, Col: 2, Offset: 9},
+		},
+		{
+			input: "X'1F''3A'",
+			err:   "Invalid hex digit '\\'' in binary string",
+			loc:   Location{Line: 0, Col: 0, Offset: 0},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		if test.err != "" {
+			var lexErr *LexError
+			if assert.True(t, errors.As(err, &lexErr), test.input) {
+				assert.Equal(t, test.err, lexErr.Message, test.input)
+				assert.Equal(t, test.loc, lexErr.Loc, test.input)
+			}
+			continue
+		}
+
+		assert.Nil(t, err, test.input)
+		assert.Equal(t, len(test.Tokens), len(tokens), test.input)
+		for i, tok := range tokens {
+			assert.Equal(t, &test.Tokens[i], tok, test.input)
+		}
+	}
+}
+
+func TestLex_nationalString(t *testing.T) {
+	tokens, err := Lex("N'hi', n'it''s', 'plain', N, n 'x'")
+	assert.Nil(t, err)
+	expected := []Token{
+		{Value: "hi", Raw: "N'hi'", Kind: StringKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 5, Offset: 5}},
+		{Value: ",", Raw: ",", Kind: SymbolKind, Loc: Location{Line: 0, Col: 5, Offset: 5}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}},
+		{Value: "it's", Raw: "n'it''s'", Kind: StringKind, Loc: Location{Line: 0, Col: 7, Offset: 7}, EndLoc: Location{Line: 0, Col: 15, Offset: 15}},
+		{Value: ",", Raw: ",", Kind: SymbolKind, Loc: Location{Line: 0, Col: 15, Offset: 15}, EndLoc: Location{Line: 0, Col: 16, Offset: 16}},
+		{Value: "plain", Raw: "'plain'", Kind: StringKind, Loc: Location{Line: 0, Col: 17, Offset: 17}, EndLoc: Location{Line: 0, Col: 24, Offset: 24}},
+		{Value: ",", Raw: ",", Kind: SymbolKind, Loc: Location{Line: 0, Col: 24, Offset: 24}, EndLoc: Location{Line: 0, Col: 25, Offset: 25}},
+		// Not directly followed by a string, N is an identifier
+		{Value: "n", Raw: "N", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 26, Offset: 26}, EndLoc: Location{Line: 0, Col: 27, Offset: 27}},
+		{Value: ",", Raw: ",", Kind: SymbolKind, Loc: Location{Line: 0, Col: 27, Offset: 27}, EndLoc: Location{Line: 0, Col: 28, Offset: 28}},
+		{Value: "n", Raw: "n", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 29, Offset: 29}, EndLoc: Location{Line: 0, Col: 30, Offset: 30}},
+		{Value: "x", Raw: "'x'", Kind: StringKind, Loc: Location{Line: 0, Col: 31, Offset: 31}, EndLoc: Location{Line: 0, Col: 34, Offset: 34}},
+	}
+	assert.Equal(t, len(expected), len(tokens))
+	for i, tok := range tokens {
+		assert.Equal(t, &expected[i], tok)
+	}
+
+	assert.True(t, tokens[0].IsNationalString())
+	assert.True(t, tokens[2].IsNationalString())
+	assert.False(t, tokens[4].IsNationalString())
+	assert.False(t, tokens[6].IsNationalString())
+	assert.False(t, tokens[9].IsNationalString())
+}
+
+func TestLex_endLoc(t *testing.T) {
+	tests := []struct {
+		input  string
+		Tokens []Token
+	}{
+		{
+			input: "  select",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 2, Offset: 2}, EndLoc: Location{Line: 0, Col: 8, Offset: 8}, keyword: SelectKeyword},
+			},
+		},
+		{
+			input: "'first\nsecond' x",
+			Tokens: []Token{
+				{Value: "first\nsecond", Raw: "'first\nsecond'", Kind: StringKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 1, Col: 7, Offset: 14}},
+				{Value: "x", Raw: "x", Kind: IdentifierKind, Loc: Location{Line: 1, Col: 8, Offset: 15}, EndLoc: Location{Line: 1, Col: 9, Offset: 16}},
+			},
+		},
+		{
+			input: "1.5e-3, 2",
+			Tokens: []Token{
+				{Value: "1.5e-3", Raw: "1.5e-3", Kind: NumericKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}},
+				{Value: ",", Raw: ",", Kind: SymbolKind, Loc: Location{Line: 0, Col: 6, Offset: 6}, EndLoc: Location{Line: 0, Col: 7, Offset: 7}},
+				{Value: "2", Raw: "2", Kind: NumericKind, Loc: Location{Line: 0, Col: 8, Offset: 8}, EndLoc: Location{Line: 0, Col: 9, Offset: 9}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		assert.Equal(t, len(test.Tokens), len(tokens), test.input)
+		for i, tok := range tokens {
+			assert.Equal(t, &test.Tokens[i], tok, test.input)
+		}
+	}
+}
+
+func TestLex_createOrReplace(t *testing.T) {
+	input := "CREATE OR REPLACE VIEW v AS SELECT a FROM viewers"
+	expected := []Token{
+		{Value: string(CreateKeyword), Kind: KeywordKind},
+		{Value: string(OrKeyword), Kind: KeywordKind},
+		{Value: string(ReplaceKeyword), Kind: KeywordKind},
+		{Value: string(ViewKeyword), Kind: KeywordKind},
+		{Value: "v", Kind: IdentifierKind},
+		{Value: string(AsKeyword), Kind: KeywordKind},
+		{Value: string(SelectKeyword), Kind: KeywordKind},
+		{Value: "a", Kind: IdentifierKind},
+		{Value: string(FromKeyword), Kind: KeywordKind},
+		{Value: "viewers", Kind: IdentifierKind},
+	}
+
+	tokens, err := Lex(input)
+	assert.Nil(t, err, input)
+	assertTokens(t, expected, tokens, input)
+}
+
+// A context that is cancelled after its Err has been checked a number of
+// times
+type countdownContext struct {
+	context.Context
+	checks int
+}
+
+func (c *countdownContext) Err() error {
+	if c.checks == 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+func TestLexContext(t *testing.T) {
+	source := strings.Repeat("select a, 'b' from t;\n", 1000)
+
+	expected, err := Lex(source)
+	assert.Nil(t, err)
+	tokens, err := LexContext(context.Background(), source)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, tokens)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tokens, err = LexContext(ctx, source)
+	assert.Nil(t, tokens)
+	assert.Equal(t, context.Canceled, err)
+
+	// Cancelled part way through
+	countdown := &countdownContext{Context: context.Background(), checks: 3}
+	tokens, err = LexContext(countdown, source)
+	assert.Nil(t, tokens)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, countdown.checks)
+}
+
+// Every lexer can be called at or past the end of the source, and finds
+// nothing there
+func TestLexers_endOfSource(t *testing.T) {
+	opts := LexOptions{PreserveWhitespace: true, PreserveComments: true}
+	lexers := map[string]lexer{
+		"lexIdentifier":         lexIdentifier,
+		"lexKeyword":            lexKeyword,
+		"lexNumeric":            lexNumeric,
+		"lexSignedNumeric":      lexSignedNumeric,
+		"lexBacktickIdentifier": lexBacktickIdentifier,
+		"lexBracketIdentifier":  lexBracketIdentifier,
+		"lexDoubleQuotedString": lexDoubleQuotedString,
+		"lexString":             lexString,
+		"lexSymbol":             lexSymbol,
+		"lexWhitespace":         opts.lexWhitespace,
+		"lexComment":            opts.lexComment,
+	}
+
+	for _, source := range []string{"", "select 'a'", "1 + ab"} {
+		for name, lex := range lexers {
+			for _, pointer := range []uint{uint(len(source)), uint(len(source)) + 1} {
+				ic := Cursor{Pointer: pointer, Loc: Location{Col: pointer}}
+				assert.NotPanics(t, func() {
+					token, cur, ok := lex(source, ic)
+					assert.False(t, ok, name)
+					assert.Nil(t, token, name)
+					assert.Equal(t, ic, cur, name)
+				}, "%s at %d of %q", name, pointer, source)
+			}
+		}
+	}
+}
+
+// Input with nothing to lex gives no tokens rather than an error
+func TestLex_empty(t *testing.T) {
+	tests := []string{
+		"",
+		" ",
+		"   \n\t",
+		"\r\n\r",
+		"-- only a comment",
+		"/* only a comment */\n",
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test)
+		assert.Nil(t, err, test)
+		assert.Equal(t, []*Token{}, tokens, test)
+
+		tokens, err = LexReader(strings.NewReader(test))
+		assert.Nil(t, err, test)
+		assert.Equal(t, []*Token{}, tokens, test)
+
+		tokens, errs := lexAll(test)
+		assert.Nil(t, errs, test)
+		assert.Equal(t, []*Token{}, tokens, test)
+	}
+}
+
+// Lexers return malformed source as an IllegalKind token whose Value is
+// the source, with the message kept apart
+func TestLex_invalidTokens(t *testing.T) {
+	tests := []struct {
+		lexer   lexer
+		source  string
+		value   string
+		message string
+	}{
+		{lexer: lexBytes, source: "X'1G' a", value: "X'1G'", message: "Invalid hex digit 'G' in binary string"},
+		{lexer: lexBytes, source: "x'abc'", value: "x'abc'", message: "Binary string must have an even number of hex digits"},
+		{lexer: lexBytes, source: "X'1", value: "X'1", message: "Unterminated string"},
+		{lexer: lexString, source: "'it''s", value: "'it''s", message: "Unterminated string"},
+		{lexer: lexIdentifier, source: `"a b`, value: `"a b`, message: "Unterminated quoted identifier"},
+		{lexer: LexOptions{}.lexComment, source: "/* a", value: "/* a", message: "Unterminated comment"},
+	}
+
+	for _, test := range tests {
+		tok, _, ok := test.lexer(test.source, Cursor{})
+		if assert.True(t, ok, test.source) {
+			assert.Equal(t, IllegalKind, tok.Kind, test.source)
+			assert.Equal(t, test.value, tok.Value, test.source)
+			assert.Equal(t, test.message, tok.invalid, test.source)
+		}
+	}
+}
+
+func TestLex_error(t *testing.T) {
+	tests := []struct {
+		input   string
+		Loc     Location
+		message string
+	}{
+		{
+			input:   "^",
+			Loc:     Location{Line: 0, Col: 0, Offset: 0},
+			message: "Unable to lex token at 0:0",
+		},
+		{
+			input:   "select a,\n  b ^ c",
+			Loc:     Location{Line: 1, Col: 4, Offset: 14},
+			message: "Unable to lex token after b at 1:4",
+		},
+		{
+			input:   "select 'unterminated",
+			Loc:     Location{Line: 0, Col: 7, Offset: 7},
+			message: "Unterminated string at 0:7",
+		},
+		{
+			// At the start of the string, not where lexing stopped
+			input:   "select 1,\n  'unterminated\nstring",
+			Loc:     Location{Line: 1, Col: 2, Offset: 12},
+			message: "Unterminated string at 1:2",
+		},
+		{
+			input:   "select \"a\n\"\"",
+			Loc:     Location{Line: 0, Col: 7, Offset: 7},
+			message: "Unterminated quoted identifier at 0:7",
+		},
+		{
+			input:   "select 1 /* unterminated\n comment",
+			Loc:     Location{Line: 0, Col: 9, Offset: 9},
+			message: "Unterminated comment at 0:9",
+		},
+		{
+			input:   "select 1ee4",
+			Loc:     Location{Line: 0, Col: 7, Offset: 7},
+			message: "Unable to lex token after select at 0:7",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, tokens, test.input)
+
+		var lexErr *LexError
+		assert.True(t, errors.As(err, &lexErr), test.input)
+		assert.Equal(t, test.Loc, lexErr.Loc, test.input)
+		assert.Equal(t, test.message, err.Error(), test.input)
+	}
+}
+
+func TestLexAll(t *testing.T) {
+	tests := []struct {
+		input  string
+		Tokens []Token
+		errs   []Location
+	}{
+		{
+			input: "select a",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "a", Kind: IdentifierKind},
+			},
+		},
+		{
+			input: "select ^a, @\n  b ?",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "^", Kind: IllegalKind},
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(CommaSymbol), Kind: SymbolKind},
+				{Value: "@", Kind: IllegalKind},
+				{Value: "b", Kind: IdentifierKind},
+				{Value: "?", Kind: IllegalKind},
+			},
+			errs: []Location{
+				{Line: 0, Col: 7, Offset: 7},
+				{Line: 0, Col: 11, Offset: 11},
+				{Line: 1, Col: 4, Offset: 17},
+			},
+		},
+		{
+			input: "^^",
+			Tokens: []Token{
+				{Value: "^", Kind: IllegalKind},
+				{Value: "^", Kind: IllegalKind},
+			},
+			errs: []Location{
+				{Line: 0, Col: 0, Offset: 0},
+				{Line: 0, Col: 1, Offset: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, errs := lexAll(test.input)
+		assertTokens(t, test.Tokens, tokens, test.input)
+		assert.Equal(t, len(test.errs), len(errs), test.input)
+		for i, err := range errs {
+			assert.Equal(t, test.errs[i], err.Loc, test.input)
+		}
+	}
+
+	// Tokens after an error keep accurate locations
+	tokens, _ := lexAll("^ select")
+	assert.Equal(t, Location{Line: 0, Col: 2, Offset: 2}, tokens[1].Loc)
+}
+
+func TestLex_unicode(t *testing.T) {
+	input := "select café, 用户名 from 'naïve' x"
+	expected := []Token{
+		{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+		{Value: "café", Raw: "café", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 7, Offset: 7}, EndLoc: Location{Line: 0, Col: 11, Offset: 12}},
+		{Value: ",", Raw: ",", Kind: SymbolKind, Loc: Location{Line: 0, Col: 11, Offset: 12}, EndLoc: Location{Line: 0, Col: 12, Offset: 13}},
+		{Value: "用户名", Raw: "用户名", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 13, Offset: 14}, EndLoc: Location{Line: 0, Col: 16, Offset: 23}},
+		{Value: string(FromKeyword), Raw: "from", Kind: KeywordKind, Loc: Location{Line: 0, Col: 17, Offset: 24}, EndLoc: Location{Line: 0, Col: 21, Offset: 28}, keyword: FromKeyword},
+		{Value: "naïve", Raw: "'naïve'", Kind: StringKind, Loc: Location{Line: 0, Col: 22, Offset: 29}, EndLoc: Location{Line: 0, Col: 29, Offset: 37}},
+		{Value: "x", Raw: "x", Kind: IdentifierKind, Loc: Location{Line: 0, Col: 30, Offset: 38}, EndLoc: Location{Line: 0, Col: 31, Offset: 39}},
+	}
+
+	tokens, err := Lex(input)
+	assert.Nil(t, err, input)
+	assert.Equal(t, len(expected), len(tokens), input)
+	for i, tok := range tokens {
+		assert.Equal(t, &expected[i], tok, input)
+	}
+
+	// A keyword followed by a non-ASCII letter is an identifier
+	tokens, err = Lex("selecté")
+	assert.Nil(t, err)
+	assertTokens(t, []Token{{Value: "selecté", Kind: IdentifierKind}}, tokens, "selecté")
+
+	_, err = Lex("select a\xff")
+	assert.Equal(t, "Invalid UTF-8 encoding after a at 0:8", err.Error())
+}
+
+// Offsets count bytes from the start of the whole source, so each token's
+// source text is between its Loc and EndLoc offsets
+func TestLex_offsets(t *testing.T) {
+	source := "select 'naïve',\r\n\t用户 /* é */ from t\n-- ü\nwhere a <> \"b\"\"c\";"
+	raw := []string{"select", "'naïve'", ",", "用户", "from", "t", "where", "a", "<>", `"b""c"`, ";"}
+
+	for _, lex := range []func(string) ([]*Token, error){
+		Lex,
+		func(source string) ([]*Token, error) {
+			return LexReader(iotest.OneByteReader(strings.NewReader(source)))
+		},
+	} {
+		tokens, err := lex(source)
+		assert.Nil(t, err)
+		if !assert.Equal(t, len(raw), len(tokens)) {
+			continue
+		}
+		for i, token := range tokens {
+			assert.Equal(t, raw[i], source[token.Loc.Offset:token.EndLoc.Offset], token.Value)
+		}
+		// After a newline and multi-byte characters
+		assert.Equal(t, Location{Line: 1, Col: 1, Offset: 19}, tokens[3].Loc)
+		assert.Equal(t, Location{Line: 1, Col: 3, Offset: 25}, tokens[3].EndLoc)
+		assert.Equal(t, Location{Line: 3, Col: 0, Offset: 48}, tokens[6].Loc)
+	}
+}
+
+func TestLex_tabWidth(t *testing.T) {
+	tests := []struct {
+		input    string
+		tabWidth uint
+		Loc      Location
+	}{
+		{
+			input: "\tselect",
+			Loc:   Location{Line: 0, Col: 1, Offset: 1},
+		},
+		{
+			input:    "\tselect",
+			tabWidth: 1,
+			Loc:      Location{Line: 0, Col: 1, Offset: 1},
+		},
+		{
+			input:    "\tselect",
+			tabWidth: 4,
+			Loc:      Location{Line: 0, Col: 4, Offset: 1},
+		},
+		{
+			input:    "\t\tselect",
+			tabWidth: 8,
+			Loc:      Location{Line: 0, Col: 16, Offset: 2},
+		},
+		{
+			// Tabs advance to the next tab stop, not by a fixed amount
+			input:    "ab\tselect",
+			tabWidth: 4,
+			Loc:      Location{Line: 0, Col: 4, Offset: 3},
+		},
+		{
+			input:    "\n  \tselect",
+			tabWidth: 4,
+			Loc:      Location{Line: 1, Col: 4, Offset: 4},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, LexOptions{TabWidth: test.tabWidth})
+		assert.Nil(t, err, test.input)
+		last := tokens[len(tokens)-1]
+		assert.Equal(t, string(SelectKeyword), last.Value, test.input)
+		assert.Equal(t, test.Loc, last.Loc, test.input)
+	}
+}
+
+func TestLex_lineEndings(t *testing.T) {
+	tests := []struct {
+		input string
+		Loc   Location
+	}{
+		{
+			input: "a\nb",
+			Loc:   Location{Line: 1, Col: 0, Offset: 2},
+		},
+		{
+			input: "a\r\nb",
+			Loc:   Location{Line: 1, Col: 0, Offset: 3},
+		},
+		{
+			input: "a\rb",
+			Loc:   Location{Line: 1, Col: 0, Offset: 2},
+		},
+		{
+			input: "a\r\n\r\n  b",
+			Loc:   Location{Line: 2, Col: 2, Offset: 7},
+		},
+		{
+			input: "a\n\rb",
+			Loc:   Location{Line: 2, Col: 0, Offset: 3},
+		},
+		{
+			input: "a 'x\r\ny' b",
+			Loc:   Location{Line: 1, Col: 3, Offset: 9},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		last := tokens[len(tokens)-1]
+		assert.Equal(t, test.Loc, last.Loc, test.input)
+	}
+}
+
+func TestLexWithOptions_dialects(t *testing.T) {
+	tests := []struct {
+		input   string
+		dialect Dialect
+		Tokens  []Token
+		err     bool
+	}{
+		{
+			input:   `select "a b" from t`,
+			dialect: ANSIDialect,
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "a b", Kind: IdentifierKind},
+				{Value: string(FromKeyword), Kind: KeywordKind},
+				{Value: "t", Kind: IdentifierKind},
+			},
+		},
+		{
+			input:   "select `a b` from t",
+			dialect: ANSIDialect,
+			err:     true,
+		},
+		{
+			input:   `select "a b" from t`,
+			dialect: PostgresDialect,
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "a b", Kind: IdentifierKind},
+				{Value: string(FromKeyword), Kind: KeywordKind},
+				{Value: "t", Kind: IdentifierKind},
+			},
+		},
+		{
+			input:   "select `a b`, \"c\" from t",
+			dialect: MySQLDialect,
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "a b", Kind: IdentifierKind},
+				{Value: string(CommaSymbol), Kind: SymbolKind},
+				{Value: "c", Kind: StringKind},
+				{Value: string(FromKeyword), Kind: KeywordKind},
+				{Value: "t", Kind: IdentifierKind},
+			},
+		},
+		{
+			input:   `select [a b], "c", [x]]y] from t`,
+			dialect: TSQLDialect,
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "a b", Kind: IdentifierKind},
+				{Value: string(CommaSymbol), Kind: SymbolKind},
+				{Value: "c", Kind: IdentifierKind},
+				{Value: string(CommaSymbol), Kind: SymbolKind},
+				{Value: "x]y", Kind: IdentifierKind},
+				{Value: string(FromKeyword), Kind: KeywordKind},
+				{Value: "t", Kind: IdentifierKind},
+			},
+		},
+		{
+			input:   `select [a b] from t`,
+			dialect: MySQLDialect,
+			err:     true,
+		},
+		{
+			// Brackets are array subscripts in Postgres
+			input:   `select a[1..2], "b"[c] from t`,
+			dialect: PostgresDialect,
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Kind: KeywordKind},
+				{Value: "a", Kind: IdentifierKind},
+				{Value: string(LeftBracketSymbol), Kind: SymbolKind},
+				{Value: "1", Kind: NumericKind},
+				{Value: string(RangeSymbol), Kind: SymbolKind},
+				{Value: "2", Kind: NumericKind},
+				{Value: string(RightBracketSymbol), Kind: SymbolKind},
+				{Value: string(CommaSymbol), Kind: SymbolKind},
+				{Value: "b", Kind: IdentifierKind},
+				{Value: string(LeftBracketSymbol), Kind: SymbolKind},
+				{Value: "c", Kind: IdentifierKind},
+				{Value: string(RightBracketSymbol), Kind: SymbolKind},
+				{Value: string(FromKeyword), Kind: KeywordKind},
+				{Value: "t", Kind: IdentifierKind},
+			},
+		},
+		{
+			input:   `select a[1] from t`,
+			dialect: ANSIDialect,
+			err:     true,
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, LexOptions{Dialect: test.dialect})
+		assert.Equal(t, test.err, err != nil, test.input)
+		if !test.err {
+			assertTokens(t, test.Tokens, tokens, test.input)
+		}
+	}
+}
+
+func TestLexWithOptions_dialectSymbols(t *testing.T) {
+	tests := []struct {
+		input   string
+		dialect Dialect
+		values  []string
+	}{
+		{
+			input:   "a <=> b",
+			dialect: MySQLDialect,
+			values:  []string{"a", "<=>", "b"},
+		},
+		{
+			input:   "a<=>b <= c <> d < e",
+			dialect: MySQLDialect,
+			values:  []string{"a", "<=>", "b", "<=", "c", "<>", "d", "<", "e"},
+		},
+		{
+			// Without <=> it's <= followed by a stray >
+			input:   "a <=> b",
+			dialect: ANSIDialect,
+			values:  []string{"a", "<=", ">", "b"},
+		},
+		{
+			input:   "a <= b",
+			dialect: PostgresDialect,
+			values:  []string{"a", "<=", "b"},
+		},
+		{
+			input:   "data->k, data->>k, a - > b, a-b",
+			dialect: PostgresDialect,
+			values:  []string{"data", "->", "k", ",", "data", "->>", "k", ",", "a", "-", ">", "b", ",", "a", "-", "b"},
+		},
+		{
+			input:   "data->>k->j -- ->",
+			dialect: PostgresDialect,
+			values:  []string{"data", "->>", "k", "->", "j"},
+		},
+		{
+			input:   "data->k",
+			dialect: ANSIDialect,
+			values:  []string{"data", "-", ">", "k"},
+		},
+		{
+			input:   "a & b | c ~d a<<e b>>f",
+			dialect: ANSIDialect,
+			values:  []string{"a", "&", "b", "|", "c", "~", "d", "a", "<<", "e", "b", ">>", "f"},
+		},
+		{
+			// || and <= still win, and a lone | or < is left over
+			input:   "a||b|c |||d <<=e <<<f >>>g",
+			dialect: ANSIDialect,
+			values:  []string{"a", "||", "b", "|", "c", "||", "|", "d", "<<", "=", "e", "<<", "<", "f", ">>", ">", "g"},
+		},
+		{
+			input:   "a # b",
+			dialect: PostgresDialect,
+			values:  []string{"a", "#", "b"},
+		},
+		{
+			input:   "a->>b >> c",
+			dialect: PostgresDialect,
+			values:  []string{"a", "->>", "b", ">>", "c"},
+		},
+		{
+			input:   "data->k",
+			dialect: MySQLDialect,
+			values:  []string{"data", "-", ">", "k"},
+		},
+		{
+			input:   "a[i][j], b [ k ]",
+			dialect: PostgresDialect,
+			values:  []string{"a", "[", "i", "]", "[", "j", "]", ",", "b", "[", "k", "]"},
+		},
+		{
+			input:   "a[i]",
+			dialect: TSQLDialect,
+			values:  []string{"a", "i"},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, LexOptions{Dialect: test.dialect})
+		assert.Nil(t, err, test.input)
+		values := []string{}
+		for _, token := range tokens {
+			if token.Kind != IdentifierKind {
+				assert.Equal(t, SymbolKind, token.Kind, test.input)
+			}
+			values = append(values, token.Value)
+		}
+		assert.Equal(t, test.values, values, test.input)
+	}
+}
+
+func TestLexWithOptions_everySymbol(t *testing.T) {
+	for _, dialect := range []Dialect{ANSIDialect, PostgresDialect, MySQLDialect, TSQLDialect} {
+		for _, symbol := range append(append([]Symbol{}, symbols...), dialectSymbols[dialect]...) {
+			input := "a" + string(symbol) + "b"
+			tokens, err := LexWithOptions(input, LexOptions{Dialect: dialect})
+			if !assert.Nil(t, err, input) || !assert.Equal(t, 3, len(tokens), input) {
+				continue
+			}
+			assert.Equal(t, string(symbol), tokens[1].Value, input)
+			assert.Equal(t, SymbolKind, tokens[1].Kind, input)
+			assert.Equal(t, uint(1), tokens[1].Loc.Col, input)
+			assert.Equal(t, uint(1+len(symbol)), tokens[1].EndLoc.Col, input)
+		}
+	}
+}
+
+// Runs of symbols split into the longest symbol at each position, even
+// where shorter ones would leave nothing over
+func TestLexWithOptions_adjacentSymbols(t *testing.T) {
+	tests := []struct {
+		input   string
+		dialect Dialect
+		values  []string
+	}{
+		{input: "<=>", dialect: MySQLDialect, values: []string{"<=>"}},
+		{input: "<=>", dialect: ANSIDialect, values: []string{"<=", ">"}},
+		{input: "<=>>", dialect: MySQLDialect, values: []string{"<=>", ">"}},
+		{input: "->>", dialect: PostgresDialect, values: []string{"->>"}},
+		{input: "->>>", dialect: PostgresDialect, values: []string{"->>", ">"}},
+		{input: "->->>", dialect: PostgresDialect, values: []string{"->", "->>"}},
+		{input: "<<=", dialect: ANSIDialect, values: []string{"<<", "="}},
+		{input: "<<>", dialect: ANSIDialect, values: []string{"<<", ">"}},
+		{input: "<>=", dialect: ANSIDialect, values: []string{"<>", "="}},
+		{input: ">>=", dialect: ANSIDialect, values: []string{">>", "="}},
+		{input: ">=>", dialect: ANSIDialect, values: []string{">=", ">"}},
+		{input: "!==", dialect: ANSIDialect, values: []string{"!=", "="}},
+		{input: "|||", dialect: ANSIDialect, values: []string{"||", "|"}},
+		{input: "::::", dialect: ANSIDialect, values: []string{"::", "::"}},
+		{input: "...", dialect: ANSIDialect, values: []string{"..", "."}},
+		{input: "][", dialect: PostgresDialect, values: []string{"]", "["}},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, LexOptions{Dialect: test.dialect})
+		if !assert.Nil(t, err, test.input) {
+			continue
+		}
+		values := []string{}
+		col := uint(0)
+		for _, token := range tokens {
+			assert.Equal(t, SymbolKind, token.Kind, test.input)
+			assert.Equal(t, col, token.Loc.Col, test.input)
+			col += uint(len(token.Value))
+			values = append(values, token.Value)
+		}
+		assert.Equal(t, test.values, values, test.input)
+	}
+}
+
+func TestIsReservedKeyword(t *testing.T) {
+	tests := []struct {
+		reserved bool
+		value    string
+	}{
+		{
+			reserved: true,
+			value:    "select",
+		},
+		{
+			reserved: true,
+			value:    "FROM",
+		},
+		{
+			reserved: true,
+			value:    "Where",
+		},
+		// false tests
+		{
+			reserved: false,
+			value:    "text",
+		},
+		{
+			reserved: false,
+			value:    "view",
+		},
+		{
+			reserved: false,
+			value:    "users",
+		},
+		{
+			reserved: false,
+			value:    "selected",
+		},
+		{
+			reserved: false,
+			value:    "Index",
+		},
+		{
+			reserved: false,
+			value:    "",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.reserved, IsReservedKeyword(test.value), test.value)
+	}
+}
+
+// A large script exercising every kind of token
+func benchmarkSource() string {
+	statement := "CREATE TABLE users (id INT, name TEXT, score FLOAT);\n" +
+		"INSERT INTO users VALUES (105, 'a name', 1.5e3);\n" +
+		"SELECT u.id, u.name FROM users u LEFT JOIN scores s ON u.id = s.id WHERE u.name IS NOT NULL AND s.score = 1;\n" +
+		"UPDATE users SET name = 'other' || name WHERE id = 105;\n"
+	return strings.Repeat(statement, 1000)
+}
+
+func BenchmarkLex(b *testing.B) {
+	source := benchmarkSource()
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Lex(source); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Thousands of plain string literals, none of which need unescaping
+func BenchmarkLex_strings(b *testing.B) {
+	source := "insert into t values (" + strings.Repeat("'a plain string literal', ", 5000) + "'last');"
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Lex(source); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkToken_lexKeyword(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lexKeyword("SELECT a", Cursor{})
+		lexKeyword("selection", Cursor{})
+	}
+}
+
+func BenchmarkToken_lexSymbol(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lexSymbol("|| a", Cursor{})
+		lexSymbol("a", Cursor{})
+	}
+}
+
+// The keyword and symbol lexers only allocate the token they return
+func TestLex_allocations(t *testing.T) {
+	tests := []struct {
+		lexer  lexer
+		source string
+		allocs float64
+	}{
+		{
+			lexer:  lexKeyword,
+			source: "SELECT a",
+			allocs: 1,
+		},
+		{
+			lexer:  lexKeyword,
+			source: "selection",
+			allocs: 0,
+		},
+		{
+			lexer:  lexSymbol,
+			source: "|| a",
+			allocs: 1,
+		},
+		{
+			lexer:  lexSymbol,
+			source: "a",
+			allocs: 0,
+		},
+		{
+			lexer:  lexIdentifier,
+			source: "users",
+			allocs: 1,
+		},
+		{
+			lexer:  lexString,
+			source: "'a plain string'",
+			allocs: 1,
+		},
+	}
+
+	for _, test := range tests {
+		allocs := testing.AllocsPerRun(100, func() {
+			test.lexer(test.source, Cursor{})
+		})
+		assert.Equal(t, test.allocs, allocs, test.source)
+	}
+}
+
+func TestLex_preserveWhitespace(t *testing.T) {
+	tests := []struct {
+		input   string
+		options LexOptions
+		Tokens  []Token
+	}{
+		{
+			input: "select   *",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: string(AsteriskSymbol), Raw: "*", Kind: SymbolKind, Loc: Location{Line: 0, Col: 9, Offset: 9}, EndLoc: Location{Line: 0, Col: 10, Offset: 10}},
+			},
+		},
+		{
+			input:   "select   *",
+			options: LexOptions{PreserveWhitespace: true},
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: "   ", Raw: "   ", Kind: WhitespaceKind, Loc: Location{Line: 0, Col: 6, Offset: 6}, EndLoc: Location{Line: 0, Col: 9, Offset: 9}},
+				{Value: string(AsteriskSymbol), Raw: "*", Kind: SymbolKind, Loc: Location{Line: 0, Col: 9, Offset: 9}, EndLoc: Location{Line: 0, Col: 10, Offset: 10}},
+			},
+		},
+		{
+			input:   " \t\r\n a +5\n",
+			options: LexOptions{PreserveWhitespace: true, AllowSignedNumerics: true},
+			Tokens: []Token{
+				{Value: " \t\r\n ", Raw: " \t\r\n ", Kind: WhitespaceKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 1, Col: 1, Offset: 5}},
+				{Value: "a", Raw: "a", Kind: IdentifierKind, Loc: Location{Line: 1, Col: 1, Offset: 5}, EndLoc: Location{Line: 1, Col: 2, Offset: 6}},
+				{Value: " ", Raw: " ", Kind: WhitespaceKind, Loc: Location{Line: 1, Col: 2, Offset: 6}, EndLoc: Location{Line: 1, Col: 3, Offset: 7}},
+				{Value: "+", Raw: "+", Kind: SymbolKind, Loc: Location{Line: 1, Col: 3, Offset: 7}, EndLoc: Location{Line: 1, Col: 4, Offset: 8}},
+				{Value: "5", Raw: "5", Kind: NumericKind, Loc: Location{Line: 1, Col: 4, Offset: 8}, EndLoc: Location{Line: 1, Col: 5, Offset: 9}},
+				{Value: "\n", Raw: "\n", Kind: WhitespaceKind, Loc: Location{Line: 1, Col: 5, Offset: 9}, EndLoc: Location{Line: 2, Col: 0, Offset: 10}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, test.options)
+		assert.Nil(t, err, test.input)
+		assert.Equal(t, len(test.Tokens), len(tokens), test.input)
+		for i, tok := range tokens {
+			assert.Equal(t, &test.Tokens[i], tok, test.input)
+		}
+	}
+}
+
+func TestLex_maxTokenLength(t *testing.T) {
+	opts := LexOptions{MaxTokenLength: 10}
+	tests := []struct {
+		source  string
+		values  []string
+		message string
+	}{
+		{
+			// The quotes count towards the length
+			source: "select '12345678' from t",
+			values: []string{"select", "12345678", "from", "t"},
+		},
+		{
+			source:  "select '123456789' from t",
+			message: "Token exceeds maximum length of 10 bytes at 0:7",
+		},
+		{
+			source:  "select '12345" + strings.Repeat("6", 100),
+			message: "Unable to lex token within maximum length of 10 bytes after select at 0:7",
+		},
+		{
+			source:  "select 'abc",
+			message: "Unterminated string at 0:7",
+		},
+		{
+			source:  "select abcdefghijk",
+			message: "Token exceeds maximum length of 10 bytes at 0:7",
+		},
+		{
+			source: "select" + strings.Repeat(" ", 100) + "\n\ta",
+			values: []string{"select", "a"},
+		},
+		{
+			source:  "select -- a long comment\na",
+			message: "Token exceeds maximum length of 10 bytes at 0:7",
+		},
+	}
+
+	for _, test := range tests {
+		for _, tokenizer := range []*Tokenizer{
+			NewTokenizer(test.source, opts),
+			NewReaderTokenizer(strings.NewReader(test.source), opts),
+		} {
+			tokens, err := collectTokens(context.Background(), tokenizer)
+			if test.message != "" {
+				if assert.NotNil(t, err, test.source) {
+					assert.Equal(t, test.message, err.Error(), test.source)
+				}
+				continue
+			}
+
+			assert.Nil(t, err, test.source)
+			values := []string{}
+			for _, token := range tokens {
+				values = append(values, token.Value)
+			}
+			assert.Equal(t, test.values, values, test.source)
+		}
+	}
+}
+
+func TestLex_comments(t *testing.T) {
+	tests := []struct {
+		input   string
+		options LexOptions
+		Tokens  []Token
+	}{
+		{
+			input: "select -- hi\n1",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: "1", Raw: "1", Kind: NumericKind, Loc: Location{Line: 1, Col: 0, Offset: 13}, EndLoc: Location{Line: 1, Col: 1, Offset: 14}},
+			},
+		},
+		{
+			input:   "select -- hi\n1",
+			options: LexOptions{PreserveComments: true},
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: "-- hi", Raw: "-- hi", Kind: CommentKind, Loc: Location{Line: 0, Col: 7, Offset: 7}, EndLoc: Location{Line: 0, Col: 12, Offset: 12}},
+				{Value: "1", Raw: "1", Kind: NumericKind, Loc: Location{Line: 1, Col: 0, Offset: 13}, EndLoc: Location{Line: 1, Col: 1, Offset: 14}},
+			},
+		},
+		{
+			input: "select /* x */ 1",
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: "1", Raw: "1", Kind: NumericKind, Loc: Location{Line: 0, Col: 15, Offset: 15}, EndLoc: Location{Line: 0, Col: 16, Offset: 16}},
+			},
+		},
+		{
+			input:   "select /* x */ 1",
+			options: LexOptions{PreserveComments: true},
+			Tokens: []Token{
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 0, Col: 6, Offset: 6}, keyword: SelectKeyword},
+				{Value: "/* x */", Raw: "/* x */", Kind: CommentKind, Loc: Location{Line: 0, Col: 7, Offset: 7}, EndLoc: Location{Line: 0, Col: 14, Offset: 14}},
+				{Value: "1", Raw: "1", Kind: NumericKind, Loc: Location{Line: 0, Col: 15, Offset: 15}, EndLoc: Location{Line: 0, Col: 16, Offset: 16}},
+			},
+		},
+		{
+			input:   "/* a\r\n * b\n */select",
+			options: LexOptions{PreserveComments: true},
+			Tokens: []Token{
+				{Value: "/* a\r\n * b\n */", Raw: "/* a\r\n * b\n */", Kind: CommentKind, Loc: Location{Line: 0, Col: 0, Offset: 0}, EndLoc: Location{Line: 2, Col: 3, Offset: 14}},
+				{Value: string(SelectKeyword), Raw: "select", Kind: KeywordKind, Loc: Location{Line: 2, Col: 3, Offset: 14}, EndLoc: Location{Line: 2, Col: 9, Offset: 20}, keyword: SelectKeyword},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, test.options)
+		assert.Nil(t, err, test.input)
+		assert.Equal(t, len(test.Tokens), len(tokens), test.input)
+		for i, tok := range tokens {
+			assert.Equal(t, &test.Tokens[i], tok, test.input)
+		}
+	}
+
+	tokens, err := LexWithOptions("-- a\n/* b */", LexOptions{PreserveComments: true})
+	assert.Nil(t, err)
+	assert.False(t, tokens[0].IsBlockComment())
+	assert.True(t, tokens[1].IsBlockComment())
+
+	_, err = Lex("select /* unterminated")
+	var lexErr *LexError
+	if assert.True(t, errors.As(err, &lexErr)) {
+		assert.Equal(t, Location{Line: 0, Col: 7, Offset: 7}, lexErr.Loc)
+	}
+}
+
+// Comments that run right up to the end of the source, with no newline
+// after them
+func TestLex_commentsAtEOF(t *testing.T) {
+	tests := []struct {
+		input   string
+		comment string
+	}{
+		{input: "SELECT 1; -- trailing note", comment: "-- trailing note"},
+		{input: "SELECT 1;--", comment: "--"},
+		{input: "SELECT 1; /* note */", comment: "/* note */"},
+		{input: "SELECT 1;/**/", comment: "/**/"},
+		{input: "SELECT 1; /* a\n * b */", comment: "/* a\n * b */"},
+	}
+
+	for _, test := range tests {
+		start := uint(len(test.input) - len(test.comment))
+		tok, cur, ok := LexOptions{PreserveComments: true}.lexComment(test.input, Cursor{Pointer: start})
+		if assert.True(t, ok, test.input) {
+			assert.Equal(t, test.comment, tok.Value, test.input)
+			assert.Equal(t, uint(len(test.input)), cur.Pointer, test.input)
+		}
+
+		// Dropped, kept or attached to the EOF token
+		tokens, err := Lex(test.input)
+		if assert.Nil(t, err, test.input) {
+			assert.Equal(t, string(SemicolonSymbol), tokens[len(tokens)-1].Value, test.input)
+			_, err = Parse(tokens)
+			assert.Nil(t, err, test.input)
+		}
+		tokens, err = LexWithOptions(test.input, LexOptions{PreserveComments: true})
+		if assert.Nil(t, err, test.input) {
+			assert.Equal(t, test.comment, tokens[len(tokens)-1].Value, test.input)
+		}
+		tokens, err = LexWithOptions(test.input, LexOptions{AttachComments: true, EmitEOF: true})
+		if assert.Nil(t, err, test.input) {
+			eof := tokens[len(tokens)-1]
+			assert.Equal(t, EOFKind, eof.Kind, test.input)
+			assert.Equal(t, []string{test.comment}, eof.LeadingComments, test.input)
+		}
+	}
+
+	// A block comment one character short of closed still fails
+	_, err := Lex("SELECT 1; /* note *")
+	assert.NotNil(t, err)
+}
+
+func TestLex_attachComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		options  LexOptions
+		values   []string
+		comments [][]string
+	}{
+		{
+			input:    "-- the query\n/* all */ SELECT * -- of them\nFROM t",
+			options:  LexOptions{AttachComments: true},
+			values:   []string{"select", "*", "from", "t"},
+			comments: [][]string{{"-- the query", "/* all */"}, nil, {"-- of them"}, nil},
+		},
+		{
+			// Whitespace tokens don't take the comments
+			input:    "a /* b */ c",
+			options:  LexOptions{AttachComments: true, PreserveWhitespace: true},
+			values:   []string{"a", " ", " ", "c"},
+			comments: [][]string{nil, nil, nil, {"/* b */"}},
+		},
+		{
+			input:    "select a -- trailing\n/* end */",
+			options:  LexOptions{AttachComments: true, EmitEOF: true},
+			values:   []string{"select", "a", ""},
+			comments: [][]string{nil, nil, {"-- trailing", "/* end */"}},
+		},
+		{
+			// Without an EOF token to attach to, trailing comments are dropped
+			input:    "select a -- trailing",
+			options:  LexOptions{AttachComments: true},
+			values:   []string{"select", "a"},
+			comments: [][]string{nil, nil},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := LexWithOptions(test.input, test.options)
+		assert.Nil(t, err, test.input)
+		values := []string{}
+		comments := [][]string{}
+		for _, token := range tokens {
+			values = append(values, token.Value)
+			comments = append(comments, token.LeadingComments)
+		}
+		assert.Equal(t, test.values, values, test.input)
+		assert.Equal(t, test.comments, comments, test.input)
+	}
+}
+
+func TestTokenKind_String(t *testing.T) {
+	tests := []struct {
+		kind TokenKind
+		name string
+	}{
+		{
+			kind: KeywordKind,
+			name: "Keyword",
+		},
+		{
+			kind: SymbolKind,
+			name: "Symbol",
+		},
+		{
+			kind: IdentifierKind,
+			name: "Identifier",
+		},
+		{
+			kind: StringKind,
+			name: "String",
+		},
+		{
+			kind: NumericKind,
+			name: "Numeric",
+		},
+		{
+			kind: EOFKind,
+			name: "EOF",
+		},
+		{
+			kind: IllegalKind,
+			name: "Illegal",
+		},
+		{
+			kind: WhitespaceKind,
+			name: "Whitespace",
+		},
+		{
+			kind: CommentKind,
+			name: "Comment",
+		},
+		{
+			kind: BytesKind,
+			name: "Bytes",
+		},
+		{
+			kind: TokenKind(99),
+			name: "TokenKind(99)",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.name, test.kind.String(), test.name)
+	}
+	// Every kind has a name
+	assert.Equal(t, len(tests)-1, len(tokenKindNames))
+}
+
+func TestToken_Keyword(t *testing.T) {
+	tokens, err := Lex("SELECT * FROM t")
+	assert.Nil(t, err)
+
+	keyword, ok := tokens[0].Keyword()
+	assert.True(t, ok)
+	assert.Equal(t, SelectKeyword, keyword)
+
+	symbol, ok := tokens[1].Symbol()
+	assert.True(t, ok)
+	assert.Equal(t, AsteriskSymbol, symbol)
+
+	// Neither a keyword nor a symbol
+	keyword, ok = tokens[3].Keyword()
+	assert.False(t, ok)
+	assert.Equal(t, Keyword(""), keyword)
+	symbol, ok = tokens[3].Symbol()
+	assert.False(t, ok)
+	assert.Equal(t, Symbol(""), symbol)
+
+	// The wrong kind
+	_, ok = tokens[0].Symbol()
+	assert.False(t, ok)
+	_, ok = tokens[1].Keyword()
+	assert.False(t, ok)
+}
+
+func TestToken_equalsAt(t *testing.T) {
+	tokens, err := Lex("a a\na")
+	assert.Nil(t, err)
+	again, err := Lex("a a\na")
+	assert.Nil(t, err)
+
+	for i := range tokens {
+		assert.True(t, tokens[i].equalsAt(again[i]), i)
+		for j := range tokens {
+			// Equal by value, but only at the same location
+			assert.True(t, tokens[i].equals(tokens[j]), i, j)
+			assert.Equal(t, i == j, tokens[i].equalsAt(tokens[j]), i, j)
+		}
+	}
+
+	// Still not equal at the same location with a different value or kind
+	other := *tokens[0]
+	other.Value = "b"
+	assert.False(t, tokens[0].equalsAt(&other))
+	other = *tokens[0]
+	other.Kind = StringKind
+	assert.False(t, tokens[0].equalsAt(&other))
+}
+
+func TestLex_resolvesKeywords(t *testing.T) {
+	for _, k := range keywords {
+		for _, source := range []string{string(k), strings.ToUpper(string(k))} {
+			tokens, err := Lex(source)
+			if assert.Nil(t, err, source) && assert.Equal(t, 1, len(tokens), source) {
+				assert.Equal(t, k, tokens[0].keyword, source)
+				keyword, ok := tokens[0].Keyword()
+				assert.True(t, ok, source)
+				assert.Equal(t, k, keyword, source)
+			}
+		}
+	}
+
+	// Only keyword tokens are resolved
+	tokens, err := Lex(`selected 'select' "select" 1 ; -`)
+	assert.Nil(t, err)
+	for _, token := range tokens {
+		assert.Equal(t, Keyword(""), token.keyword, token.Value)
+	}
+}
+
+func TestToken_Category(t *testing.T) {
+	tokens, err := LexWithOptions(
+		"SELECT u.name, count(*) /* all */ FROM users u WHERE u.age >= 18 AND u.name <> 'x' || \"y\"; -- done",
+		LexOptions{PreserveComments: true, PreserveWhitespace: true, EmitEOF: true},
+	)
+	assert.Nil(t, err)
+
+	categories := []string{}
+	for _, token := range tokens {
+		if token.Kind == WhitespaceKind {
+			assert.Equal(t, WhitespaceCategory, token.Category())
+			continue
+		}
+		categories = append(categories, token.Value+":"+token.Category().String())
+	}
+
+	assert.Equal(t, []string{
+		"select:Keyword",
+		"u:Identifier", ".:Punctuation", "name:Identifier", ",:Punctuation",
+		"count:Identifier", "(:Punctuation", "*:Operator", "):Punctuation",
+		"/* all */:Comment",
+		"from:Keyword", "users:Identifier", "u:Identifier",
+		"where:Keyword", "u:Identifier", ".:Punctuation", "age:Identifier", ">=:Operator", "18:Literal",
+		"and:Keyword", "u:Identifier", ".:Punctuation", "name:Identifier", "<>:Operator", "x:Literal",
+		"||:Operator", "y:Identifier", ";:Punctuation",
+		"-- done:Comment",
+		":Other",
+	}, categories)
+
+	assert.Equal(t, OtherCategory, (&Token{Value: "^", Kind: IllegalKind}).Category())
+	assert.Equal(t, "TokenCategory(99)", TokenCategory(99).String())
+}
+
+func TestKeyword_String(t *testing.T) {
+	assert.Equal(t, "SELECT", SelectKeyword.String())
+	assert.Equal(t, "NULL", fmt.Sprint(NullKeyword))
+	assert.Equal(t, "||", ConcatSymbol.String())
+}
+
+func TestLex_raw(t *testing.T) {
+	tests := []struct {
+		input  string
+		values []string
+		raws   []string
+	}{
+		{
+			input:  "SELECT Name FROM Users",
+			values: []string{"select", "name", "from", "users"},
+			raws:   []string{"SELECT", "Name", "FROM", "Users"},
+		},
+		{
+			input:  `sElEcT "Quoted ""id""", 'it''s', 1.5e3`,
+			values: []string{"select", `Quoted "id"`, ",", "it's", ",", "1.5e3"},
+			raws:   []string{"sElEcT", `"Quoted ""id"""`, ",", "'it''s'", ",", "1.5e3"},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		values := []string{}
+		raws := []string{}
+		for _, token := range tokens {
+			values = append(values, token.Value)
+			raws = append(raws, token.Raw)
+		}
+		assert.Equal(t, test.values, values, test.input)
+		assert.Equal(t, test.raws, raws, test.input)
+	}
+}
+
+func TestToken_json(t *testing.T) {
+	tokens, err := Lex("select\n  'a b'")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		token *Token
+		json  string
+	}{
+		{
+			token: tokens[0],
+			json:  `{"value":"select","raw":"select","kind":"Keyword","loc":{"line":0,"col":0,"offset":0},"endLoc":{"line":0,"col":6,"offset":6}}`,
+		},
+		{
+			token: tokens[1],
+			json:  `{"value":"a b","raw":"'a b'","kind":"String","loc":{"line":1,"col":2,"offset":9},"endLoc":{"line":1,"col":7,"offset":14}}`,
+		},
+	}
+
+	for _, test := range tests {
+		b, err := json.Marshal(test.token)
+		assert.Nil(t, err, test.json)
+		assert.Equal(t, test.json, string(b))
+
+		var token Token
+		assert.Nil(t, json.Unmarshal(b, &token), test.json)
+		assert.Equal(t, test.token, &token, test.json)
+	}
+
+	_, err = json.Marshal(&Token{Kind: TokenKind(99)})
+	assert.NotNil(t, err)
+	var token Token
+	assert.NotNil(t, json.Unmarshal([]byte(`{"kind":"Bogus"}`), &token))
+}
+
+// Check the invariant every lexed token stream holds: each token's span
+// is within source, starts at or after the end of the token before it
+// and is the token's raw text
+func validateTokenStream(tokens []*Token, source string) error {
+	end := uint(0)
+	for i, token := range tokens {
+		if token.Loc.Offset < end {
+			return fmt.Errorf("Token %d %q starts at %d, before the previous token ends at %d", i, token.Raw, token.Loc.Offset, end)
+		}
+		if token.EndLoc.Offset < token.Loc.Offset {
+			return fmt.Errorf("Token %d %q ends at %d, before it starts at %d", i, token.Raw, token.EndLoc.Offset, token.Loc.Offset)
+		}
+		if token.EndLoc.Offset > uint(len(source)) {
+			return fmt.Errorf("Token %d %q ends at %d, past the end of the source at %d", i, token.Raw, token.EndLoc.Offset, len(source))
+		}
+		if span := source[token.Loc.Offset:token.EndLoc.Offset]; span != token.Raw {
+			return fmt.Errorf("Token %d has raw text %q but spans %q", i, token.Raw, span)
+		}
+		end = token.EndLoc.Offset
+	}
+	return nil
+}
+
+func TestValidateTokenStream(t *testing.T) {
+	source := "select a"
+	tokens, err := Lex(source)
+	assert.Nil(t, err)
+	assert.Nil(t, validateTokenStream(tokens, source))
+	assert.Nil(t, validateTokenStream(nil, source))
+
+	tests := []struct {
+		tokens []*Token
+		err    string
+	}{
+		{
+			tokens: []*Token{tokens[1], tokens[0]},
+			err:    `Token 1 "select" starts at 0, before the previous token ends at 8`,
+		},
+		{
+			tokens: []*Token{{Raw: "a", Loc: Location{Offset: 7}, EndLoc: Location{Offset: 6}}},
+			err:    `Token 0 "a" ends at 6, before it starts at 7`,
+		},
+		{
+			tokens: []*Token{{Raw: "a!", Loc: Location{Offset: 7}, EndLoc: Location{Offset: 9}}},
+			err:    `Token 0 "a!" ends at 9, past the end of the source at 8`,
+		},
+		{
+			tokens: []*Token{{Raw: "b", Loc: Location{Offset: 7}, EndLoc: Location{Offset: 8}}},
+			err:    `Token 0 has raw text "b" but spans "a"`,
+		},
+	}
+
+	for _, test := range tests {
+		err := validateTokenStream(test.tokens, source)
+		if assert.NotNil(t, err, test.err) {
+			assert.Equal(t, test.err, err.Error())
+		}
+	}
+}
+
+func FuzzLex(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"SELECT a, b AS \"B\" FROM t WHERE a >= 1.5e3 AND b <> 'it''s';",
+		"select *\r\nfrom t -- comment\n/* block\n comment */;",
+		"INSERT INTO files VALUES (1, X'1F3A', N'café');",
+		"a<=>b->>c..d[1]",
+		"select 'unterminated",
+		"\t\xff",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		tokens, err := Lex(source)
+		if err == nil {
+			if err := validateTokenStream(tokens, source); err != nil {
+				t.Fatalf("%s in %q", err, source)
+			}
+		}
+
+		// With nothing skipped the tokens cover the whole source
+		tokens, err = LexWithOptions(source, LexOptions{PreserveWhitespace: true, PreserveComments: true, EmitEOF: true})
+		if err != nil {
+			return
+		}
+		if err := validateTokenStream(tokens, source); err != nil {
+			t.Fatalf("%s in %q", err, source)
+		}
+		var raw strings.Builder
+		for _, token := range tokens {
+			raw.WriteString(token.Raw)
+		}
+		if raw.String() != source {
+			t.Fatalf("Tokens of %q only cover %q", source, raw.String())
+		}
+	})
+}
@@ -0,0 +1,1217 @@
+package gosql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ColumnType uint
+
+const (
+	TextType ColumnType = iota
+	IntType
+	// The type of comparisons and other predicates
+	BoolType
+	// The type of a NULL literal, which can be used as any other type
+	NullType
+)
+
+func (c ColumnType) String() string {
+	switch c {
+	case TextType:
+		return "text"
+	case IntType:
+		return "int"
+	case BoolType:
+		return "boolean"
+	case NullType:
+		return "null"
+	}
+	return fmt.Sprintf("ColumnType(%d)", uint(c))
+}
+
+// The column type each type keyword is stored as. The others aren't
+// supported by the memory backend yet.
+var memoryColumnTypes = map[Keyword]ColumnType{
+	IntKeyword:      IntType,
+	BigintKeyword:   IntType,
+	SmallintKeyword: IntType,
+	TextKeyword:     TextType,
+	VarcharKeyword:  TextType,
+	CharKeyword:     TextType,
+}
+
+// A Cell is one value of a result row. Its type is the type of its
+// column. The value of a NULL cell is the zero value of its type.
+type Cell interface {
+	AsInt() int64
+	AsText() string
+	AsBool() bool
+	IsNull() bool
+}
+
+// A memoryCell is one typed value of a row, or NULL
+type memoryCell struct {
+	typ     ColumnType
+	i       int64
+	text    string
+	boolean bool
+	null    bool
+}
+
+func (c memoryCell) AsInt() int64 {
+	return c.i
+}
+
+func (c memoryCell) AsText() string {
+	switch c.typ {
+	case IntType:
+		return strconv.FormatInt(c.i, 10)
+	case BoolType:
+		return strconv.FormatBool(c.boolean)
+	}
+	return c.text
+}
+
+func (c memoryCell) AsBool() bool {
+	return c.boolean
+}
+
+func (c memoryCell) IsNull() bool {
+	return c.null
+}
+
+type table struct {
+	// In the order they were declared by CREATE TABLE, which is the order
+	// * expands to
+	columns     []string
+	columnTypes []ColumnType
+	rows        [][]memoryCell
+	// The name or alias of the table each column is from, only set on the
+	// tables a SELECT reads from so columns can be qualified
+	qualifiers []string
+	// The FROM items to the left of a lateral subquery and the row of them
+	// it is being run for, whose columns it can refer to when its own
+	// tables don't have them. Nil outside of one.
+	outer    *table
+	outerRow []memoryCell
+}
+
+// ErrTableExists is returned when creating a table whose name is taken
+type ErrTableExists struct {
+	Name string
+}
+
+func (e *ErrTableExists) Error() string {
+	return fmt.Sprintf("Table %s already exists", e.Name)
+}
+
+// ErrTableNotFound is returned when a statement refers to a table that
+// doesn't exist
+type ErrTableNotFound struct {
+	Name string
+}
+
+func (e *ErrTableNotFound) Error() string {
+	return fmt.Sprintf("Table %s does not exist", e.Name)
+}
+
+// ErrViewExists is returned when creating a view whose name is taken by
+// another view without OR REPLACE
+type ErrViewExists struct {
+	Name string
+}
+
+func (e *ErrViewExists) Error() string {
+	return fmt.Sprintf("View %s already exists", e.Name)
+}
+
+// A MemoryBackend stores tables as slices of rows in memory. Views are
+// stored as their queries and run each time they're selected from.
+type MemoryBackend struct {
+	tables map[string]*table
+	views  map[string]*SelectStatement
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		tables: map[string]*table{},
+		views:  map[string]*SelectStatement{},
+	}
+}
+
+func (mb *MemoryBackend) CreateTable(crt *CreateTableStatement) error {
+	if _, ok := mb.tables[crt.Name.Value]; ok {
+		return &ErrTableExists{Name: crt.Name.Value}
+	}
+	if _, ok := mb.views[crt.Name.Value]; ok {
+		return &ErrViewExists{Name: crt.Name.Value}
+	}
+
+	t := table{}
+	for _, col := range crt.Cols {
+		for _, existing := range t.columns {
+			if existing == col.Name.Value {
+				return fmt.Errorf("Duplicate column %s", col.Name.Value)
+			}
+		}
+
+		typ, ok := memoryColumnTypes[Keyword(col.Datatype.Name.Value)]
+		if !ok {
+			return fmt.Errorf("Unsupported type %s for column %s", Keyword(col.Datatype.Name.Value), col.Name.Value)
+		}
+
+		t.columns = append(t.columns, col.Name.Value)
+		t.columnTypes = append(t.columnTypes, typ)
+	}
+
+	mb.tables[crt.Name.Value] = &t
+	return nil
+}
+
+// CreateView stores a view's query after checking that it runs. Views
+// share a namespace with tables, and only OR REPLACE may redefine one.
+func (mb *MemoryBackend) CreateView(crt *CreateViewStatement) error {
+	name := crt.Name.Value
+	if _, ok := mb.tables[name]; ok {
+		return &ErrTableExists{Name: name}
+	}
+	if _, ok := mb.views[name]; ok && !crt.OrReplace {
+		return &ErrViewExists{Name: name}
+	}
+	if mb.readsView(crt.Query, name) {
+		return fmt.Errorf("View %s cannot select from itself", name)
+	}
+
+	if _, err := mb.Select(crt.Query); err != nil {
+		return err
+	}
+
+	mb.views[name] = crt.Query
+	return nil
+}
+
+// Collects the names of the tables a statement reads from
+type tableFinder struct {
+	BaseVisitor
+	names []string
+}
+
+func (f *tableFinder) VisitTable(name *Token) error {
+	f.names = append(f.names, name.Value)
+	return nil
+}
+
+// Whether slct reads from the view name, directly or through other views
+func (mb *MemoryBackend) readsView(slct *SelectStatement, name string) bool {
+	f := tableFinder{}
+	Walk(slct, &f)
+	for _, table := range f.names {
+		if table == name {
+			return true
+		}
+		if view, ok := mb.views[table]; ok && mb.readsView(view, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mb *MemoryBackend) Insert(inst *InsertStatement) error {
+	t, ok := mb.tables[inst.Table.Value]
+	if !ok {
+		return &ErrTableNotFound{Name: inst.Table.Value}
+	}
+
+	// Convert every row before storing any, so a bad row inserts nothing
+	rows := make([][]memoryCell, 0, len(inst.Values))
+	for _, values := range inst.Values {
+		if len(values) != len(t.columns) {
+			return fmt.Errorf("Expected %d values for table %s, got %d", len(t.columns), inst.Table.Value, len(values))
+		}
+
+		row := make([]memoryCell, 0, len(values))
+		for i, value := range values {
+			if value.Kind != LiteralKind {
+				return fmt.Errorf("Only literal values can be inserted")
+			}
+
+			cell, err := literalToCell(value.Literal)
+			if err != nil {
+				return err
+			}
+
+			cell, err = assignCell(cell, t.columnTypes[i])
+			if err != nil {
+				return fmt.Errorf("%s for column %s", err, t.columns[i])
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	t.rows = append(t.rows, rows...)
+	return nil
+}
+
+// Convert cell to be stored in a column of type typ. NULL can be stored
+// in any column and text that is an integer as an int, but otherwise the
+// types must match.
+func assignCell(cell memoryCell, typ ColumnType) (memoryCell, error) {
+	if cell.null {
+		return nullCell(typ), nil
+	}
+	if cell.typ == typ {
+		return cell, nil
+	}
+
+	if cell.typ == TextType && typ == IntType {
+		i, err := strconv.ParseInt(strings.TrimSpace(cell.text), 10, 64)
+		if err != nil {
+			return memoryCell{}, fmt.Errorf("Invalid int '%s'", cell.text)
+		}
+		return memoryCell{typ: IntType, i: i}, nil
+	}
+
+	return memoryCell{}, fmt.Errorf("Cannot store %s as %s", cell.typ, typ)
+}
+
+func literalToCell(t *Token) (memoryCell, error) {
+	switch t.Kind {
+	case NumericKind:
+		i, err := strconv.ParseInt(t.Value, 10, 64)
+		if err != nil {
+			return memoryCell{}, fmt.Errorf("Unsupported number %s, only integers are supported", t.Value)
+		}
+		return memoryCell{typ: IntType, i: i}, nil
+	case StringKind:
+		return memoryCell{typ: TextType, text: t.Value}, nil
+	case KeywordKind:
+		if Keyword(t.Value) == NullKeyword {
+			return nullCell(NullType), nil
+		}
+	}
+	return memoryCell{}, fmt.Errorf("Cannot use %s as a value", t.Value)
+}
+
+func (mb *MemoryBackend) Select(slct *SelectStatement) (*Results, error) {
+	return mb.selectFor(slct, nil, nil)
+}
+
+// Run slct with the columns of outer in scope, valued by outerRow, as a
+// lateral subquery is. outer is nil otherwise.
+func (mb *MemoryBackend) selectFor(slct *SelectStatement, outer *table, outerRow []memoryCell) (*Results, error) {
+	if err := unsupportedClauses(slct); err != nil {
+		return nil, err
+	}
+
+	// Without a FROM the items are evaluated once, against an empty row
+	t := &table{}
+	rows := [][]memoryCell{{}}
+	if slct.From != nil {
+		var err error
+		t, err = mb.tableFrom(slct.From)
+		if err != nil {
+			return nil, err
+		}
+		rows = t.rows
+	}
+	t.outer = outer
+	t.outerRow = outerRow
+
+	if slct.Where != nil {
+		typ, err := t.expressionType(slct.Where)
+		if err != nil {
+			return nil, err
+		}
+		if !isPredicate(typ) {
+			return nil, fmt.Errorf("WHERE must be a boolean expression, got %s", typ)
+		}
+
+		filtered := [][]memoryCell{}
+		for _, row := range rows {
+			cell, err := t.evaluateCell(row, slct.Where)
+			if err != nil {
+				return nil, err
+			}
+			if cell.boolean {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+
+	// * expands to every column of the table, in the order they were
+	// created
+	items := []*SelectItem{}
+	for _, item := range slct.Items {
+		if !item.Asterisk {
+			items = append(items, item)
+			continue
+		}
+		for i, col := range t.columns {
+			items = append(items, &SelectItem{
+				Expression: &Expression{
+					Literal:   &Token{Value: col, Kind: IdentifierKind},
+					Qualifier: &Token{Value: t.qualifiers[i], Kind: IdentifierKind},
+					Kind:      LiteralKind,
+				},
+			})
+		}
+	}
+
+	grouped := len(slct.GroupBy) > 0
+	for _, item := range items {
+		if isAggregate(item.Expression) {
+			grouped = true
+		}
+	}
+	if grouped {
+		if len(slct.OrderBy) > 0 {
+			if len(slct.GroupBy) > 0 {
+				return nil, fmt.Errorf("ORDER BY is not supported with GROUP BY")
+			}
+			return nil, fmt.Errorf("ORDER BY is not supported with aggregates")
+		}
+		return t.groupResults(rows, items, groupingSets(slct.GroupBy), slct.Limit, slct.Offset)
+	}
+
+	if len(slct.OrderBy) > 0 {
+		sorted, err := t.sortRows(rows, slct.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+		rows = sorted
+	}
+
+	start, end, err := limitRange(len(rows), slct.Limit, slct.Offset)
+	if err != nil {
+		return nil, err
+	}
+	rows = rows[start:end]
+
+	results := &Results{}
+	for _, item := range items {
+		typ, err := t.expressionType(item.Expression)
+		if err != nil {
+			return nil, err
+		}
+		results.Columns = append(results.Columns, ResultColumn{Name: itemName(item), Type: typ})
+	}
+
+	for _, row := range rows {
+		result := make([]Cell, 0, len(items))
+		for _, item := range items {
+			cell, err := t.evaluateCell(row, item.Expression)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, cell)
+		}
+		results.Rows = append(results.Rows, result)
+	}
+
+	return results, nil
+}
+
+// The rows a FROM clause reads, with each column qualified by the name or
+// alias of its table. Joins pair every row of the left table with every
+// row of the right, and inner joins keep the pairs their ON predicate
+// holds for.
+func (mb *MemoryBackend) tableFrom(ref *TableReference) (*table, error) {
+	switch ref.Kind {
+	case NamedTableKind:
+		qualifier := ref.Name.Value
+		if ref.As != nil {
+			qualifier = ref.As.Value
+		}
+
+		stored, ok := mb.tables[ref.Name.Value]
+		if !ok {
+			view, ok := mb.views[ref.Name.Value]
+			if !ok {
+				return nil, &ErrTableNotFound{Name: ref.Name.Value}
+			}
+
+			results, err := mb.Select(view)
+			if err != nil {
+				return nil, err
+			}
+			return resultsTable(results, qualifier), nil
+		}
+
+		t := *stored
+		t.qualifiers = make([]string, len(t.columns))
+		for i := range t.qualifiers {
+			t.qualifiers[i] = qualifier
+		}
+		return &t, nil
+	case SubqueryTableKind:
+		// With nothing to its left a lateral subquery is like any other
+		return mb.subqueryTable(ref, nil, nil)
+	case JoinTableKind:
+		switch ref.Join.Kind {
+		case InnerJoinKind, CrossJoinKind, CommaJoinKind:
+		default:
+			return nil, fmt.Errorf("Only inner and cross joins are supported")
+		}
+
+		left, err := mb.tableFrom(ref.Join.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		// A lateral subquery is run again for each row of the left table.
+		// Its columns are the same every time, so running it for a row of
+		// NULLs finds them even if the left table has no rows.
+		lateral := ref.Join.Right.Kind == SubqueryTableKind && ref.Join.Right.Lateral
+		var right *table
+		if lateral {
+			right, err = mb.subqueryTable(ref.Join.Right, left, left.nullRow())
+		} else {
+			right, err = mb.tableFrom(ref.Join.Right)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, qualifier := range right.qualifiers {
+			for _, existing := range left.qualifiers {
+				// Subqueries without an alias have no name to clash
+				if qualifier == existing && qualifier != "" {
+					return nil, fmt.Errorf("Table %s is specified more than once", qualifier)
+				}
+			}
+		}
+
+		joined := &table{
+			columns:     append(append([]string{}, left.columns...), right.columns...),
+			columnTypes: append(append([]ColumnType{}, left.columnTypes...), right.columnTypes...),
+			qualifiers:  append(append([]string{}, left.qualifiers...), right.qualifiers...),
+		}
+
+		if ref.Join.On != nil {
+			typ, err := joined.expressionType(ref.Join.On)
+			if err != nil {
+				return nil, err
+			}
+			if !isPredicate(typ) {
+				return nil, fmt.Errorf("ON must be a boolean expression, got %s", typ)
+			}
+		}
+
+		for _, l := range left.rows {
+			if lateral {
+				right, err = mb.subqueryTable(ref.Join.Right, left, l)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			for _, r := range right.rows {
+				row := append(append(make([]memoryCell, 0, len(joined.columns)), l...), r...)
+				if ref.Join.On != nil {
+					cell, err := joined.evaluateCell(row, ref.Join.On)
+					if err != nil {
+						return nil, err
+					}
+					if !cell.boolean {
+						continue
+					}
+				}
+				joined.rows = append(joined.rows, row)
+			}
+		}
+		return joined, nil
+	}
+	return nil, fmt.Errorf("Only tables and joins of them can be selected from")
+}
+
+// The rows of a subquery in FROM, with its columns qualified by its alias
+// if it has one. outer and outerRow are in scope as for selectFor.
+func (mb *MemoryBackend) subqueryTable(ref *TableReference, outer *table, outerRow []memoryCell) (*table, error) {
+	results, err := mb.selectFor(ref.Subquery, outer, outerRow)
+	if err != nil {
+		return nil, err
+	}
+
+	qualifier := ""
+	if ref.As != nil {
+		qualifier = ref.As.Value
+	}
+	return resultsTable(results, qualifier), nil
+}
+
+// A table holding results, with each column qualified by qualifier
+func resultsTable(results *Results, qualifier string) *table {
+	t := &table{}
+	for _, col := range results.Columns {
+		t.columns = append(t.columns, col.Name)
+		t.columnTypes = append(t.columnTypes, col.Type)
+		t.qualifiers = append(t.qualifiers, qualifier)
+	}
+	for _, result := range results.Rows {
+		row := make([]memoryCell, 0, len(result))
+		for _, cell := range result {
+			row = append(row, cell.(memoryCell))
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+// A row with a NULL for each of the table's columns
+func (t *table) nullRow() []memoryCell {
+	row := make([]memoryCell, 0, len(t.columns))
+	for _, typ := range t.columnTypes {
+		row = append(row, nullCell(typ))
+	}
+	return row
+}
+
+// Clauses the parser accepts but the memory backend can't run yet
+func unsupportedClauses(slct *SelectStatement) error {
+	clauses := []struct {
+		used bool
+		name string
+	}{
+		{slct.Distinct, "DISTINCT"},
+		{slct.Having != nil, "HAVING"},
+	}
+	for _, clause := range clauses {
+		if clause.used {
+			return fmt.Errorf("%s is not supported", clause.name)
+		}
+	}
+	return nil
+}
+
+// Sort a copy of rows by the ORDER BY terms, each evaluated against the
+// table's columns. Later terms order rows the earlier ones find equal,
+// and rows equal by every term keep their order.
+func (t *table) sortRows(rows [][]memoryCell, terms []*OrderTerm) ([][]memoryCell, error) {
+	for _, term := range terms {
+		if _, err := t.expressionType(term.Expression); err != nil {
+			return nil, err
+		}
+	}
+
+	type keyedRow struct {
+		row  []memoryCell
+		keys []memoryCell
+	}
+	keyed := make([]keyedRow, 0, len(rows))
+	for _, row := range rows {
+		keys := make([]memoryCell, 0, len(terms))
+		for _, term := range terms {
+			key, err := t.evaluateCell(row, term.Expression)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		keyed = append(keyed, keyedRow{row: row, keys: keys})
+	}
+
+	sort.SliceStable(keyed, func(i, j int) bool {
+		for k, term := range terms {
+			c := compareCells(keyed[i].keys[k], keyed[j].keys[k])
+			if term.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	sorted := make([][]memoryCell, 0, len(keyed))
+	for _, k := range keyed {
+		sorted = append(sorted, k.row)
+	}
+	return sorted, nil
+}
+
+// The name of a result column: its alias, the column it selects or the
+// function it calls
+func itemName(item *SelectItem) string {
+	switch {
+	case item.As != nil:
+		return item.As.Value
+	case item.Expression.Kind == LiteralKind && item.Expression.Literal.Kind == IdentifierKind:
+		return item.Expression.Literal.Value
+	case item.Expression.Kind == CallKind:
+		return item.Expression.Call.Name.Value
+	}
+	return "?column?"
+}
+
+// The slice of n rows left after skipping the first offset and keeping
+// at most limit of the rest. Either may be nil to not skip or not limit.
+func limitRange(n int, limit, offset *Token) (start, end int, err error) {
+	end = n
+	if offset != nil {
+		skip, err := strconv.ParseUint(offset.Value, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid OFFSET %s", offset.Value)
+		}
+		if skip < uint64(n) {
+			start = int(skip)
+		} else {
+			start = n
+		}
+	}
+
+	if limit != nil {
+		keep, err := strconv.ParseUint(limit.Value, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid LIMIT %s", limit.Value)
+		}
+		if keep < uint64(end-start) {
+			end = start + int(keep)
+		}
+	}
+	return start, end, nil
+}
+
+// The aggregate functions and the types of the values each accepts. They
+// all skip NULLs, so count(a) counts the rows where a isn't NULL.
+var aggregateArgTypes = map[string][]ColumnType{
+	"count": {TextType, IntType, BoolType, NullType},
+	"sum":   {IntType},
+	// Rounds toward zero, as the only number type is int
+	"avg": {IntType},
+	"min": {TextType, IntType},
+	"max": {TextType, IntType},
+}
+
+func isAggregate(exp *Expression) bool {
+	if exp.Kind != CallKind {
+		return false
+	}
+	_, ok := aggregateArgTypes[exp.Call.Name.Value]
+	return ok
+}
+
+// The sets of expressions a GROUP BY clause groups by, one for each
+// combination of a set from each of its elements. Without a GROUP BY
+// there is just the empty set, which puts every row in one group.
+func groupingSets(elements []*GroupingElement) [][]*Expression {
+	sets := [][]*Expression{{}}
+	for _, element := range elements {
+		var choices [][]*Expression
+		switch element.Kind {
+		case ExpressionGroupingKind:
+			choices = [][]*Expression{{element.Expression}}
+		case RollupGroupingKind:
+			for n := len(element.Expressions); n >= 0; n-- {
+				choices = append(choices, element.Expressions[:n])
+			}
+		case CubeGroupingKind:
+			// Each subset is a bitmask with the first expression as its
+			// highest bit, counting down from all of them to none
+			n := len(element.Expressions)
+			for mask := 1<<n - 1; mask >= 0; mask-- {
+				choice := []*Expression{}
+				for i, exp := range element.Expressions {
+					if mask&(1<<(n-1-i)) != 0 {
+						choice = append(choice, exp)
+					}
+				}
+				choices = append(choices, choice)
+			}
+		case SetsGroupingKind:
+			choices = element.Sets
+		}
+
+		combined := make([][]*Expression, 0, len(sets)*len(choices))
+		for _, set := range sets {
+			for _, choice := range choices {
+				combined = append(combined, append(append([]*Expression{}, set...), choice...))
+			}
+		}
+		sets = combined
+	}
+	return sets
+}
+
+// Group rows by each of sets in turn, giving a result row for each group,
+// before LIMIT and OFFSET apply to them. Items other than aggregate calls
+// must be grouped by or not refer to columns. An expression grouped by in
+// some sets is NULL in the rows of the others, as in the subtotals of a
+// ROLLUP.
+func (t *table) groupResults(rows [][]memoryCell, items []*SelectItem, sets [][]*Expression, limit, offset *Token) (*Results, error) {
+	// Items match the expressions grouped by when they format the same
+	grouped := map[string]bool{}
+	for _, set := range sets {
+		for _, exp := range set {
+			if _, err := t.expressionType(exp); err != nil {
+				return nil, err
+			}
+			grouped[exp.String()] = true
+		}
+	}
+
+	results := &Results{}
+	types := make([]ColumnType, 0, len(items))
+	for _, item := range items {
+		var typ ColumnType
+		var err error
+		if isAggregate(item.Expression) {
+			typ, err = t.aggregateType(item.Expression.Call)
+		} else {
+			column := firstColumn(item.Expression)
+			if column != nil && !grouped[item.Expression.String()] {
+				return nil, fmt.Errorf("Column %s must appear in GROUP BY or be used in an aggregate", column.Value)
+			}
+			typ, err = t.expressionType(item.Expression)
+		}
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, typ)
+		results.Columns = append(results.Columns, ResultColumn{Name: itemName(item), Type: typ})
+	}
+
+	for _, set := range sets {
+		inSet := map[string]bool{}
+		for _, exp := range set {
+			inSet[exp.String()] = true
+		}
+
+		groups, err := t.groupRows(rows, set)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			result := make([]Cell, 0, len(items))
+			for i, item := range items {
+				name := item.Expression.String()
+				switch {
+				case isAggregate(item.Expression):
+					cell, err := t.aggregate(group, item.Expression.Call, types[i])
+					if err != nil {
+						return nil, err
+					}
+					result = append(result, cell)
+				case grouped[name] && !inSet[name]:
+					result = append(result, nullCell(types[i]))
+				default:
+					// The rows of a group all have the same value, and an
+					// item without columns has one without a row
+					var row []memoryCell
+					if len(group) > 0 {
+						row = group[0]
+					}
+					cell, err := t.evaluateCell(row, item.Expression)
+					if err != nil {
+						return nil, err
+					}
+					result = append(result, cell)
+				}
+			}
+			results.Rows = append(results.Rows, result)
+		}
+	}
+
+	start, end, err := limitRange(len(results.Rows), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	results.Rows = results.Rows[start:end]
+	return results, nil
+}
+
+// Split rows into groups with equal values of exps, in the order each
+// group's first row appears. NULLs are equal to each other here. With no
+// exps every row is in one group, which there is even without rows.
+func (t *table) groupRows(rows [][]memoryCell, exps []*Expression) ([][][]memoryCell, error) {
+	if len(exps) == 0 {
+		return [][][]memoryCell{rows}, nil
+	}
+
+	var keys [][]memoryCell
+	var groups [][][]memoryCell
+	for _, row := range rows {
+		key := make([]memoryCell, 0, len(exps))
+		for _, exp := range exps {
+			cell, err := t.evaluateCell(row, exp)
+			if err != nil {
+				return nil, err
+			}
+			key = append(key, cell)
+		}
+
+		i := 0
+		for i < len(keys) && !equalCells(keys[i], key) {
+			i++
+		}
+		if i == len(keys) {
+			keys = append(keys, key)
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return groups, nil
+}
+
+// Finds the first column an expression refers to
+type columnFinder struct {
+	BaseVisitor
+	column *Token
+}
+
+func (f *columnFinder) VisitExpression(e *Expression) error {
+	if e.Kind == LiteralKind && e.Literal.Kind == IdentifierKind {
+		f.column = e.Literal
+		return StopWalk
+	}
+	return nil
+}
+
+// The first column exp refers to, or nil if it doesn't
+func firstColumn(exp *Expression) *Token {
+	f := columnFinder{}
+	Walk(exp, &f)
+	return f.column
+}
+
+// The type an aggregate call gives, checking its argument
+func (t *table) aggregateType(call *CallExpression) (ColumnType, error) {
+	name := call.Name.Value
+	if call.Asterisk {
+		if name != "count" {
+			return 0, fmt.Errorf("Cannot apply %s to *", name)
+		}
+		return IntType, nil
+	}
+	if len(call.Args) != 1 {
+		return 0, fmt.Errorf("Expected 1 argument to %s, got %d", name, len(call.Args))
+	}
+
+	typ, err := t.expressionType(call.Args[0])
+	if err != nil {
+		return 0, err
+	}
+	for _, accepted := range aggregateArgTypes[name] {
+		if typ == accepted {
+			if name == "count" {
+				return IntType, nil
+			}
+			return typ, nil
+		}
+	}
+	return 0, fmt.Errorf("Cannot apply %s to %s", name, typ)
+}
+
+// The value of an aggregate call over rows, which must have been checked
+// by aggregateType giving typ
+func (t *table) aggregate(rows [][]memoryCell, call *CallExpression, typ ColumnType) (Cell, error) {
+	name := call.Name.Value
+	if call.Asterisk {
+		return memoryCell{typ: IntType, i: int64(len(rows))}, nil
+	}
+
+	var result memoryCell
+	n := 0
+	for _, row := range rows {
+		cell, err := t.evaluateCell(row, call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if cell.null {
+			continue
+		}
+
+		n++
+		switch {
+		case n == 1:
+			result = cell
+		case name == "sum" || name == "avg":
+			result.i += cell.i
+		case name == "min" && compareCells(cell, result) < 0:
+			result = cell
+		case name == "max" && compareCells(cell, result) > 0:
+			result = cell
+		}
+	}
+
+	switch {
+	case name == "count":
+		return memoryCell{typ: IntType, i: int64(n)}, nil
+	case n == 0:
+		return nullCell(typ), nil
+	case name == "avg":
+		result.i /= int64(n)
+	}
+	return result, nil
+}
+
+// The index of the column an identifier refers to. An unqualified name
+// must only match one column of the tables selected from.
+func (t *table) columnIndex(exp *Expression) (int, error) {
+	index := -1
+	for i := range t.columns {
+		if !t.columnMatches(i, exp) {
+			continue
+		}
+		if index >= 0 {
+			return 0, fmt.Errorf("Column %s is ambiguous", exp.Literal.Value)
+		}
+		index = i
+	}
+
+	if index < 0 {
+		return 0, fmt.Errorf("Column %s does not exist", exp)
+	}
+	return index, nil
+}
+
+// Whether the identifier exp names the table's ith column
+func (t *table) columnMatches(i int, exp *Expression) bool {
+	if t.columns[i] != exp.Literal.Value {
+		return false
+	}
+	return exp.Qualifier == nil || (t.qualifiers != nil && t.qualifiers[i] == exp.Qualifier.Value)
+}
+
+// Whether an identifier refers to an outer column rather than one of the
+// table's own, which hide outer columns of the same name
+func (t *table) isOuterColumn(exp *Expression) bool {
+	if t.outer == nil {
+		return false
+	}
+	for i := range t.columns {
+		if t.columnMatches(i, exp) {
+			return false
+		}
+	}
+	return true
+}
+
+// Comparison operators and whether they hold given the sign of comparing
+// their operands
+var comparisons = map[string]func(int) bool{
+	string(EqualsSymbol):             func(c int) bool { return c == 0 },
+	string(NotEqualSymbol):           func(c int) bool { return c != 0 },
+	string(BangEqualSymbol):          func(c int) bool { return c != 0 },
+	string(LessThanSymbol):           func(c int) bool { return c < 0 },
+	string(GreaterThanSymbol):        func(c int) bool { return c > 0 },
+	string(LessThanOrEqualSymbol):    func(c int) bool { return c <= 0 },
+	string(GreaterThanOrEqualSymbol): func(c int) bool { return c >= 0 },
+}
+
+// The type of the cells exp evaluates to, checking the columns it
+// references exist and its operators are applied to the right types
+func (t *table) expressionType(exp *Expression) (ColumnType, error) {
+	switch exp.Kind {
+	case LiteralKind:
+		if exp.Literal.Kind == IdentifierKind {
+			if t.isOuterColumn(exp) {
+				return t.outer.expressionType(exp)
+			}
+			i, err := t.columnIndex(exp)
+			if err != nil {
+				return 0, err
+			}
+			return t.columnTypes[i], nil
+		}
+
+		cell, err := literalToCell(exp.Literal)
+		return cell.typ, err
+	case UnaryKind:
+		typ, err := t.expressionType(exp.Unary.Expression)
+		if err != nil {
+			return 0, err
+		}
+		if exp.Unary.Op.Kind == SymbolKind {
+			if typ != IntType && typ != NullType {
+				return 0, fmt.Errorf("Cannot apply %s to %s", exp.Unary.Op.Value, typ)
+			}
+			return IntType, nil
+		}
+		if !isPredicate(typ) {
+			return 0, fmt.Errorf("Cannot apply %s to %s", exp.Unary.Op.Value, typ)
+		}
+		return BoolType, nil
+	case IsNullKind:
+		if _, err := t.expressionType(exp.IsNull.Expression); err != nil {
+			return 0, err
+		}
+		return BoolType, nil
+	case BinaryKind:
+		a, err := t.expressionType(exp.Binary.A)
+		if err != nil {
+			return 0, err
+		}
+		b, err := t.expressionType(exp.Binary.B)
+		if err != nil {
+			return 0, err
+		}
+
+		op := exp.Binary.Op.Value
+		switch {
+		case op == string(AndKeyword) || op == string(OrKeyword):
+			if !isPredicate(a) || !isPredicate(b) {
+				return 0, fmt.Errorf("Cannot apply %s to %s and %s", op, a, b)
+			}
+		case comparisons[op] != nil:
+			if a != b && a != NullType && b != NullType {
+				return 0, fmt.Errorf("Cannot compare %s and %s", a, b)
+			}
+		default:
+			return 0, fmt.Errorf("Unsupported operator %s", op)
+		}
+		return BoolType, nil
+	}
+
+	return 0, fmt.Errorf("Unsupported expression %s", exp)
+}
+
+// Whether an expression of type typ can be used as a condition. NULL can,
+// and holds for no rows.
+func isPredicate(typ ColumnType) bool {
+	return typ == BoolType || typ == NullType
+}
+
+// The value of exp for row. The expression must have been checked by
+// expressionType. NULL is unknown so, as in SQL's three-valued logic,
+// comparing it gives NULL, as do AND and OR unless the other operand
+// decides the result alone.
+func (t *table) evaluateCell(row []memoryCell, exp *Expression) (memoryCell, error) {
+	switch exp.Kind {
+	case LiteralKind:
+		if exp.Literal.Kind == IdentifierKind {
+			if t.isOuterColumn(exp) {
+				return t.outer.evaluateCell(t.outerRow, exp)
+			}
+			i, err := t.columnIndex(exp)
+			if err != nil {
+				return memoryCell{}, err
+			}
+			return row[i], nil
+		}
+
+		return literalToCell(exp.Literal)
+	case UnaryKind:
+		cell, err := t.evaluateCell(row, exp.Unary.Expression)
+		if err != nil {
+			return memoryCell{}, err
+		}
+		switch exp.Unary.Op.Value {
+		case string(MinusSymbol):
+			if cell.null {
+				return nullCell(IntType), nil
+			}
+			return memoryCell{typ: IntType, i: -cell.i}, nil
+		case string(PlusSymbol):
+			if cell.null {
+				return nullCell(IntType), nil
+			}
+			return memoryCell{typ: IntType, i: cell.i}, nil
+		}
+		if cell.null {
+			return nullCell(BoolType), nil
+		}
+		return boolCell(!cell.boolean), nil
+	case IsNullKind:
+		cell, err := t.evaluateCell(row, exp.IsNull.Expression)
+		if err != nil {
+			return memoryCell{}, err
+		}
+		return boolCell(cell.null != exp.IsNull.Not), nil
+	case BinaryKind:
+		a, err := t.evaluateCell(row, exp.Binary.A)
+		if err != nil {
+			return memoryCell{}, err
+		}
+		b, err := t.evaluateCell(row, exp.Binary.B)
+		if err != nil {
+			return memoryCell{}, err
+		}
+
+		switch op := exp.Binary.Op.Value; op {
+		case string(AndKeyword):
+			if (!a.null && !a.boolean) || (!b.null && !b.boolean) {
+				return boolCell(false), nil
+			}
+			if a.null || b.null {
+				return nullCell(BoolType), nil
+			}
+			return boolCell(true), nil
+		case string(OrKeyword):
+			if (!a.null && a.boolean) || (!b.null && b.boolean) {
+				return boolCell(true), nil
+			}
+			if a.null || b.null {
+				return nullCell(BoolType), nil
+			}
+			return boolCell(false), nil
+		default:
+			if holds, ok := comparisons[op]; ok {
+				if a.null || b.null {
+					return nullCell(BoolType), nil
+				}
+				return boolCell(holds(compareCells(a, b))), nil
+			}
+		}
+		return memoryCell{}, fmt.Errorf("Unsupported operator %s", exp.Binary.Op.Value)
+	}
+
+	return memoryCell{}, fmt.Errorf("Unsupported expression %s", exp)
+}
+
+func boolCell(b bool) memoryCell {
+	return memoryCell{typ: BoolType, boolean: b}
+}
+
+func nullCell(typ ColumnType) memoryCell {
+	return memoryCell{typ: typ, null: true}
+}
+
+// Whether each cell of a compares equal to the cell of b at its position
+func equalCells(a, b []memoryCell) bool {
+	for i := range a {
+		if compareCells(a[i], b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// -1, 0 or 1 as a is less than, equal to or greater than b, which are
+// the same type. Ints compare numerically, text lexicographically and
+// false before true. NULLs are equal to each other and greater than
+// everything else, so they sort last, or first with DESC.
+func compareCells(a, b memoryCell) int {
+	switch {
+	case a.null && b.null:
+		return 0
+	case a.null:
+		return 1
+	case b.null:
+		return -1
+	}
+
+	switch a.typ {
+	case IntType:
+		switch {
+		case a.i < b.i:
+			return -1
+		case a.i > b.i:
+			return 1
+		}
+		return 0
+	case BoolType:
+		switch {
+		case a.boolean == b.boolean:
+			return 0
+		case b.boolean:
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.text, b.text)
+}
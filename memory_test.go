@@ -0,0 +1,1265 @@
+package gosql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Run each statement of source against mb, failing the test on any error
+func mustExecute(t *testing.T, mb *MemoryBackend, source string) {
+	t.Helper()
+	tokens, err := Lex(source)
+	if !assert.Nil(t, err, source) {
+		return
+	}
+	ast, err := Parse(tokens)
+	if !assert.Nil(t, err, source) {
+		return
+	}
+	for _, stmt := range ast.Statements {
+		switch stmt.Kind {
+		case CreateTableKind:
+			assert.Nil(t, mb.CreateTable(stmt.CreateTableStatement), source)
+		case CreateViewKind:
+			assert.Nil(t, mb.CreateView(stmt.CreateViewStatement), source)
+		case InsertKind:
+			assert.Nil(t, mb.Insert(stmt.InsertStatement), source)
+		}
+	}
+}
+
+// Parse a single statement of source
+func mustParse(t *testing.T, source string) *Statement {
+	t.Helper()
+	tokens, err := Lex(source)
+	assert.Nil(t, err, source)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err, source)
+	return ast.Statements[0]
+}
+
+func TestMemoryBackend_CreateTableInsert(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (1, 'George');
+		INSERT INTO users VALUES (2, 'it''s');
+		INSERT INTO users VALUES (3, 'a'), ('4', 'b');
+	`)
+
+	users := mb.tables["users"]
+	if !assert.NotNil(t, users) {
+		return
+	}
+	assert.Equal(t, []string{"id", "name"}, users.columns)
+	assert.Equal(t, []ColumnType{IntType, TextType}, users.columnTypes)
+	assert.Equal(t, [][]memoryCell{
+		{{typ: IntType, i: 1}, {typ: TextType, text: "George"}},
+		{{typ: IntType, i: 2}, {typ: TextType, text: "it's"}},
+		{{typ: IntType, i: 3}, {typ: TextType, text: "a"}},
+		{{typ: IntType, i: 4}, {typ: TextType, text: "b"}},
+	}, users.rows)
+}
+
+func TestMemoryBackend_CreateTable_errors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, "CREATE TABLE users (id INT);")
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "CREATE TABLE users (id INT);",
+			err:    "Table users already exists",
+		},
+		{
+			source: "CREATE TABLE t (id INT, id TEXT);",
+			err:    "Duplicate column id",
+		},
+		{
+			source: "CREATE TABLE t (id INT, at TIMESTAMP);",
+			err:    "Unsupported type TIMESTAMP for column at",
+		},
+	}
+
+	for _, test := range tests {
+		err := mb.CreateTable(mustParse(t, test.source).CreateTableStatement)
+		if assert.NotNil(t, err, test.source) {
+			assert.Equal(t, test.err, err.Error(), test.source)
+		}
+	}
+	// Nothing was created by the failed statements
+	assert.Equal(t, 1, len(mb.tables))
+}
+
+func TestMemoryBackend_tableErrors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, "CREATE TABLE users (id INT);")
+
+	err := mb.CreateTable(mustParse(t, "CREATE TABLE users (name TEXT);").CreateTableStatement)
+	var exists *ErrTableExists
+	if assert.True(t, errors.As(err, &exists)) {
+		assert.Equal(t, "users", exists.Name)
+	}
+
+	tests := []struct {
+		source string
+		name   string
+	}{
+		{
+			source: "SELECT id FROM scores;",
+			name:   "scores",
+		},
+		{
+			source: "SELECT users.id FROM users JOIN teams ON users.id = teams.id;",
+			name:   "teams",
+		},
+		{
+			source: "INSERT INTO nope VALUES (1);",
+			name:   "nope",
+		},
+	}
+
+	for _, test := range tests {
+		stmt := mustParse(t, test.source)
+		if stmt.Kind == InsertKind {
+			err = mb.Insert(stmt.InsertStatement)
+		} else {
+			_, err = mb.Select(stmt.SelectStatement)
+		}
+
+		var notFound *ErrTableNotFound
+		if assert.True(t, errors.As(err, &notFound), test.source) {
+			assert.Equal(t, test.name, notFound.Name, test.source)
+			assert.Equal(t, "Table "+test.name+" does not exist", err.Error(), test.source)
+		}
+	}
+}
+
+func TestMemoryBackend_CreateView(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (1, 'George'), (2, 'John'), (3, 'Paul');
+		CREATE VIEW named AS SELECT id, name FROM users WHERE id > 1;
+	`)
+
+	selectNamed := func(source string) *Results {
+		results, err := mb.Select(mustParse(t, source).SelectStatement)
+		assert.Nil(t, err, source)
+		return results
+	}
+
+	results := selectNamed("SELECT n.name FROM named n WHERE id < 3;")
+	assert.Equal(t, []ResultColumn{{"name", TextType}}, results.Columns)
+	assert.Equal(t, [][]Cell{{textCell("John")}}, results.Rows)
+
+	// The view is run again each time, so it sees new rows
+	mustExecute(t, mb, "INSERT INTO users VALUES (4, 'Ringo');")
+	results = selectNamed("SELECT count(*) FROM named;")
+	assert.Equal(t, [][]Cell{{intCell(3)}}, results.Rows)
+
+	err := mb.CreateView(mustParse(t, "CREATE VIEW named AS SELECT id FROM users;").CreateViewStatement)
+	var exists *ErrViewExists
+	if assert.True(t, errors.As(err, &exists)) {
+		assert.Equal(t, "named", exists.Name)
+		assert.Equal(t, "View named already exists", err.Error())
+	}
+
+	mustExecute(t, mb, "CREATE OR REPLACE VIEW named AS SELECT name, id AS num FROM users WHERE id < 3;")
+	results = selectNamed("SELECT * FROM named;")
+	assert.Equal(t, []ResultColumn{{"name", TextType}, {"num", IntType}}, results.Columns)
+	assert.Equal(t, [][]Cell{
+		{textCell("George"), intCell(1)},
+		{textCell("John"), intCell(2)},
+	}, results.Rows)
+
+	// Views can be built on other views and joined like tables
+	mustExecute(t, mb, "CREATE VIEW first AS SELECT num FROM named WHERE name = 'George';")
+	results = selectNamed("SELECT users.name FROM first JOIN users ON first.num = users.id;")
+	assert.Equal(t, [][]Cell{{textCell("George")}}, results.Rows)
+}
+
+func TestMemoryBackend_CreateView_errors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT);
+		CREATE VIEW ids AS SELECT id FROM users;
+		CREATE VIEW more AS SELECT id FROM ids;
+	`)
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "CREATE VIEW users AS SELECT 1;",
+			err:    "Table users already exists",
+		},
+		{
+			source: "CREATE OR REPLACE VIEW users AS SELECT 1;",
+			err:    "Table users already exists",
+		},
+		{
+			source: "CREATE TABLE ids (id INT);",
+			err:    "View ids already exists",
+		},
+		{
+			source: "CREATE VIEW scores AS SELECT score FROM nope;",
+			err:    "Table nope does not exist",
+		},
+		{
+			source: "CREATE VIEW scores AS SELECT score FROM users;",
+			err:    "Column score does not exist",
+		},
+		{
+			source: "CREATE OR REPLACE VIEW ids AS SELECT id FROM ids;",
+			err:    "View ids cannot select from itself",
+		},
+		{
+			source: "CREATE OR REPLACE VIEW ids AS SELECT id FROM more;",
+			err:    "View ids cannot select from itself",
+		},
+	}
+
+	for _, test := range tests {
+		stmt := mustParse(t, test.source)
+		var err error
+		if stmt.Kind == CreateTableKind {
+			err = mb.CreateTable(stmt.CreateTableStatement)
+		} else {
+			err = mb.CreateView(stmt.CreateViewStatement)
+		}
+		assert.EqualError(t, err, test.err, test.source)
+	}
+
+	// A failed replacement keeps the old definition
+	results, err := mb.Select(mustParse(t, "SELECT id FROM more;").SelectStatement)
+	assert.Nil(t, err)
+	assert.Equal(t, []ResultColumn{{"id", IntType}}, results.Columns)
+}
+
+func TestMemoryBackend_Insert_errors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, "CREATE TABLE users (id INT, name TEXT);")
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "INSERT INTO nope VALUES (1, 'a');",
+			err:    "Table nope does not exist",
+		},
+		{
+			source: "INSERT INTO users VALUES (1);",
+			err:    "Expected 2 values for table users, got 1",
+		},
+		{
+			source: "INSERT INTO users VALUES (1, 'a', 'b');",
+			err:    "Expected 2 values for table users, got 3",
+		},
+		{
+			source: "INSERT INTO users VALUES (1.5, 'a');",
+			err:    "Unsupported number 1.5, only integers are supported",
+		},
+		{
+			source: "INSERT INTO users VALUES ('abc', 'a');",
+			err:    "Invalid int 'abc' for column id",
+		},
+		{
+			source: "INSERT INTO users VALUES ('1.5', 'a');",
+			err:    "Invalid int '1.5' for column id",
+		},
+		{
+			source: "INSERT INTO users VALUES (1, 2);",
+			err:    "Cannot store int as text for column name",
+		},
+		{
+			source: "INSERT INTO users VALUES (1, name);",
+			err:    "Cannot use name as a value",
+		},
+		{
+			source: "INSERT INTO users VALUES (1 + 1, 'a');",
+			err:    "Only literal values can be inserted",
+		},
+		{
+			// The good first row isn't inserted either
+			source: "INSERT INTO users VALUES (1, 'a'), ('b', 'c');",
+			err:    "Invalid int 'b' for column id",
+		},
+	}
+
+	for _, test := range tests {
+		err := mb.Insert(mustParse(t, test.source).InsertStatement)
+		if assert.NotNil(t, err, test.source) {
+			assert.Equal(t, test.err, err.Error(), test.source)
+		}
+	}
+	assert.Equal(t, 0, len(mb.tables["users"].rows))
+}
+
+func intCell(i int64) memoryCell {
+	return memoryCell{typ: IntType, i: i}
+}
+
+func textCell(text string) memoryCell {
+	return memoryCell{typ: TextType, text: text}
+}
+
+// The first column of every row, as ints
+func firstColumnInts(results *Results) []int64 {
+	ints := []int64{}
+	for _, row := range results.Rows {
+		ints = append(ints, row[0].AsInt())
+	}
+	return ints
+}
+
+func TestMemoryBackend_Select(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT, age INT);
+		INSERT INTO users VALUES (1, 'George', 30);
+		INSERT INTO users VALUES (2, 'Ringo', 25);
+	`)
+
+	tests := []struct {
+		source  string
+		columns []ResultColumn
+		rows    [][]Cell
+	}{
+		{
+			source:  "SELECT * FROM users;",
+			columns: []ResultColumn{{"id", IntType}, {"name", TextType}, {"age", IntType}},
+			rows: [][]Cell{
+				{intCell(1), textCell("George"), intCell(30)},
+				{intCell(2), textCell("Ringo"), intCell(25)},
+			},
+		},
+		{
+			source:  "SELECT age, id FROM users;",
+			columns: []ResultColumn{{"age", IntType}, {"id", IntType}},
+			rows: [][]Cell{
+				{intCell(30), intCell(1)},
+				{intCell(25), intCell(2)},
+			},
+		},
+		{
+			source:  "SELECT name AS n, *, 'x' FROM users;",
+			columns: []ResultColumn{{"n", TextType}, {"id", IntType}, {"name", TextType}, {"age", IntType}, {"?column?", TextType}},
+			rows: [][]Cell{
+				{textCell("George"), intCell(1), textCell("George"), intCell(30), textCell("x")},
+				{textCell("Ringo"), intCell(2), textCell("Ringo"), intCell(25), textCell("x")},
+			},
+		},
+		{
+			source:  "SELECT 1, 'one';",
+			columns: []ResultColumn{{"?column?", IntType}, {"?column?", TextType}},
+			rows:    [][]Cell{{intCell(1), textCell("one")}},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.columns, results.Columns, test.source)
+		assert.Equal(t, test.rows, results.Rows, test.source)
+	}
+}
+
+func TestMemoryBackend_Select_asteriskOrder(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE t (zeta INT, alpha TEXT, mid INT);
+		INSERT INTO t VALUES (1, 'a', 2);
+		CREATE TABLE u (c INT, b INT, a TEXT);
+		INSERT INTO u VALUES (3, 4, 'b');
+	`)
+
+	tests := []struct {
+		source  string
+		columns []string
+	}{
+		{
+			source:  "SELECT * FROM t;",
+			columns: []string{"zeta", "alpha", "mid"},
+		},
+		{
+			source:  "SELECT mid, *, zeta FROM t;",
+			columns: []string{"mid", "zeta", "alpha", "mid", "zeta"},
+		},
+		{
+			// The left table's columns come first
+			source:  "SELECT * FROM u JOIN t ON zeta < c;",
+			columns: []string{"c", "b", "a", "zeta", "alpha", "mid"},
+		},
+		{
+			source:  "SELECT * FROM t JOIN u ON zeta < c;",
+			columns: []string{"zeta", "alpha", "mid", "c", "b", "a"},
+		},
+	}
+
+	for _, test := range tests {
+		// The same every time, not left to map iteration order
+		for i := 0; i < 10; i++ {
+			results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+			if !assert.Nil(t, err, test.source) {
+				break
+			}
+			columns := []string{}
+			for _, col := range results.Columns {
+				columns = append(columns, col.Name)
+			}
+			assert.Equal(t, test.columns, columns, test.source)
+		}
+	}
+}
+
+func TestMemoryBackend_Select_duplicateColumns(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE t (a INT, b TEXT);
+		INSERT INTO t VALUES (1, 'x');
+		CREATE TABLE u (a TEXT, c INT);
+		INSERT INTO u VALUES ('y', 2);
+	`)
+
+	tests := []struct {
+		source  string
+		columns []ResultColumn
+		rows    [][]Cell
+	}{
+		{
+			source:  "SELECT a, b AS a, a FROM t;",
+			columns: []ResultColumn{{"a", IntType}, {"a", TextType}, {"a", IntType}},
+			rows:    [][]Cell{{intCell(1), textCell("x"), intCell(1)}},
+		},
+		{
+			// Both tables have an a
+			source:  "SELECT * FROM t JOIN u ON c > 1;",
+			columns: []ResultColumn{{"a", IntType}, {"b", TextType}, {"a", TextType}, {"c", IntType}},
+			rows:    [][]Cell{{intCell(1), textCell("x"), textCell("y"), intCell(2)}},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.columns, results.Columns, test.source)
+		assert.Equal(t, test.rows, results.Rows, test.source)
+
+		// Lookup by name finds the first of them
+		i, ok := results.ColumnIndex("a")
+		assert.True(t, ok, test.source)
+		assert.Equal(t, 0, i, test.source)
+		_, ok = results.ColumnIndex("d")
+		assert.False(t, ok, test.source)
+	}
+}
+
+func TestMemoryBackend_Select_errors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, "CREATE TABLE users (id INT, name TEXT);")
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "SELECT id FROM nope;",
+			err:    "Table nope does not exist",
+		},
+		{
+			source: "SELECT id, email FROM users;",
+			err:    "Column email does not exist",
+		},
+		{
+			source: "SELECT DISTINCT name FROM users;",
+			err:    "DISTINCT is not supported",
+		},
+		{
+			source: "SELECT * FROM users ORDER BY email;",
+			err:    "Column email does not exist",
+		},
+		{
+			source: "SELECT * FROM users ORDER BY id AND name;",
+			err:    "Cannot apply and to int and text",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if assert.NotNil(t, err, test.source) {
+			assert.Equal(t, test.err, err.Error(), test.source)
+		}
+	}
+}
+
+func TestMemoryBackend_Select_orderBy(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT, age INT);
+		INSERT INTO users VALUES (1, 'Ringo', 30), (2, 'George', 25), (3, 'John', 30), (4, 'Paul', 25), (10, 'George', 40);
+	`)
+
+	tests := []struct {
+		source string
+		ids    []int64
+	}{
+		{
+			source: "SELECT id FROM users ORDER BY name;",
+			// Equal names keep their inserted order
+			ids: []int64{2, 10, 3, 4, 1},
+		},
+		{
+			source: "SELECT id FROM users ORDER BY id DESC;",
+			ids:    []int64{10, 4, 3, 2, 1},
+		},
+		{
+			source: "SELECT id FROM users ORDER BY age DESC, name;",
+			ids:    []int64{10, 3, 1, 2, 4},
+		},
+		{
+			source: "SELECT id FROM users ORDER BY age, name DESC;",
+			ids:    []int64{4, 2, 1, 3, 10},
+		},
+		{
+			source: "SELECT id FROM users WHERE age = 30 ORDER BY age = 30, id DESC;",
+			ids:    []int64{3, 1},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.ids, firstColumnInts(results), test.source)
+	}
+
+	// By a column that isn't selected
+	results, err := mb.Select(mustParse(t, "SELECT name FROM users ORDER BY id DESC;").SelectStatement)
+	assert.Nil(t, err)
+	assert.Equal(t, textCell("George"), results.Rows[0][0])
+	assert.Equal(t, textCell("Ringo"), results.Rows[4][0])
+
+	// Sorting doesn't reorder the table
+	results, err = mb.Select(mustParse(t, "SELECT id FROM users;").SelectStatement)
+	assert.Nil(t, err)
+	assert.Equal(t, intCell(1), results.Rows[0][0])
+}
+
+func TestMemoryBackend_Select_limit(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE t (id INT);
+		INSERT INTO t VALUES (1), (2), (3), (4), (5);
+	`)
+
+	tests := []struct {
+		source string
+		ids    []int64
+	}{
+		{
+			source: "SELECT id FROM t LIMIT 2;",
+			ids:    []int64{1, 2},
+		},
+		{
+			source: "SELECT id FROM t LIMIT 10;",
+			ids:    []int64{1, 2, 3, 4, 5},
+		},
+		{
+			source: "SELECT id FROM t LIMIT 0;",
+			ids:    []int64{},
+		},
+		{
+			source: "SELECT id FROM t OFFSET 3;",
+			ids:    []int64{4, 5},
+		},
+		{
+			source: "SELECT id FROM t LIMIT 2 OFFSET 1;",
+			ids:    []int64{2, 3},
+		},
+		{
+			source: "SELECT id FROM t OFFSET 5;",
+			ids:    []int64{},
+		},
+		{
+			source: "SELECT id FROM t LIMIT 2 OFFSET 99;",
+			ids:    []int64{},
+		},
+		{
+			// After filtering and ordering
+			source: "SELECT id FROM t WHERE id <> 4 ORDER BY id DESC LIMIT 2 OFFSET 1;",
+			ids:    []int64{3, 2},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.ids, firstColumnInts(results), test.source)
+	}
+
+	_, err := mb.Select(mustParse(t, "SELECT id FROM t LIMIT 99999999999999999999;").SelectStatement)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Invalid LIMIT 99999999999999999999", err.Error())
+	}
+}
+
+func TestMemoryBackend_Select_aggregates(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT, age INT);
+		INSERT INTO users VALUES (1, 'Ringo', 30), (2, 'George', 25), (3, 'John', 40), (4, 'Paul', 28);
+		CREATE TABLE empty (id INT);
+	`)
+
+	tests := []struct {
+		source  string
+		columns []ResultColumn
+		rows    [][]Cell
+	}{
+		{
+			source:  "SELECT count(*) FROM users;",
+			columns: []ResultColumn{{"count", IntType}},
+			rows:    [][]Cell{{intCell(4)}},
+		},
+		{
+			source:  "SELECT count(name), max(age), min(age), sum(age) AS total, avg(age) FROM users;",
+			columns: []ResultColumn{{"count", IntType}, {"max", IntType}, {"min", IntType}, {"total", IntType}, {"avg", IntType}},
+			rows:    [][]Cell{{intCell(4), intCell(40), intCell(25), intCell(123), intCell(30)}},
+		},
+		{
+			source:  "SELECT min(name), max(name) FROM users WHERE id <> 2;",
+			columns: []ResultColumn{{"min", TextType}, {"max", TextType}},
+			rows:    [][]Cell{{textCell("John"), textCell("Ringo")}},
+		},
+		{
+			// Constants don't need to be aggregated
+			source:  "SELECT 'total', count(*) FROM users WHERE age > 100;",
+			columns: []ResultColumn{{"?column?", TextType}, {"count", IntType}},
+			rows:    [][]Cell{{textCell("total"), intCell(0)}},
+		},
+		{
+			// Aggregates of no rows are NULL, except count
+			source:  "SELECT count(*), max(id), sum(id) FROM empty;",
+			columns: []ResultColumn{{"count", IntType}, {"max", IntType}, {"sum", IntType}},
+			rows:    [][]Cell{{intCell(0), nullCell(IntType), nullCell(IntType)}},
+		},
+		{
+			// LIMIT and OFFSET apply to the single result row
+			source:  "SELECT count(*) FROM users LIMIT 1;",
+			columns: []ResultColumn{{"count", IntType}},
+			rows:    [][]Cell{{intCell(4)}},
+		},
+		{
+			source:  "SELECT count(*) FROM users OFFSET 1;",
+			columns: []ResultColumn{{"count", IntType}},
+			rows:    [][]Cell{},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.columns, results.Columns, test.source)
+		assert.Equal(t, test.rows, results.Rows, test.source)
+	}
+}
+
+func TestMemoryBackend_Select_aggregateErrors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, "CREATE TABLE users (id INT, name TEXT, age INT);")
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "SELECT name, count(*) FROM users;",
+			err:    "Column name must appear in GROUP BY or be used in an aggregate",
+		},
+		{
+			source: "SELECT max(age), *, count(*) FROM users;",
+			err:    "Column id must appear in GROUP BY or be used in an aggregate",
+		},
+		{
+			source: "SELECT sum(name) FROM users;",
+			err:    "Cannot apply sum to text",
+		},
+		{
+			source: "SELECT avg(name) FROM users;",
+			err:    "Cannot apply avg to text",
+		},
+		{
+			source: "SELECT max(age = 1) FROM users;",
+			err:    "Cannot apply max to boolean",
+		},
+		{
+			source: "SELECT sum(*) FROM users;",
+			err:    "Cannot apply sum to *",
+		},
+		{
+			source: "SELECT max(id, age) FROM users;",
+			err:    "Expected 1 argument to max, got 2",
+		},
+		{
+			source: "SELECT count(email) FROM users;",
+			err:    "Column email does not exist",
+		},
+		{
+			source: "SELECT count(*) FROM users ORDER BY id;",
+			err:    "ORDER BY is not supported with aggregates",
+		},
+		{
+			source: "SELECT name, age FROM users GROUP BY ROLLUP (name);",
+			err:    "Column age must appear in GROUP BY or be used in an aggregate",
+		},
+		{
+			source: "SELECT name FROM users GROUP BY name ORDER BY name;",
+			err:    "ORDER BY is not supported with GROUP BY",
+		},
+		{
+			source: "SELECT count(*) FROM users GROUP BY CUBE (email);",
+			err:    "Column email does not exist",
+		},
+		{
+			source: "SELECT id FROM users WHERE count(*) = 1;",
+			err:    "Unsupported expression count(*)",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if assert.NotNil(t, err, test.source) {
+			assert.Equal(t, test.err, err.Error(), test.source)
+		}
+	}
+}
+
+func TestMemoryBackend_Select_groupBy(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE sales (region TEXT, product TEXT, amount INT);
+		INSERT INTO sales VALUES ('east', 'apple', 10), ('east', 'pear', 5), ('west', 'apple', 7), ('east', 'apple', 3);
+		CREATE TABLE empty (id INT);
+	`)
+
+	tests := []struct {
+		source  string
+		columns []ResultColumn
+		rows    [][]Cell
+	}{
+		{
+			// Groups come in the order their first row appears
+			source:  "SELECT region, product, sum(amount) FROM sales GROUP BY region, product;",
+			columns: []ResultColumn{{"region", TextType}, {"product", TextType}, {"sum", IntType}},
+			rows: [][]Cell{
+				{textCell("east"), textCell("apple"), intCell(13)},
+				{textCell("east"), textCell("pear"), intCell(5)},
+				{textCell("west"), textCell("apple"), intCell(7)},
+			},
+		},
+		{
+			// The detail rows, then the subtotal of each region and the
+			// grand total, with NULL in the columns rolled up
+			source:  "SELECT region, product, sum(amount) FROM sales GROUP BY ROLLUP (region, product);",
+			columns: []ResultColumn{{"region", TextType}, {"product", TextType}, {"sum", IntType}},
+			rows: [][]Cell{
+				{textCell("east"), textCell("apple"), intCell(13)},
+				{textCell("east"), textCell("pear"), intCell(5)},
+				{textCell("west"), textCell("apple"), intCell(7)},
+				{textCell("east"), nullCell(TextType), intCell(18)},
+				{textCell("west"), nullCell(TextType), intCell(7)},
+				{nullCell(TextType), nullCell(TextType), intCell(25)},
+			},
+		},
+		{
+			source:  "SELECT region, product, count(*) FROM sales GROUP BY CUBE (region, product);",
+			columns: []ResultColumn{{"region", TextType}, {"product", TextType}, {"count", IntType}},
+			rows: [][]Cell{
+				{textCell("east"), textCell("apple"), intCell(2)},
+				{textCell("east"), textCell("pear"), intCell(1)},
+				{textCell("west"), textCell("apple"), intCell(1)},
+				{textCell("east"), nullCell(TextType), intCell(3)},
+				{textCell("west"), nullCell(TextType), intCell(1)},
+				{nullCell(TextType), textCell("apple"), intCell(3)},
+				{nullCell(TextType), textCell("pear"), intCell(1)},
+				{nullCell(TextType), nullCell(TextType), intCell(4)},
+			},
+		},
+		{
+			source:  "SELECT 'by', product, sum(amount) FROM sales WHERE amount > 4 GROUP BY GROUPING SETS ((product), ());",
+			columns: []ResultColumn{{"?column?", TextType}, {"product", TextType}, {"sum", IntType}},
+			rows: [][]Cell{
+				{textCell("by"), textCell("apple"), intCell(17)},
+				{textCell("by"), textCell("pear"), intCell(5)},
+				{textCell("by"), nullCell(TextType), intCell(22)},
+			},
+		},
+		{
+			// Each region is combined with each set of the rollup
+			source:  "SELECT region, product FROM sales GROUP BY region, ROLLUP (product) LIMIT 2 OFFSET 2;",
+			columns: []ResultColumn{{"region", TextType}, {"product", TextType}},
+			rows: [][]Cell{
+				{textCell("west"), textCell("apple")},
+				{textCell("east"), nullCell(TextType)},
+			},
+		},
+		{
+			// No rows have no groups, but the grand total is still there
+			source:  "SELECT id, count(*) FROM empty GROUP BY ROLLUP (id);",
+			columns: []ResultColumn{{"id", IntType}, {"count", IntType}},
+			rows:    [][]Cell{{nullCell(IntType), intCell(0)}},
+		},
+		{
+			source:  "SELECT id FROM empty GROUP BY id;",
+			columns: []ResultColumn{{"id", IntType}},
+			rows:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.columns, results.Columns, test.source)
+		assert.Equal(t, test.rows, results.Rows, test.source)
+	}
+}
+
+func TestMemoryBackend_Select_join(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (1, 'George'), (2, 'John'), (3, 'Paul');
+		CREATE TABLE scores (user_id INT, score INT);
+		INSERT INTO scores VALUES (2, 10), (1, 20), (2, 30), (4, 40);
+	`)
+
+	tests := []struct {
+		source  string
+		columns []ResultColumn
+		rows    [][]Cell
+	}{
+		{
+			source:  "SELECT * FROM users JOIN scores ON id = user_id;",
+			columns: []ResultColumn{{"id", IntType}, {"name", TextType}, {"user_id", IntType}, {"score", IntType}},
+			rows: [][]Cell{
+				{intCell(1), textCell("George"), intCell(1), intCell(20)},
+				{intCell(2), textCell("John"), intCell(2), intCell(10)},
+				{intCell(2), textCell("John"), intCell(2), intCell(30)},
+			},
+		},
+		{
+			source:  "SELECT u.name, s.score FROM users u INNER JOIN scores AS s ON u.id = s.user_id WHERE s.score > 15 ORDER BY score DESC;",
+			columns: []ResultColumn{{"name", TextType}, {"score", IntType}},
+			rows: [][]Cell{
+				{textCell("John"), intCell(30)},
+				{textCell("George"), intCell(20)},
+			},
+		},
+		{
+			// A table joined with itself under different aliases
+			source:  "SELECT a.id, b.id FROM users a JOIN users b ON a.id < b.id WHERE b.id = 3;",
+			columns: []ResultColumn{{"id", IntType}, {"id", IntType}},
+			rows: [][]Cell{
+				{intCell(1), intCell(3)},
+				{intCell(2), intCell(3)},
+			},
+		},
+		{
+			source:  "SELECT users.name FROM users WHERE users.id = 3;",
+			columns: []ResultColumn{{"name", TextType}},
+			rows:    [][]Cell{{textCell("Paul")}},
+		},
+		{
+			source:  "SELECT count(*) FROM users JOIN scores ON id = user_id JOIN users v ON v.id = 1;",
+			columns: []ResultColumn{{"count", IntType}},
+			rows:    [][]Cell{{intCell(3)}},
+		},
+		{
+			source:  "SELECT name, score FROM users, scores WHERE id = user_id AND score > 15;",
+			columns: []ResultColumn{{"name", TextType}, {"score", IntType}},
+			rows: [][]Cell{
+				{textCell("George"), intCell(20)},
+				{textCell("John"), intCell(30)},
+			},
+		},
+		{
+			source:  "SELECT count(*) FROM users CROSS JOIN scores;",
+			columns: []ResultColumn{{"count", IntType}},
+			rows:    [][]Cell{{intCell(12)}},
+		},
+		{
+			source:  "SELECT s.top, u.name FROM (SELECT max(score) AS top FROM scores) AS s, users u WHERE u.id = 3;",
+			columns: []ResultColumn{{"top", IntType}, {"name", TextType}},
+			rows:    [][]Cell{{intCell(40), textCell("Paul")}},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.columns, results.Columns, test.source)
+		assert.Equal(t, test.rows, results.Rows, test.source)
+	}
+}
+
+func TestMemoryBackend_Select_lateral(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (1, 'George'), (2, 'John'), (3, 'Paul');
+		CREATE TABLE scores (user_id INT, score INT);
+		INSERT INTO scores VALUES (2, 10), (1, 20), (2, 30), (4, 40);
+	`)
+
+	tests := []struct {
+		source  string
+		columns []ResultColumn
+		rows    [][]Cell
+	}{
+		{
+			// Each user is paired with their own scores
+			source:  "SELECT u.name, s.score FROM users u, LATERAL (SELECT score FROM scores WHERE user_id = u.id) s;",
+			columns: []ResultColumn{{"name", TextType}, {"score", IntType}},
+			rows: [][]Cell{
+				{textCell("George"), intCell(20)},
+				{textCell("John"), intCell(10)},
+				{textCell("John"), intCell(30)},
+			},
+		},
+		{
+			// Aggregated per user, so users without scores still get a row
+			source:  "SELECT name, total, n FROM users, LATERAL (SELECT sum(score) AS total, count(*) AS n FROM scores WHERE user_id = id) t;",
+			columns: []ResultColumn{{"name", TextType}, {"total", IntType}, {"n", IntType}},
+			rows: [][]Cell{
+				{textCell("George"), intCell(20), intCell(1)},
+				{textCell("John"), intCell(40), intCell(2)},
+				{textCell("Paul"), nullCell(IntType), intCell(0)},
+			},
+		},
+		{
+			// The subquery's own columns hide outer ones of the same name
+			source:  "SELECT u.name, s.name FROM users u CROSS JOIN LATERAL (SELECT name FROM users v WHERE id = 3) s WHERE u.id = 1;",
+			columns: []ResultColumn{{"name", TextType}, {"name", TextType}},
+			rows:    [][]Cell{{textCell("George"), textCell("Paul")}},
+		},
+		{
+			source:  "SELECT * FROM users, LATERAL (SELECT name AS copy) c WHERE id = 2;",
+			columns: []ResultColumn{{"id", IntType}, {"name", TextType}, {"copy", TextType}},
+			rows:    [][]Cell{{intCell(2), textCell("John"), textCell("John")}},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.columns, results.Columns, test.source)
+		assert.Equal(t, test.rows, results.Rows, test.source)
+	}
+}
+
+func TestMemoryBackend_Select_joinErrors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		CREATE TABLE orders (id INT, user_id INT);
+	`)
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "SELECT id FROM users JOIN orders ON users.id = user_id;",
+			err:    "Column id is ambiguous",
+		},
+		{
+			source: "SELECT name FROM users JOIN orders ON id = user_id;",
+			err:    "Column id is ambiguous",
+		},
+		{
+			source: "SELECT o.name FROM users JOIN orders o ON users.id = o.user_id;",
+			err:    "Column o.name does not exist",
+		},
+		{
+			source: "SELECT users.id FROM users u;",
+			err:    "Column users.id does not exist",
+		},
+		{
+			source: "SELECT name FROM users JOIN users ON true;",
+			err:    "Table users is specified more than once",
+		},
+		{
+			source: "SELECT name FROM users JOIN orders ON user_id;",
+			err:    "ON must be a boolean expression, got int",
+		},
+		{
+			source: "SELECT name FROM users LEFT JOIN orders ON users.id = user_id;",
+			err:    "Only inner and cross joins are supported",
+		},
+		{
+			source: "SELECT name FROM users JOIN nope ON true;",
+			err:    "Table nope does not exist",
+		},
+		{
+			source: "SELECT name FROM users, users;",
+			err:    "Table users is specified more than once",
+		},
+		{
+			// Only a lateral subquery sees the FROM items to its left
+			source: "SELECT name FROM users u, (SELECT user_id FROM orders WHERE user_id = u.id) o;",
+			err:    "Column u.id does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if assert.NotNil(t, err, test.source) {
+			assert.Equal(t, test.err, err.Error(), test.source)
+		}
+	}
+}
+
+func TestMemoryBackend_Select_where(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (1, 'George');
+		INSERT INTO users VALUES (2, 'John');
+		INSERT INTO users VALUES (3, 'Paul');
+		INSERT INTO users VALUES (4, 'Ringo');
+	`)
+
+	tests := []struct {
+		source string
+		ids    []int64
+	}{
+		{
+			source: "SELECT id FROM users WHERE id = 2;",
+			ids:    []int64{2},
+		},
+		{
+			source: "SELECT id FROM users WHERE id >= 2 AND id < 4;",
+			ids:    []int64{2, 3},
+		},
+		{
+			source: "SELECT id FROM users WHERE id <= 1 OR id > 3;",
+			ids:    []int64{1, 4},
+		},
+		{
+			source: "SELECT id FROM users WHERE id <> 2 AND id != 3;",
+			ids:    []int64{1, 4},
+		},
+		{
+			source: "SELECT id FROM users WHERE name = 'Paul';",
+			ids:    []int64{3},
+		},
+		{
+			source: "SELECT id FROM users WHERE name < 'John';",
+			ids:    []int64{1},
+		},
+		{
+			source: "SELECT id FROM users WHERE NOT (name > 'John' OR id = 1);",
+			ids:    []int64{2},
+		},
+		{
+			source: "SELECT id FROM users WHERE id = 5;",
+			ids:    []int64{},
+		},
+		{
+			source: "SELECT id FROM users WHERE -id < -2 AND +id <> 4;",
+			ids:    []int64{3},
+		},
+		{
+			source: "SELECT -id FROM users WHERE id = - -1;",
+			ids:    []int64{-1},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.ids, firstColumnInts(results), test.source)
+	}
+}
+
+func TestMemoryBackend_Select_whereErrors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (1, 'George');
+	`)
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{
+			source: "SELECT id FROM users WHERE id = 'George';",
+			err:    "Cannot compare int and text",
+		},
+		{
+			source: "SELECT id FROM users WHERE name > 1 OR id = 1;",
+			err:    "Cannot compare text and int",
+		},
+		{
+			source: "SELECT id FROM users WHERE id AND name = 'George';",
+			err:    "Cannot apply and to int and boolean",
+		},
+		{
+			source: "SELECT id FROM users WHERE NOT name;",
+			err:    "Cannot apply not to text",
+		},
+		{
+			source: "SELECT id FROM users WHERE -name = 1;",
+			err:    "Cannot apply - to text",
+		},
+		{
+			source: "SELECT id FROM users WHERE name;",
+			err:    "WHERE must be a boolean expression, got text",
+		},
+		{
+			source: "SELECT id FROM users WHERE email = 'x';",
+			err:    "Column email does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if assert.NotNil(t, err, test.source) {
+			assert.Equal(t, test.err, err.Error(), test.source)
+		}
+	}
+}
+
+func TestMemoryBackend_null(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT, age INT);
+		INSERT INTO users VALUES (1, 'George', 25), (2, NULL, 30), (3, 'John', NULL), (4, NULL, NULL);
+	`)
+
+	results, err := mb.Select(mustParse(t, "SELECT name, age FROM users WHERE id = 2;").SelectStatement)
+	if assert.Nil(t, err) {
+		assert.Equal(t, [][]Cell{{nullCell(TextType), intCell(30)}}, results.Rows)
+	}
+
+	tests := []struct {
+		source string
+		ids    []int64
+	}{
+		{
+			// Comparing NULL gives NULL, which no row passes
+			source: "SELECT id FROM users WHERE name = 'George' OR name <> 'George';",
+			ids:    []int64{1, 3},
+		},
+		{
+			source: "SELECT id FROM users WHERE NOT age > 27;",
+			ids:    []int64{1},
+		},
+		{
+			source: "SELECT id FROM users WHERE name = NULL OR NULL = NULL;",
+			ids:    []int64{},
+		},
+		{
+			source: "SELECT id FROM users WHERE NULL;",
+			ids:    []int64{},
+		},
+		{
+			// NULL AND false is false, NULL OR true is true
+			source: "SELECT id FROM users WHERE NOT (age > 27 AND id = 3);",
+			ids:    []int64{1, 2, 4},
+		},
+		{
+			source: "SELECT id FROM users WHERE age > 27 OR id = 4;",
+			ids:    []int64{2, 4},
+		},
+		{
+			source: "SELECT id FROM users WHERE name IS NULL;",
+			ids:    []int64{2, 4},
+		},
+		{
+			source: "SELECT id FROM users WHERE name IS NOT NULL AND age IS NULL;",
+			ids:    []int64{3},
+		},
+		{
+			// NULLs sort last, or first with DESC
+			source: "SELECT id FROM users ORDER BY age;",
+			ids:    []int64{1, 2, 3, 4},
+		},
+		{
+			source: "SELECT id FROM users ORDER BY age DESC, id;",
+			ids:    []int64{3, 4, 2, 1},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := mb.Select(mustParse(t, test.source).SelectStatement)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+		assert.Equal(t, test.ids, firstColumnInts(results), test.source)
+	}
+
+	// Aggregates skip NULLs
+	results, err = mb.Select(mustParse(t, "SELECT count(*), count(name), sum(age), avg(age), min(name) FROM users;").SelectStatement)
+	if assert.Nil(t, err) {
+		assert.Equal(t, [][]Cell{{intCell(4), intCell(2), intCell(55), intCell(27), textCell("George")}}, results.Rows)
+	}
+	results, err = mb.Select(mustParse(t, "SELECT count(age), max(age) FROM users WHERE id = 4;").SelectStatement)
+	if assert.Nil(t, err) {
+		assert.Equal(t, [][]Cell{{intCell(0), nullCell(IntType)}}, results.Rows)
+	}
+	assert.Contains(t, results.String(), "|     0 | NULL |")
+}
+
+func TestMemoryBackend_Insert_types(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (1, 'George');
+		INSERT INTO users VALUES ('2', '3');
+		INSERT INTO users VALUES (' -4 ', ' 5 ');
+	`)
+
+	// Numeric strings are stored as ints in int columns but kept as is in
+	// text ones
+	assert.Equal(t, [][]memoryCell{
+		{intCell(1), textCell("George")},
+		{intCell(2), textCell("3")},
+		{intCell(-4), textCell(" 5 ")},
+	}, mb.tables["users"].rows)
+
+	results, err := mb.Select(mustParse(t, "SELECT name FROM users WHERE id > 1;").SelectStatement)
+	if assert.Nil(t, err) {
+		assert.Equal(t, [][]Cell{{textCell("3")}}, results.Rows)
+	}
+
+	_, err = mb.Select(mustParse(t, "SELECT name FROM users WHERE name = 3;").SelectStatement)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Cannot compare text and int", err.Error())
+	}
+}
@@ -0,0 +1,1805 @@
+package gosql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ParseError struct {
+	Loc     Location
+	Message string
+	// The index of the statement the error is in, counting from 0
+	Statement int
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at %d:%d", e.Message, e.Loc.Line, e.Loc.Col)
+}
+
+// Parse the tokens of one or more statements, each ending in a semicolon.
+// Whitespace, comment and EOF tokens are ignored.
+func Parse(tokens []*Token) (*Ast, error) {
+	tokens = significantTokens(tokens)
+	a := Ast{}
+	cursor := uint(0)
+	for cursor < uint(len(tokens)) {
+		stmt, newCursor, err := parseStatement(tokens, cursor)
+		if err != nil {
+			return nil, inStatement(err, len(a.Statements))
+		}
+		cursor = newCursor
+		a.Statements = append(a.Statements, stmt)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(SemicolonSymbol)) {
+			return nil, inStatement(parseError(tokens, cursor, "Expected semicolon after statement"), len(a.Statements)-1)
+		}
+		cursor++
+	}
+
+	return &a, nil
+}
+
+// ParseProgram parses the tokens of semicolon separated statements. Unlike
+// Parse the last statement needn't end in a semicolon, and empty
+// statements (eg between ;;) are skipped rather than being an error. An
+// error's Statement is the index the statement would have had in the
+// result, so skipped empty statements aren't counted.
+func ParseProgram(tokens []*Token) ([]*Statement, error) {
+	tokens = significantTokens(tokens)
+	var statements []*Statement
+	cursor := uint(0)
+	for cursor < uint(len(tokens)) {
+		if expectToken(tokens, cursor, tokenFromSymbol(SemicolonSymbol)) {
+			cursor++
+			continue
+		}
+
+		stmt, newCursor, err := parseStatement(tokens, cursor)
+		if err != nil {
+			return nil, inStatement(err, len(statements))
+		}
+		cursor = newCursor
+		statements = append(statements, stmt)
+
+		if cursor < uint(len(tokens)) && !expectToken(tokens, cursor, tokenFromSymbol(SemicolonSymbol)) {
+			return nil, inStatement(parseError(tokens, cursor, "Expected semicolon after statement"), len(statements)-1)
+		}
+	}
+
+	return statements, nil
+}
+
+// Record the index of the statement a parse error is in
+func inStatement(err error, index int) error {
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.Statement = index
+	}
+	return err
+}
+
+// Drop the tokens that don't affect the parse
+func significantTokens(tokens []*Token) []*Token {
+	significant := make([]*Token, 0, len(tokens))
+	for _, token := range tokens {
+		switch token.Kind {
+		case WhitespaceKind, CommentKind, EOFKind:
+			continue
+		}
+		significant = append(significant, token)
+	}
+	return significant
+}
+
+func tokenFromKeyword(k Keyword) Token {
+	return Token{
+		Kind:    KeywordKind,
+		Value:   string(k),
+		keyword: k,
+	}
+}
+
+func tokenFromSymbol(s Symbol) Token {
+	return Token{
+		Kind:  SymbolKind,
+		Value: string(s),
+	}
+}
+
+// The keyword at the cursor, or false if there isn't one
+func keywordAt(tokens []*Token, cursor uint) (Keyword, bool) {
+	if cursor >= uint(len(tokens)) {
+		return "", false
+	}
+	return tokens[cursor].Keyword()
+}
+
+// Whether the token at the cursor has the value and kind of t
+func expectToken(tokens []*Token, cursor uint, t Token) bool {
+	if cursor >= uint(len(tokens)) {
+		return false
+	}
+	// Keywords compare by the Keyword they resolved to, not their text
+	if t.Kind == KeywordKind {
+		keyword, ok := tokens[cursor].Keyword()
+		return ok && keyword == t.keyword
+	}
+	return t.equals(tokens[cursor])
+}
+
+// Whether the token at the cursor is INDEX. It isn't reserved, so it
+// lexes as an identifier, and a quoted "index" is only ever a name.
+func expectIndexKeyword(tokens []*Token, cursor uint) bool {
+	if cursor >= uint(len(tokens)) {
+		return false
+	}
+	token := tokens[cursor]
+	return token.Kind == IdentifierKind && token.Value == string(IndexKeyword) &&
+		(token.Raw == "" || strings.EqualFold(token.Raw, token.Value))
+}
+
+// Build an error located at the token at the cursor, or just past the
+// last token if the input ran out
+func parseError(tokens []*Token, cursor uint, message string) *ParseError {
+	if cursor < uint(len(tokens)) {
+		return &ParseError{
+			Loc:     tokens[cursor].Loc,
+			Message: message + ", got " + tokens[cursor].Value,
+		}
+	}
+
+	var loc Location
+	if len(tokens) > 0 {
+		loc = tokens[len(tokens)-1].EndLoc
+	}
+	return &ParseError{
+		Loc:     loc,
+		Message: message + ", got end of input",
+	}
+}
+
+func parseStatement(tokens []*Token, initialCursor uint) (*Statement, uint, error) {
+	if expectToken(tokens, initialCursor, tokenFromKeyword(SelectKeyword)) {
+		slct, cursor, err := parseSelectStatement(tokens, initialCursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            SelectKind,
+			SelectStatement: slct,
+		}, cursor, nil
+	}
+
+	if expectToken(tokens, initialCursor, tokenFromKeyword(CreateKeyword)) {
+		if expectToken(tokens, initialCursor+1, tokenFromKeyword(OrKeyword)) ||
+			expectToken(tokens, initialCursor+1, tokenFromKeyword(ViewKeyword)) {
+			crtView, cursor, err := parseCreateViewStatement(tokens, initialCursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			return &Statement{
+				Kind:                CreateViewKind,
+				CreateViewStatement: crtView,
+			}, cursor, nil
+		}
+
+		if expectToken(tokens, initialCursor+1, tokenFromKeyword(UniqueKeyword)) ||
+			expectIndexKeyword(tokens, initialCursor+1) {
+			crtIdx, cursor, err := parseCreateIndexStatement(tokens, initialCursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			return &Statement{
+				Kind:                 CreateIndexKind,
+				CreateIndexStatement: crtIdx,
+			}, cursor, nil
+		}
+
+		crtTbl, cursor, err := parseCreateTableStatement(tokens, initialCursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:                 CreateTableKind,
+			CreateTableStatement: crtTbl,
+		}, cursor, nil
+	}
+
+	if expectToken(tokens, initialCursor, tokenFromKeyword(InsertKeyword)) {
+		inst, cursor, err := parseInsertStatement(tokens, initialCursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            InsertKind,
+			InsertStatement: inst,
+		}, cursor, nil
+	}
+
+	if expectToken(tokens, initialCursor, tokenFromKeyword(UpdateKeyword)) {
+		updt, cursor, err := parseUpdateStatement(tokens, initialCursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            UpdateKind,
+			UpdateStatement: updt,
+		}, cursor, nil
+	}
+
+	if expectToken(tokens, initialCursor, tokenFromKeyword(DeleteKeyword)) {
+		dlt, cursor, err := parseDeleteStatement(tokens, initialCursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Statement{
+			Kind:            DeleteKind,
+			DeleteStatement: dlt,
+		}, cursor, nil
+	}
+
+	return nil, initialCursor, parseError(tokens, initialCursor, "Expected statement")
+}
+
+func parseSelectStatement(tokens []*Token, initialCursor uint) (*SelectStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(SelectKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SELECT")
+	}
+	cursor++
+
+	slct := SelectStatement{Keywords: []*Token{tokens[initialCursor]}}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(DistinctKeyword)) {
+		slct.Distinct = true
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+	}
+
+	items, cursor, err := parseSelectItems(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	slct.Items = items
+
+	if expectToken(tokens, cursor, tokenFromKeyword(FromKeyword)) {
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		from, newCursor, err := parseFromItems(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		slct.From = from
+		cursor = newCursor
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(WhereKeyword)) {
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		where, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		slct.Where = where
+		cursor = newCursor
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(GroupKeyword)) {
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(ByKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected BY after GROUP")
+		}
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		groupBy, newCursor, err := parseGroupingElements(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		slct.GroupBy = groupBy
+		cursor = newCursor
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(HavingKeyword)) {
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		having, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		slct.Having = having
+		cursor = newCursor
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(OrderKeyword)) {
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(ByKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected BY after ORDER")
+		}
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		orderBy, newCursor, err := parseOrderTerms(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		slct.OrderBy = orderBy
+		cursor = newCursor
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(LimitKeyword)) {
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		limit, newCursor, ok := parseInteger(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected integer after LIMIT")
+		}
+		slct.Limit = limit
+		cursor = newCursor
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(OffsetKeyword)) {
+		slct.Keywords = append(slct.Keywords, tokens[cursor])
+		cursor++
+
+		offset, newCursor, ok := parseInteger(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected integer after OFFSET")
+		}
+		slct.Offset = offset
+		cursor = newCursor
+	}
+
+	return &slct, cursor, nil
+}
+
+// The keywords that start each kind of join, other than a bare JOIN for
+// an inner join. OUTER may follow LEFT, RIGHT and FULL.
+var joinKeywords = map[Keyword]JoinKind{
+	InnerKeyword: InnerJoinKind,
+	LeftKeyword:  LeftJoinKind,
+	RightKeyword: RightJoinKind,
+	FullKeyword:  FullJoinKind,
+	CrossKeyword: CrossJoinKind,
+}
+
+// Parse the comma separated items of a FROM clause. Like joins they nest
+// to the left, but commas bind looser, so a, b JOIN c is a cross join of
+// a with the join of b and c.
+func parseFromItems(tokens []*Token, initialCursor uint) (*TableReference, uint, error) {
+	table, cursor, err := parseTableReference(tokens, initialCursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	for expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+		cursor++
+
+		right, newCursor, err := parseTableReference(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		table = &TableReference{
+			Kind: JoinTableKind,
+			Join: &JoinClause{
+				Kind:  CommaJoinKind,
+				Left:  table,
+				Right: right,
+			},
+		}
+	}
+	return table, cursor, nil
+}
+
+// Parse a named table or subquery followed by any number of joins, which nest to the
+// left so a JOIN b JOIN c joins the result of a JOIN b to c
+func parseTableReference(tokens []*Token, initialCursor uint) (*TableReference, uint, error) {
+	table, cursor, err := parseTablePrimary(tokens, initialCursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	for {
+		join := JoinClause{Kind: InnerJoinKind}
+		if keyword, ok := keywordAt(tokens, cursor); ok {
+			if kind, ok := joinKeywords[keyword]; ok {
+				join.Kind = kind
+				join.Keywords = append(join.Keywords, tokens[cursor])
+				cursor++
+
+				outer := kind == LeftJoinKind || kind == RightJoinKind || kind == FullJoinKind
+				if outer && expectToken(tokens, cursor, tokenFromKeyword(OuterKeyword)) {
+					join.Keywords = append(join.Keywords, tokens[cursor])
+					cursor++
+				}
+
+				if !expectToken(tokens, cursor, tokenFromKeyword(JoinKeyword)) {
+					return nil, initialCursor, parseError(tokens, cursor, "Expected JOIN")
+				}
+			}
+		}
+		if !expectToken(tokens, cursor, tokenFromKeyword(JoinKeyword)) {
+			return table, cursor, nil
+		}
+		join.Keywords = append(join.Keywords, tokens[cursor])
+		cursor++
+
+		right, newCursor, err := parseTablePrimary(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		if join.Kind != CrossJoinKind {
+			if !expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected ON after joined table")
+			}
+			join.Keywords = append(join.Keywords, tokens[cursor])
+			cursor++
+
+			on, newCursor, err := parseExpression(tokens, cursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			join.On = on
+			cursor = newCursor
+		}
+
+		join.Left = table
+		join.Right = right
+		table = &TableReference{
+			Kind: JoinTableKind,
+			Join: &join,
+		}
+	}
+}
+
+// Parse a table name or [LATERAL] subquery with an optional alias
+func parseTablePrimary(tokens []*Token, initialCursor uint) (*TableReference, uint, error) {
+	var table TableReference
+	cursor := initialCursor
+
+	if expectToken(tokens, cursor, tokenFromKeyword(LateralKeyword)) {
+		table.Lateral = true
+		table.Keywords = append(table.Keywords, tokens[cursor])
+		cursor++
+	}
+
+	if subquery, newCursor, ok, err := parseSubquery(tokens, cursor); ok {
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		table.Kind = SubqueryTableKind
+		table.Subquery = subquery
+		cursor = newCursor
+	} else if table.Lateral {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected subquery after LATERAL")
+	} else {
+		name, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+		}
+		table.Kind = NamedTableKind
+		table.Name = name
+		cursor = newCursor
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(AsKeyword)) {
+		table.Keywords = append(table.Keywords, tokens[cursor])
+		cursor++
+		as, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected alias after AS")
+		}
+		table.As = as
+		cursor = newCursor
+	} else if as, newCursor, ok := parseToken(tokens, cursor, IdentifierKind); ok {
+		table.As = as
+		cursor = newCursor
+	}
+
+	return &table, cursor, nil
+}
+
+// Parse the comma separated elements of a GROUP BY clause
+func parseGroupingElements(tokens []*Token, initialCursor uint) ([]*GroupingElement, uint, error) {
+	cursor := initialCursor
+
+	var elements []*GroupingElement
+	for {
+		element, newCursor, err := parseGroupingElement(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		elements = append(elements, element)
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			return elements, cursor, nil
+		}
+		cursor++
+	}
+}
+
+// Parse an expression, ROLLUP (...), CUBE (...) or GROUPING SETS (...)
+func parseGroupingElement(tokens []*Token, initialCursor uint) (*GroupingElement, uint, error) {
+	keyword, _ := keywordAt(tokens, initialCursor)
+	switch keyword {
+	case RollupKeyword, CubeKeyword:
+		element := GroupingElement{Kind: RollupGroupingKind, Keywords: []*Token{tokens[initialCursor]}}
+		if keyword == CubeKeyword {
+			element.Kind = CubeGroupingKind
+		}
+		cursor := initialCursor + 1
+
+		name := strings.ToUpper(string(keyword))
+		if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected ( after "+name)
+		}
+		cursor++
+
+		exps, cursor, err := parseExpressions(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		element.Expressions = exps
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected ) after "+name+" expressions")
+		}
+		return &element, cursor + 1, nil
+	case GroupingKeyword:
+		element := GroupingElement{Kind: SetsGroupingKind, Keywords: []*Token{tokens[initialCursor]}}
+		cursor := initialCursor + 1
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(SetsKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected SETS after GROUPING")
+		}
+		element.Keywords = append(element.Keywords, tokens[cursor])
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected ( after GROUPING SETS")
+		}
+		cursor++
+
+		for {
+			set, newCursor, err := parseGroupingSet(tokens, cursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			element.Sets = append(element.Sets, set)
+			cursor = newCursor
+
+			if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+				break
+			}
+			cursor++
+		}
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected ) after GROUPING SETS")
+		}
+		return &element, cursor + 1, nil
+	}
+
+	exp, cursor, err := parseExpression(tokens, initialCursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	return &GroupingElement{Kind: ExpressionGroupingKind, Expression: exp}, cursor, nil
+}
+
+// Parse one set of GROUPING SETS: a parenthesized list of expressions, ()
+// for the empty set, or a single expression. A list of one, as in (a), is
+// the same set as the expression a, but (a + b) * 2 is an expression.
+func parseGroupingSet(tokens []*Token, initialCursor uint) ([]*Expression, uint, error) {
+	if expectToken(tokens, initialCursor, tokenFromSymbol(LeftParenSymbol)) {
+		if expectToken(tokens, initialCursor+1, tokenFromSymbol(RightParenSymbol)) {
+			return []*Expression{}, initialCursor + 2, nil
+		}
+
+		exps, cursor, err := parseExpressions(tokens, initialCursor+1)
+		if err == nil && expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+			cursor++
+			if expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) || expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+				return exps, cursor, nil
+			}
+		}
+	}
+
+	exp, cursor, err := parseExpression(tokens, initialCursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+	return []*Expression{exp}, cursor, nil
+}
+
+// Parse a comma separated list of at least one expression, each
+// optionally followed by ASC or DESC
+func parseOrderTerms(tokens []*Token, initialCursor uint) ([]*OrderTerm, uint, error) {
+	cursor := initialCursor
+
+	var terms []*OrderTerm
+	for {
+		exp, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		term := OrderTerm{Expression: exp}
+		if expectToken(tokens, cursor, tokenFromKeyword(AscKeyword)) {
+			term.Keywords = []*Token{tokens[cursor]}
+			cursor++
+		} else if expectToken(tokens, cursor, tokenFromKeyword(DescKeyword)) {
+			term.Desc = true
+			term.Keywords = []*Token{tokens[cursor]}
+			cursor++
+		}
+		terms = append(terms, &term)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			return terms, cursor, nil
+		}
+		cursor++
+	}
+}
+
+// Consume a numeric token at the cursor if it's a non-negative integer,
+// ie only digits
+func parseInteger(tokens []*Token, initialCursor uint) (*Token, uint, bool) {
+	token, cursor, ok := parseToken(tokens, initialCursor, NumericKind)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	for _, c := range token.Value {
+		if c < '0' || c > '9' {
+			return nil, initialCursor, false
+		}
+	}
+	return token, cursor, true
+}
+
+func parseInsertStatement(tokens []*Token, initialCursor uint) (*InsertStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(InsertKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected INSERT")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(IntoKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected INTO")
+	}
+	cursor++
+
+	table, cursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(ValuesKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected VALUES")
+	}
+	cursor++
+
+	inst := InsertStatement{
+		Table:    table,
+		Keywords: []*Token{tokens[initialCursor], tokens[initialCursor+1], tokens[cursor-1]},
+	}
+	for {
+		row, newCursor, err := parseValuesRow(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		// Every row must fill the same columns as the first
+		if len(inst.Values) > 0 && len(row) != len(inst.Values[0]) {
+			return nil, initialCursor, &ParseError{
+				Loc:     tokens[cursor].Loc,
+				Message: fmt.Sprintf("Expected %d values in each row, got %d", len(inst.Values[0]), len(row)),
+			}
+		}
+		inst.Values = append(inst.Values, row)
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			return &inst, cursor, nil
+		}
+		cursor++
+	}
+}
+
+// Parse one parenthesized, non-empty row of values
+func parseValuesRow(tokens []*Token, initialCursor uint) ([]*Expression, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ( before values")
+	}
+	cursor++
+
+	if expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected at least one value")
+	}
+
+	values, cursor, err := parseExpressions(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ) after values")
+	}
+	cursor++
+
+	return values, cursor, nil
+}
+
+func parseUpdateStatement(tokens []*Token, initialCursor uint) (*UpdateStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(UpdateKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected UPDATE")
+	}
+	cursor++
+
+	table, cursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(SetKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SET")
+	}
+	cursor++
+
+	updt := UpdateStatement{
+		Table:    table,
+		Keywords: []*Token{tokens[initialCursor], tokens[cursor-1]},
+	}
+	for {
+		column, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+		if !ok {
+			if len(updt.Set) == 0 {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected at least one assignment")
+			}
+			return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(EqualsSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected = after column")
+		}
+		cursor++
+
+		value, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		updt.Set = append(updt.Set, &Assignment{Column: column, Value: value})
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(WhereKeyword)) {
+		updt.Keywords = append(updt.Keywords, tokens[cursor])
+		cursor++
+
+		where, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		updt.Where = where
+		cursor = newCursor
+	}
+
+	return &updt, cursor, nil
+}
+
+func parseDeleteStatement(tokens []*Token, initialCursor uint) (*DeleteStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(DeleteKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected DELETE")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(FromKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected FROM")
+	}
+	cursor++
+
+	table, cursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+
+	dlt := DeleteStatement{
+		Table:    table,
+		Keywords: []*Token{tokens[initialCursor], tokens[initialCursor+1]},
+	}
+	if expectToken(tokens, cursor, tokenFromKeyword(WhereKeyword)) {
+		dlt.Keywords = append(dlt.Keywords, tokens[cursor])
+		cursor++
+
+		where, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		dlt.Where = where
+		cursor = newCursor
+	}
+
+	return &dlt, cursor, nil
+}
+
+// The keywords a column may be declared with, and the most size
+// parameters each may be given
+var columnTypes = map[Keyword]int{
+	IntKeyword:       0,
+	TextKeyword:      0,
+	FloatKeyword:     0,
+	DoubleKeyword:    0,
+	RealKeyword:      0,
+	BooleanKeyword:   0,
+	BoolKeyword:      0,
+	VarcharKeyword:   1,
+	CharKeyword:      1,
+	NumericKeyword:   2,
+	BigintKeyword:    0,
+	SmallintKeyword:  0,
+	TimestampKeyword: 0,
+}
+
+// Parse a type keyword with an optional parenthesized list of integer
+// sizes. ok is false if there's no type keyword at the cursor, and err is
+// set if there is but its size is invalid.
+func parseDataType(tokens []*Token, initialCursor uint) (*DataType, uint, bool, error) {
+	name, cursor, ok := parseToken(tokens, initialCursor, KeywordKind)
+	if !ok {
+		return nil, initialCursor, false, nil
+	}
+	keyword, _ := name.Keyword()
+	maxParams, ok := columnTypes[keyword]
+	if !ok {
+		return nil, initialCursor, false, nil
+	}
+
+	datatype := DataType{Name: name}
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+		return &datatype, cursor, true, nil
+	}
+	if maxParams == 0 {
+		return nil, initialCursor, true, parseError(tokens, cursor, "Expected no size for "+keyword.String())
+	}
+	cursor++
+
+	for {
+		param, newCursor, ok := parseInteger(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, true, parseError(tokens, cursor, "Expected integer size")
+		}
+		datatype.Params = append(datatype.Params, param)
+		cursor = newCursor
+
+		if len(datatype.Params) == maxParams || !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, true, parseError(tokens, cursor, "Expected ) after size")
+	}
+	cursor++
+
+	return &datatype, cursor, true, nil
+}
+
+func parseCreateTableStatement(tokens []*Token, initialCursor uint) (*CreateTableStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CREATE")
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(TableKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected TABLE")
+	}
+	cursor++
+
+	name, cursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ( before column definitions")
+	}
+	cursor++
+
+	cols, cursor, err := parseColumnDefinitions(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ) after column definitions")
+	}
+	cursor++
+
+	return &CreateTableStatement{
+		Name:     name,
+		Cols:     cols,
+		Keywords: []*Token{tokens[initialCursor], tokens[initialCursor+1]},
+	}, cursor, nil
+}
+
+func parseCreateIndexStatement(tokens []*Token, initialCursor uint) (*CreateIndexStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CREATE")
+	}
+	cursor++
+
+	keywords := []*Token{tokens[initialCursor]}
+	unique := expectToken(tokens, cursor, tokenFromKeyword(UniqueKeyword))
+	if unique {
+		keywords = append(keywords, tokens[cursor])
+		cursor++
+	}
+
+	if !expectIndexKeyword(tokens, cursor) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected INDEX")
+	}
+	keywords = append(keywords, tokens[cursor])
+	cursor++
+
+	name, cursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected index name")
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(OnKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ON after index name")
+	}
+	keywords = append(keywords, tokens[cursor])
+	cursor++
+
+	table, cursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected table name")
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ( before index columns")
+	}
+	cursor++
+
+	if expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected at least one index column")
+	}
+
+	var cols []*Token
+	for {
+		col, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+		}
+		cursor = newCursor
+		cols = append(cols, col)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ) after index columns")
+	}
+	cursor++
+
+	return &CreateIndexStatement{
+		Name:     name,
+		Table:    table,
+		Cols:     cols,
+		Unique:   unique,
+		Keywords: keywords,
+	}, cursor, nil
+}
+
+func parseCreateViewStatement(tokens []*Token, initialCursor uint) (*CreateViewStatement, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(CreateKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CREATE")
+	}
+	cursor++
+
+	keywords := []*Token{tokens[initialCursor]}
+	orReplace := expectToken(tokens, cursor, tokenFromKeyword(OrKeyword))
+	if orReplace {
+		cursor++
+		if !expectToken(tokens, cursor, tokenFromKeyword(ReplaceKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected REPLACE after CREATE OR")
+		}
+		keywords = append(keywords, tokens[cursor-1], tokens[cursor])
+		cursor++
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(ViewKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected VIEW")
+	}
+	keywords = append(keywords, tokens[cursor])
+	cursor++
+
+	name, cursor, ok := parseToken(tokens, cursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected view name")
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(AsKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected AS after view name")
+	}
+	keywords = append(keywords, tokens[cursor])
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(SelectKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected SELECT after AS")
+	}
+	query, cursor, err := parseSelectStatement(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	return &CreateViewStatement{
+		Name:      name,
+		Query:     query,
+		OrReplace: orReplace,
+		Keywords:  keywords,
+	}, cursor, nil
+}
+
+// Parse a comma separated list of at least one column definition
+func parseColumnDefinitions(tokens []*Token, initialCursor uint) ([]*ColumnDefinition, uint, error) {
+	cursor := initialCursor
+
+	if expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected at least one column definition")
+	}
+
+	var cols []*ColumnDefinition
+	for {
+		name, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected column name")
+		}
+		cursor = newCursor
+
+		datatype, newCursor, ok, err := parseDataType(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected column type")
+		}
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		col := ColumnDefinition{
+			Name:     name,
+			Datatype: datatype,
+		}
+		newCursor, err = parseColumnConstraints(tokens, cursor, &col)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+		cols = append(cols, &col)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			return cols, cursor, nil
+		}
+		cursor++
+	}
+}
+
+// The keywords of each column constraint
+var constraintKeywords = map[ConstraintKind][]Keyword{
+	PrimaryKeyConstraint: {PrimaryKeyword, KeyKeyword},
+	NotNullConstraint:    {NotKeyword, NullKeyword},
+	UniqueConstraint:     {UniqueKeyword},
+}
+
+// Parse any number of column constraints and a DEFAULT clause into col,
+// in any order but each at most once
+func parseColumnConstraints(tokens []*Token, initialCursor uint, col *ColumnDefinition) (uint, error) {
+	cursor := initialCursor
+
+	for {
+		if expectToken(tokens, cursor, tokenFromKeyword(DefaultKeyword)) {
+			if col.Default != nil {
+				return initialCursor, parseError(tokens, cursor, "Duplicate DEFAULT")
+			}
+			col.Keywords = append(col.Keywords, tokens[cursor])
+			cursor++
+
+			exp, newCursor, err := parseExpression(tokens, cursor)
+			if err != nil {
+				return initialCursor, err
+			}
+			col.Default = exp
+			cursor = newCursor
+			continue
+		}
+
+		var kind ConstraintKind
+		switch {
+		case expectToken(tokens, cursor, tokenFromKeyword(PrimaryKeyword)):
+			kind = PrimaryKeyConstraint
+		case expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)):
+			kind = NotNullConstraint
+		case expectToken(tokens, cursor, tokenFromKeyword(UniqueKeyword)):
+			kind = UniqueConstraint
+		default:
+			return cursor, nil
+		}
+
+		for _, constraint := range col.Constraints {
+			if constraint == kind {
+				return initialCursor, parseError(tokens, cursor, "Duplicate column constraint")
+			}
+		}
+
+		for _, keyword := range constraintKeywords[kind] {
+			if !expectToken(tokens, cursor, tokenFromKeyword(keyword)) {
+				return initialCursor, parseError(tokens, cursor, "Expected "+keyword.String())
+			}
+			col.Keywords = append(col.Keywords, tokens[cursor])
+			cursor++
+		}
+		col.Constraints = append(col.Constraints, kind)
+	}
+}
+
+// Parse a comma separated list of at least one select item
+func parseSelectItems(tokens []*Token, initialCursor uint) ([]*SelectItem, uint, error) {
+	cursor := initialCursor
+
+	var items []*SelectItem
+	for {
+		if expectToken(tokens, cursor, tokenFromSymbol(AsteriskSymbol)) {
+			items = append(items, &SelectItem{Asterisk: true})
+			cursor++
+		} else {
+			exp, newCursor, err := parseExpression(tokens, cursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			cursor = newCursor
+
+			item := SelectItem{Expression: exp}
+			if expectToken(tokens, cursor, tokenFromKeyword(AsKeyword)) {
+				item.Keywords = []*Token{tokens[cursor]}
+				cursor++
+				as, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+				if !ok {
+					return nil, initialCursor, parseError(tokens, cursor, "Expected alias after AS")
+				}
+				item.As = as
+				cursor = newCursor
+			} else if as, newCursor, ok := parseToken(tokens, cursor, IdentifierKind); ok {
+				// An implicit alias
+				item.As = as
+				cursor = newCursor
+			}
+			items = append(items, &item)
+		}
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			return items, cursor, nil
+		}
+		cursor++
+	}
+}
+
+// Parse a comma separated list of at least one expression
+func parseExpressions(tokens []*Token, initialCursor uint) ([]*Expression, uint, error) {
+	cursor := initialCursor
+
+	var exps []*Expression
+	for {
+		exp, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		exps = append(exps, exp)
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(CommaSymbol)) {
+			return exps, cursor, nil
+		}
+		cursor++
+	}
+}
+
+// How tightly each binary operator binds its operands, keyed by the
+// operator's keyword or symbol. Operators with higher precedence are
+// applied first, and operators of equal precedence from left to right.
+var BinaryOperatorPrecedence = map[string]uint{
+	string(OrKeyword):  1,
+	string(AndKeyword): 2,
+
+	string(EqualsSymbol):             3,
+	string(NotEqualSymbol):           3,
+	string(BangEqualSymbol):          3,
+	string(LessThanSymbol):           3,
+	string(GreaterThanSymbol):        3,
+	string(LessThanOrEqualSymbol):    3,
+	string(GreaterThanOrEqualSymbol): 3,
+	string(NullSafeEqualSymbol):      3,
+
+	string(PlusSymbol):   4,
+	string(MinusSymbol):  4,
+	string(ConcatSymbol): 4,
+	// Postgres groups these with || as "any other operator"
+	string(ArrowSymbol):       4,
+	string(DoubleArrowSymbol): 4,
+
+	string(AsteriskSymbol): 5,
+	string(SlashSymbol):    5,
+}
+
+// The binary operator at the cursor, if there is one, and its precedence
+func binaryOperator(tokens []*Token, cursor uint) (*Token, uint, bool) {
+	if cursor >= uint(len(tokens)) {
+		return nil, 0, false
+	}
+
+	op := tokens[cursor]
+	if op.Kind != KeywordKind && op.Kind != SymbolKind {
+		return nil, 0, false
+	}
+	precedence, ok := BinaryOperatorPrecedence[op.Value]
+	return op, precedence, ok
+}
+
+func parseExpression(tokens []*Token, initialCursor uint) (*Expression, uint, error) {
+	return parseBinaryExpression(tokens, initialCursor, 0)
+}
+
+// Parse an operand followed by any binary operators that bind tighter
+// than minPrecedence, by precedence climbing
+func parseBinaryExpression(tokens []*Token, initialCursor uint, minPrecedence uint) (*Expression, uint, error) {
+	exp, cursor, err := parseUnary(tokens, initialCursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	for {
+		if between, newCursor, ok, err := parseBetween(tokens, cursor, exp, minPrecedence); ok {
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			exp = between
+			cursor = newCursor
+			continue
+		}
+
+		if isNull, newCursor, ok, err := parseIsNull(tokens, cursor, exp, minPrecedence); ok {
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			exp = isNull
+			cursor = newCursor
+			continue
+		}
+
+		if in, newCursor, ok, err := parseIn(tokens, cursor, exp, minPrecedence); ok {
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			exp = in
+			cursor = newCursor
+			continue
+		}
+
+		if like, newCursor, ok, err := parseLike(tokens, cursor, exp, minPrecedence); ok {
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			exp = like
+			cursor = newCursor
+			continue
+		}
+
+		op, precedence, ok := binaryOperator(tokens, cursor)
+		if !ok || precedence <= minPrecedence {
+			return exp, cursor, nil
+		}
+		cursor++
+
+		b, newCursor, err := parseBinaryExpression(tokens, cursor, precedence)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		exp = &Expression{
+			Kind: BinaryKind,
+			Binary: &BinaryExpression{
+				A:  exp,
+				B:  b,
+				Op: op,
+			},
+		}
+	}
+}
+
+// NOT applies to everything that binds tighter than AND, so NOT a = b
+// AND c is (NOT (a = b)) AND c
+var notPrecedence = BinaryOperatorPrecedence[string(AndKeyword)]
+
+// Parse an operand, or a prefix operator and the expression it applies
+// to. NOT negates everything that binds tighter than AND, while a sign
+// applies to the operand alone, so -a * b is (-a) * b.
+func parseUnary(tokens []*Token, initialCursor uint) (*Expression, uint, error) {
+	var exp *Expression
+	var err error
+	cursor := initialCursor + 1
+	switch {
+	case expectToken(tokens, initialCursor, tokenFromKeyword(NotKeyword)):
+		exp, cursor, err = parseBinaryExpression(tokens, cursor, notPrecedence)
+	case expectToken(tokens, initialCursor, tokenFromSymbol(MinusSymbol)),
+		expectToken(tokens, initialCursor, tokenFromSymbol(PlusSymbol)):
+		exp, cursor, err = parseUnary(tokens, cursor)
+	default:
+		return parseOperand(tokens, initialCursor)
+	}
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	return &Expression{
+		Kind: UnaryKind,
+		Unary: &UnaryExpression{
+			Op:         tokens[initialCursor],
+			Expression: exp,
+		},
+	}, cursor, nil
+}
+
+// BETWEEN binds like a comparison. It isn't in BinaryOperatorPrecedence
+// because its AND belongs to it rather than being a conjunction.
+var betweenPrecedence = BinaryOperatorPrecedence[string(EqualsSymbol)]
+
+// Parse [NOT] BETWEEN low AND high applied to exp, if that's what is at
+// the cursor and it binds tighter than minPrecedence. ok is false if
+// not, and err is set if it is but it's invalid.
+func parseBetween(tokens []*Token, initialCursor uint, exp *Expression, minPrecedence uint) (*Expression, uint, bool, error) {
+	cursor := initialCursor
+	between := BetweenExpression{Expression: exp}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+		between.Not = true
+		between.Keywords = append(between.Keywords, tokens[cursor])
+		cursor++
+	}
+	if !expectToken(tokens, cursor, tokenFromKeyword(BetweenKeyword)) || betweenPrecedence <= minPrecedence {
+		return nil, initialCursor, false, nil
+	}
+	between.Keywords = append(between.Keywords, tokens[cursor])
+	cursor++
+
+	low, cursor, err := parseBinaryExpression(tokens, cursor, betweenPrecedence)
+	if err != nil {
+		return nil, initialCursor, true, err
+	}
+	between.Low = low
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(AndKeyword)) {
+		return nil, initialCursor, true, parseError(tokens, cursor, "Expected AND after lower bound of BETWEEN")
+	}
+	between.Keywords = append(between.Keywords, tokens[cursor])
+	cursor++
+
+	high, cursor, err := parseBinaryExpression(tokens, cursor, betweenPrecedence)
+	if err != nil {
+		return nil, initialCursor, true, err
+	}
+	between.High = high
+
+	return &Expression{
+		Kind:    BetweenKind,
+		Between: &between,
+	}, cursor, true, nil
+}
+
+// Parse IS [NOT] NULL applied to exp, if that's what is at the cursor and
+// it binds tighter than minPrecedence. It binds like a comparison. ok is
+// false if not, and err is set if it is but isn't followed by NULL: IS
+// only tests for NULL.
+func parseIsNull(tokens []*Token, initialCursor uint, exp *Expression, minPrecedence uint) (*Expression, uint, bool, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(IsKeyword)) || betweenPrecedence <= minPrecedence {
+		return nil, initialCursor, false, nil
+	}
+	cursor++
+
+	isNull := IsNullExpression{Expression: exp, Keywords: []*Token{tokens[initialCursor]}}
+	if expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+		isNull.Not = true
+		isNull.Keywords = append(isNull.Keywords, tokens[cursor])
+		cursor++
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(NullKeyword)) {
+		message := "Expected NULL after IS"
+		if isNull.Not {
+			message += " NOT"
+		}
+		return nil, initialCursor, true, parseError(tokens, cursor, message)
+	}
+	isNull.Keywords = append(isNull.Keywords, tokens[cursor])
+	cursor++
+
+	return &Expression{
+		Kind:   IsNullKind,
+		IsNull: &isNull,
+	}, cursor, true, nil
+}
+
+// Parse [NOT] IN applied to exp, followed by a subquery or a
+// parenthesized list of at least one value, if that's what is at the
+// cursor and it binds tighter than minPrecedence. It binds like a
+// comparison. ok is false if not, and err is set if it is but it's
+// invalid.
+func parseIn(tokens []*Token, initialCursor uint, exp *Expression, minPrecedence uint) (*Expression, uint, bool, error) {
+	cursor := initialCursor
+	in := InExpression{Expression: exp}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+		in.Not = true
+		in.Keywords = append(in.Keywords, tokens[cursor])
+		cursor++
+	}
+	if !expectToken(tokens, cursor, tokenFromKeyword(InKeyword)) || betweenPrecedence <= minPrecedence {
+		return nil, initialCursor, false, nil
+	}
+	in.Keywords = append(in.Keywords, tokens[cursor])
+	cursor++
+
+	if subquery, newCursor, ok, err := parseSubquery(tokens, cursor); ok {
+		if err != nil {
+			return nil, initialCursor, true, err
+		}
+		in.Subquery = subquery
+		return &Expression{
+			Kind: InKind,
+			In:   &in,
+		}, newCursor, true, nil
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+		return nil, initialCursor, true, parseError(tokens, cursor, "Expected ( after IN")
+	}
+	cursor++
+
+	if expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, true, parseError(tokens, cursor, "Expected at least one value in IN list")
+	}
+	values, cursor, err := parseExpressions(tokens, cursor)
+	if err != nil {
+		return nil, initialCursor, true, err
+	}
+	in.Values = values
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, true, parseError(tokens, cursor, "Expected ) after IN list")
+	}
+	cursor++
+
+	return &Expression{
+		Kind: InKind,
+		In:   &in,
+	}, cursor, true, nil
+}
+
+// Parse [NOT] LIKE pattern applied to exp, if that's what is at the
+// cursor and it binds tighter than minPrecedence. It binds like a
+// comparison. ok is false if not, and err is set if it is but the pattern
+// is invalid.
+func parseLike(tokens []*Token, initialCursor uint, exp *Expression, minPrecedence uint) (*Expression, uint, bool, error) {
+	cursor := initialCursor
+	like := LikeExpression{Expression: exp}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(NotKeyword)) {
+		like.Not = true
+		like.Keywords = append(like.Keywords, tokens[cursor])
+		cursor++
+	}
+	if !expectToken(tokens, cursor, tokenFromKeyword(LikeKeyword)) || betweenPrecedence <= minPrecedence {
+		return nil, initialCursor, false, nil
+	}
+	like.Keywords = append(like.Keywords, tokens[cursor])
+	cursor++
+
+	pattern, cursor, err := parseBinaryExpression(tokens, cursor, betweenPrecedence)
+	if err != nil {
+		return nil, initialCursor, true, err
+	}
+	like.Pattern = pattern
+
+	return &Expression{
+		Kind: LikeKind,
+		Like: &like,
+	}, cursor, true, nil
+}
+
+// Parse a parenthesized SELECT. ok is false if the tokens at the cursor
+// aren't the start of one, and err is set if they are but it's invalid.
+func parseSubquery(tokens []*Token, initialCursor uint) (*SelectStatement, uint, bool, error) {
+	if !expectToken(tokens, initialCursor, tokenFromSymbol(LeftParenSymbol)) ||
+		!expectToken(tokens, initialCursor+1, tokenFromKeyword(SelectKeyword)) {
+		return nil, initialCursor, false, nil
+	}
+
+	slct, cursor, err := parseSelectStatement(tokens, initialCursor+1)
+	if err != nil {
+		return nil, initialCursor, true, err
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, true, parseError(tokens, cursor, "Expected ) after subquery")
+	}
+	return slct, cursor + 1, true, nil
+}
+
+// Parse a simple or searched CASE expression
+func parseCase(tokens []*Token, initialCursor uint) (*CaseExpression, uint, error) {
+	cursor := initialCursor
+	if !expectToken(tokens, cursor, tokenFromKeyword(CaseKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected CASE")
+	}
+	cursor++
+
+	c := CaseExpression{Keywords: []*Token{tokens[initialCursor]}}
+	if !expectToken(tokens, cursor, tokenFromKeyword(WhenKeyword)) {
+		operand, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		c.Operand = operand
+		cursor = newCursor
+	}
+
+	for expectToken(tokens, cursor, tokenFromKeyword(WhenKeyword)) {
+		c.Keywords = append(c.Keywords, tokens[cursor])
+		cursor++
+
+		condition, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(ThenKeyword)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected THEN after WHEN condition")
+		}
+		c.Keywords = append(c.Keywords, tokens[cursor])
+		cursor++
+
+		result, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		c.Whens = append(c.Whens, &WhenClause{
+			Condition: condition,
+			Result:    result,
+		})
+	}
+
+	if len(c.Whens) == 0 {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected WHEN in CASE")
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(ElseKeyword)) {
+		c.Keywords = append(c.Keywords, tokens[cursor])
+		cursor++
+
+		els, newCursor, err := parseExpression(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		c.Else = els
+		cursor = newCursor
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(EndKeyword)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected END after CASE")
+	}
+	c.Keywords = append(c.Keywords, tokens[cursor])
+	cursor++
+
+	return &c, cursor, nil
+}
+
+// Parse a primary expression followed by any number of :: casts, which
+// bind tighter than any operator
+func parseOperand(tokens []*Token, initialCursor uint) (*Expression, uint, error) {
+	exp, cursor, err := parsePrimary(tokens, initialCursor)
+	if err != nil {
+		return nil, initialCursor, err
+	}
+
+	for expectToken(tokens, cursor, tokenFromSymbol(DoubleColonSymbol)) {
+		cursor++
+
+		typ, newCursor, ok, err := parseDataType(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected type after ::")
+		}
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		cursor = newCursor
+
+		exp = &Expression{
+			Kind: CastKind,
+			Cast: &CastExpression{
+				Expression: exp,
+				Type:       typ,
+			},
+		}
+	}
+
+	return exp, cursor, nil
+}
+
+// Parse a literal, a subquery, a CASE, a function call or a
+// parenthesized expression
+func parsePrimary(tokens []*Token, initialCursor uint) (*Expression, uint, error) {
+	if expectToken(tokens, initialCursor, tokenFromKeyword(CaseKeyword)) {
+		c, cursor, err := parseCase(tokens, initialCursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Expression{
+			Kind: CaseKind,
+			Case: c,
+		}, cursor, nil
+	}
+
+	if subquery, cursor, ok, err := parseSubquery(tokens, initialCursor); ok {
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		return &Expression{
+			Kind:     SubqueryKind,
+			Subquery: subquery,
+		}, cursor, nil
+	}
+
+	if expectToken(tokens, initialCursor, tokenFromSymbol(LeftParenSymbol)) {
+		if expectToken(tokens, initialCursor+1, tokenFromSymbol(RightParenSymbol)) {
+			return nil, initialCursor, parseError(tokens, initialCursor+1, "Expected expression inside parentheses")
+		}
+
+		exp, cursor, err := parseExpression(tokens, initialCursor+1)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+			return nil, initialCursor, parseError(tokens, cursor, "Expected ) after expression")
+		}
+		return exp, cursor + 1, nil
+	}
+
+	if identifier, cursor, ok := parseToken(tokens, initialCursor, IdentifierKind); ok {
+		exp := Expression{
+			Kind:    LiteralKind,
+			Literal: identifier,
+		}
+
+		if expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+			call, newCursor, err := parseCall(tokens, initialCursor)
+			if err != nil {
+				return nil, initialCursor, err
+			}
+			return &Expression{
+				Kind: CallKind,
+				Call: call,
+			}, newCursor, nil
+		}
+
+		// A column qualified by its table, eg t.a
+		if expectToken(tokens, cursor, tokenFromSymbol(DotSymbol)) {
+			cursor++
+			column, newCursor, ok := parseToken(tokens, cursor, IdentifierKind)
+			if !ok {
+				return nil, initialCursor, parseError(tokens, cursor, "Expected column name after .")
+			}
+			exp.Qualifier = identifier
+			exp.Literal = column
+			cursor = newCursor
+		}
+		return &exp, cursor, nil
+	}
+
+	for _, kind := range []TokenKind{NumericKind, StringKind, BytesKind} {
+		if literal, cursor, ok := parseToken(tokens, initialCursor, kind); ok {
+			return &Expression{
+				Kind:    LiteralKind,
+				Literal: literal,
+			}, cursor, nil
+		}
+	}
+
+	if expectToken(tokens, initialCursor, tokenFromKeyword(NullKeyword)) {
+		return &Expression{
+			Kind:    LiteralKind,
+			Literal: tokens[initialCursor],
+		}, initialCursor + 1, nil
+	}
+
+	return nil, initialCursor, parseError(tokens, initialCursor, "Expected expression")
+}
+
+// Parse a function name followed by its parenthesized arguments, which
+// may be empty as in now(), or * as in count(*)
+func parseCall(tokens []*Token, initialCursor uint) (*CallExpression, uint, error) {
+	name, cursor, ok := parseToken(tokens, initialCursor, IdentifierKind)
+	if !ok {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected function name")
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(LeftParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ( after function name")
+	}
+	cursor++
+
+	call := CallExpression{Name: name}
+	if expectToken(tokens, cursor, tokenFromSymbol(AsteriskSymbol)) {
+		call.Asterisk = true
+		cursor++
+	} else if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		args, newCursor, err := parseExpressions(tokens, cursor)
+		if err != nil {
+			return nil, initialCursor, err
+		}
+		call.Args = args
+		cursor = newCursor
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(RightParenSymbol)) {
+		return nil, initialCursor, parseError(tokens, cursor, "Expected ) after function arguments")
+	}
+	cursor++
+
+	return &call, cursor, nil
+}
+
+// Consume the token at the cursor if it is of the given kind
+func parseToken(tokens []*Token, initialCursor uint, kind TokenKind) (*Token, uint, bool) {
+	if initialCursor >= uint(len(tokens)) {
+		return nil, initialCursor, false
+	}
+
+	if current := tokens[initialCursor]; current.Kind == kind {
+		return current, initialCursor + 1, true
+	}
+
+	return nil, initialCursor, false
+}
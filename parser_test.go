@@ -0,0 +1,2017 @@
+package gosql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source string
+		// Builds the expected ast from the lexed tokens it should point to
+		ast func(tokens []*Token) *Ast
+	}{
+		{
+			source: "SELECT * FROM users;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: SelectKind,
+					SelectStatement: &SelectStatement{
+						Items:    []*SelectItem{{Asterisk: true}},
+						From:     &TableReference{Kind: NamedTableKind, Name: tokens[3]},
+						Keywords: []*Token{tokens[0], tokens[2]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "SELECT a, b FROM t;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: SelectKind,
+					SelectStatement: &SelectStatement{
+						Items: []*SelectItem{
+							{Expression: &Expression{Kind: LiteralKind, Literal: tokens[1]}},
+							{Expression: &Expression{Kind: LiteralKind, Literal: tokens[3]}},
+						},
+						From:     &TableReference{Kind: NamedTableKind, Name: tokens[5]},
+						Keywords: []*Token{tokens[0], tokens[4]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "select 1, 'a';\nselect * from t;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{
+					{
+						Kind: SelectKind,
+						SelectStatement: &SelectStatement{
+							Items: []*SelectItem{
+								{Expression: &Expression{Kind: LiteralKind, Literal: tokens[1]}},
+								{Expression: &Expression{Kind: LiteralKind, Literal: tokens[3]}},
+							},
+							Keywords: []*Token{tokens[0]},
+						},
+					},
+					{
+						Kind: SelectKind,
+						SelectStatement: &SelectStatement{
+							Items:    []*SelectItem{{Asterisk: true}},
+							From:     &TableReference{Kind: NamedTableKind, Name: tokens[8]},
+							Keywords: []*Token{tokens[5], tokens[7]},
+						},
+					},
+				}}
+			},
+		},
+		{
+			source: "SELECT * FROM t WHERE id = 1;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: SelectKind,
+					SelectStatement: &SelectStatement{
+						Items: []*SelectItem{{Asterisk: true}},
+						From:  &TableReference{Kind: NamedTableKind, Name: tokens[3]},
+						Where: &Expression{
+							Kind: BinaryKind,
+							Binary: &BinaryExpression{
+								A:  &Expression{Kind: LiteralKind, Literal: tokens[5]},
+								B:  &Expression{Kind: LiteralKind, Literal: tokens[7]},
+								Op: tokens[6],
+							},
+						},
+						Keywords: []*Token{tokens[0], tokens[2], tokens[4]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "SELECT * FROM t WHERE a = -1;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: SelectKind,
+					SelectStatement: &SelectStatement{
+						Items: []*SelectItem{{Asterisk: true}},
+						From:  &TableReference{Kind: NamedTableKind, Name: tokens[3]},
+						Where: &Expression{
+							Kind: BinaryKind,
+							Binary: &BinaryExpression{
+								A: &Expression{Kind: LiteralKind, Literal: tokens[5]},
+								B: &Expression{
+									Kind: UnaryKind,
+									Unary: &UnaryExpression{
+										Op:         tokens[7],
+										Expression: &Expression{Kind: LiteralKind, Literal: tokens[8]},
+									},
+								},
+								Op: tokens[6],
+							},
+						},
+						Keywords: []*Token{tokens[0], tokens[2], tokens[4]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "CREATE TABLE users (id INT, name TEXT);",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: CreateTableKind,
+					CreateTableStatement: &CreateTableStatement{
+						Name: tokens[2],
+						Cols: []*ColumnDefinition{
+							{Name: tokens[4], Datatype: &DataType{Name: tokens[5]}},
+							{Name: tokens[7], Datatype: &DataType{Name: tokens[8]}},
+						},
+						Keywords: []*Token{tokens[0], tokens[1]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "CREATE INDEX users_name ON users (name, id);",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: CreateIndexKind,
+					CreateIndexStatement: &CreateIndexStatement{
+						Name:     tokens[2],
+						Table:    tokens[4],
+						Cols:     []*Token{tokens[6], tokens[8]},
+						Keywords: []*Token{tokens[0], tokens[1], tokens[3]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "CREATE UNIQUE INDEX users_email ON users (email);",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: CreateIndexKind,
+					CreateIndexStatement: &CreateIndexStatement{
+						Name:     tokens[3],
+						Table:    tokens[5],
+						Cols:     []*Token{tokens[7]},
+						Unique:   true,
+						Keywords: []*Token{tokens[0], tokens[1], tokens[2], tokens[4]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "CREATE VIEW adults AS SELECT name FROM users;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: CreateViewKind,
+					CreateViewStatement: &CreateViewStatement{
+						Name: tokens[2],
+						Query: &SelectStatement{
+							Items:    []*SelectItem{{Expression: &Expression{Kind: LiteralKind, Literal: tokens[5]}}},
+							From:     &TableReference{Kind: NamedTableKind, Name: tokens[7]},
+							Keywords: []*Token{tokens[4], tokens[6]},
+						},
+						Keywords: []*Token{tokens[0], tokens[1], tokens[3]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "CREATE OR REPLACE VIEW v AS SELECT 1;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: CreateViewKind,
+					CreateViewStatement: &CreateViewStatement{
+						Name: tokens[4],
+						Query: &SelectStatement{
+							Items:    []*SelectItem{{Expression: &Expression{Kind: LiteralKind, Literal: tokens[7]}}},
+							Keywords: []*Token{tokens[6]},
+						},
+						OrReplace: true,
+						Keywords:  []*Token{tokens[0], tokens[1], tokens[2], tokens[3], tokens[5]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "INSERT INTO users VALUES (105, 'George');",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: InsertKind,
+					InsertStatement: &InsertStatement{
+						Table: tokens[2],
+						Values: [][]*Expression{{
+							{Kind: LiteralKind, Literal: tokens[5]},
+							{Kind: LiteralKind, Literal: tokens[7]},
+						}},
+						Keywords: []*Token{tokens[0], tokens[1], tokens[3]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "INSERT INTO t VALUES (1,'a'), (2,'b');",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: InsertKind,
+					InsertStatement: &InsertStatement{
+						Table: tokens[2],
+						Values: [][]*Expression{
+							{
+								{Kind: LiteralKind, Literal: tokens[5]},
+								{Kind: LiteralKind, Literal: tokens[7]},
+							},
+							{
+								{Kind: LiteralKind, Literal: tokens[11]},
+								{Kind: LiteralKind, Literal: tokens[13]},
+							},
+						},
+						Keywords: []*Token{tokens[0], tokens[1], tokens[3]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "UPDATE t SET a = 1, b = a + 1 WHERE id = 5;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: UpdateKind,
+					UpdateStatement: &UpdateStatement{
+						Table: tokens[1],
+						Set: []*Assignment{
+							{Column: tokens[3], Value: &Expression{Kind: LiteralKind, Literal: tokens[5]}},
+							{Column: tokens[7], Value: &Expression{
+								Kind: BinaryKind,
+								Binary: &BinaryExpression{
+									A:  &Expression{Kind: LiteralKind, Literal: tokens[9]},
+									B:  &Expression{Kind: LiteralKind, Literal: tokens[11]},
+									Op: tokens[10],
+								},
+							}},
+						},
+						Where: &Expression{
+							Kind: BinaryKind,
+							Binary: &BinaryExpression{
+								A:  &Expression{Kind: LiteralKind, Literal: tokens[13]},
+								B:  &Expression{Kind: LiteralKind, Literal: tokens[15]},
+								Op: tokens[14],
+							},
+						},
+						Keywords: []*Token{tokens[0], tokens[2], tokens[12]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "update t set a = 'x', b = c;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: UpdateKind,
+					UpdateStatement: &UpdateStatement{
+						Table: tokens[1],
+						Set: []*Assignment{
+							{Column: tokens[3], Value: &Expression{Kind: LiteralKind, Literal: tokens[5]}},
+							{Column: tokens[7], Value: &Expression{Kind: LiteralKind, Literal: tokens[9]}},
+						},
+						Keywords: []*Token{tokens[0], tokens[2]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "DELETE FROM t WHERE id = 1;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: DeleteKind,
+					DeleteStatement: &DeleteStatement{
+						Table: tokens[2],
+						Where: &Expression{
+							Kind: BinaryKind,
+							Binary: &BinaryExpression{
+								A:  &Expression{Kind: LiteralKind, Literal: tokens[4]},
+								B:  &Expression{Kind: LiteralKind, Literal: tokens[6]},
+								Op: tokens[5],
+							},
+						},
+						Keywords: []*Token{tokens[0], tokens[1], tokens[3]},
+					},
+				}}}
+			},
+		},
+		{
+			// Deletes every row
+			source: "delete from t;",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{Statements: []*Statement{{
+					Kind: DeleteKind,
+					DeleteStatement: &DeleteStatement{
+						Table:    tokens[2],
+						Keywords: []*Token{tokens[0], tokens[1]},
+					},
+				}}}
+			},
+		},
+		{
+			source: "",
+			ast: func(tokens []*Token) *Ast {
+				return &Ast{}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, test.ast(tokens), ast, test.source)
+	}
+}
+
+func TestExpectToken(t *testing.T) {
+	tokens := []*Token{
+		{Value: "SELECT", Kind: KeywordKind, keyword: SelectKeyword},
+		{Value: "select", Kind: IdentifierKind},
+		{Value: "from", Kind: KeywordKind},
+		{Value: string(CommaSymbol), Kind: SymbolKind},
+	}
+
+	// Keywords match by their resolved Keyword, or their value if unresolved
+	assert.True(t, expectToken(tokens, 0, tokenFromKeyword(SelectKeyword)))
+	assert.False(t, expectToken(tokens, 1, tokenFromKeyword(SelectKeyword)))
+	assert.True(t, expectToken(tokens, 2, tokenFromKeyword(FromKeyword)))
+	assert.False(t, expectToken(tokens, 2, tokenFromKeyword(SelectKeyword)))
+	assert.True(t, expectToken(tokens, 3, tokenFromSymbol(CommaSymbol)))
+	assert.False(t, expectToken(tokens, 3, tokenFromKeyword(SelectKeyword)))
+	assert.False(t, expectToken(tokens, 4, tokenFromSymbol(CommaSymbol)))
+}
+
+func TestParse_ignoresWhitespaceAndComments(t *testing.T) {
+	tokens, err := LexWithOptions("select /* all */ *\nfrom users; -- done", LexOptions{
+		PreserveWhitespace: true,
+		PreserveComments:   true,
+		EmitEOF:            true,
+	})
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ast.Statements))
+	assert.Equal(t, "users", ast.Statements[0].SelectStatement.From.Name.Value)
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		source  string
+		message string
+		loc     Location
+	}{
+		{
+			source:  "SELECT FROM users;",
+			message: "Expected expression, got from",
+			loc:     Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:  "SELECT a,\n  FROM users;",
+			message: "Expected expression, got from",
+			loc:     Location{Line: 1, Col: 2, Offset: 12},
+		},
+		{
+			source:  "SELECT a FROM;",
+			message: "Expected table name, got ;",
+			loc:     Location{Line: 0, Col: 13, Offset: 13},
+		},
+		{
+			source:  "SELECT a FROM t",
+			message: "Expected semicolon after statement, got end of input",
+			loc:     Location{Line: 0, Col: 15, Offset: 15},
+		},
+		{
+			source:  "SELECT a b c FROM t;",
+			message: "Expected semicolon after statement, got c",
+			loc:     Location{Line: 0, Col: 11, Offset: 11},
+		},
+		{
+			source:  "SELECT a AS FROM t;",
+			message: "Expected alias after AS, got from",
+			loc:     Location{Line: 0, Col: 12, Offset: 12},
+		},
+		{
+			source:  "SELECT a AS 'x' FROM t;",
+			message: "Expected alias after AS, got x",
+			loc:     Location{Line: 0, Col: 12, Offset: 12},
+		},
+		{
+			source:  "SELECT",
+			message: "Expected expression, got end of input",
+			loc:     Location{Line: 0, Col: 6, Offset: 6},
+		},
+		{
+			source:  "SELECT * FROM t WHERE id = ;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 27, Offset: 27},
+		},
+		{
+			source:  "SELECT * FROM t WHERE;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 21, Offset: 21},
+		},
+		{
+			source:  "SELECT * FROM t WHERE (id = 1;",
+			message: "Expected ) after expression, got ;",
+			loc:     Location{Line: 0, Col: 29, Offset: 29},
+		},
+		{
+			source:  "SELECT * FROM t ORDER a;",
+			message: "Expected BY after ORDER, got a",
+			loc:     Location{Line: 0, Col: 22, Offset: 22},
+		},
+		{
+			source:  "SELECT * FROM t ORDER BY;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 24, Offset: 24},
+		},
+		{
+			source:  "SELECT * FROM t ORDER BY a DESC ASC;",
+			message: "Expected semicolon after statement, got asc",
+			loc:     Location{Line: 0, Col: 32, Offset: 32},
+		},
+		{
+			source:  "SELECT * FROM a JOIN b;",
+			message: "Expected ON after joined table, got ;",
+			loc:     Location{Line: 0, Col: 22, Offset: 22},
+		},
+		{
+			source:  "SELECT * FROM a LEFT JOIN b WHERE a.id = 1;",
+			message: "Expected ON after joined table, got where",
+			loc:     Location{Line: 0, Col: 28, Offset: 28},
+		},
+		{
+			source:  "SELECT * FROM a LEFT b ON a.id = b.id;",
+			message: "Expected JOIN, got b",
+			loc:     Location{Line: 0, Col: 21, Offset: 21},
+		},
+		{
+			source:  "SELECT * FROM a JOIN ON a.id = 1;",
+			message: "Expected table name, got on",
+			loc:     Location{Line: 0, Col: 21, Offset: 21},
+		},
+		{
+			source:  "SELECT * FROM a, ;",
+			message: "Expected table name, got ;",
+			loc:     Location{Line: 0, Col: 17, Offset: 17},
+		},
+		{
+			source:  "SELECT * FROM a, LATERAL b;",
+			message: "Expected subquery after LATERAL, got b",
+			loc:     Location{Line: 0, Col: 25, Offset: 25},
+		},
+		{
+			source:  "SELECT a. FROM t;",
+			message: "Expected column name after ., got from",
+			loc:     Location{Line: 0, Col: 10, Offset: 10},
+		},
+		{
+			source:  "SELECT DISTINCT FROM t;",
+			message: "Expected expression, got from",
+			loc:     Location{Line: 0, Col: 16, Offset: 16},
+		},
+		{
+			source:  "SELECT DISTINCT;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 15, Offset: 15},
+		},
+		{
+			source:  "SELECT * FROM (SELECT a FROM t sub;",
+			message: "Expected ) after subquery, got ;",
+			loc:     Location{Line: 0, Col: 34, Offset: 34},
+		},
+		{
+			source:  "SELECT * FROM t WHERE a IN (SELECT FROM u);",
+			message: "Expected expression, got from",
+			loc:     Location{Line: 0, Col: 35, Offset: 35},
+		},
+		{
+			source:  "SELECT * FROM (SELECT a FROM t) AS;",
+			message: "Expected alias after AS, got ;",
+			loc:     Location{Line: 0, Col: 34, Offset: 34},
+		},
+		{
+			source:  "SELECT count(* FROM t;",
+			message: "Expected ) after function arguments, got from",
+			loc:     Location{Line: 0, Col: 15, Offset: 15},
+		},
+		{
+			source:  "SELECT a, () FROM t;",
+			message: "Expected expression inside parentheses, got )",
+			loc:     Location{Line: 0, Col: 11, Offset: 11},
+		},
+		{
+			source:  "SELECT (a + ()) * 2 FROM t;",
+			message: "Expected expression inside parentheses, got )",
+			loc:     Location{Line: 0, Col: 13, Offset: 13},
+		},
+		{
+			source:  "SELECT lower(a,) FROM t;",
+			message: "Expected expression, got )",
+			loc:     Location{Line: 0, Col: 15, Offset: 15},
+		},
+		{
+			source:  "SELECT lower(a b) FROM t;",
+			message: "Expected ) after function arguments, got b",
+			loc:     Location{Line: 0, Col: 15, Offset: 15},
+		},
+		{
+			source:  "SELECT a FROM t GROUP a;",
+			message: "Expected BY after GROUP, got a",
+			loc:     Location{Line: 0, Col: 22, Offset: 22},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY a,;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 27, Offset: 27},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY ROLLUP a;",
+			message: "Expected ( after ROLLUP, got a",
+			loc:     Location{Line: 0, Col: 32, Offset: 32},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY CUBE (a;",
+			message: "Expected ) after CUBE expressions, got ;",
+			loc:     Location{Line: 0, Col: 32, Offset: 32},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY GROUPING (a);",
+			message: "Expected SETS after GROUPING, got (",
+			loc:     Location{Line: 0, Col: 34, Offset: 34},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY GROUPING SETS ((a), b;",
+			message: "Expected ) after GROUPING SETS, got ;",
+			loc:     Location{Line: 0, Col: 46, Offset: 46},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY a HAVING;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 33, Offset: 33},
+		},
+		{
+			source:  "SELECT * FROM t LIMIT 1.5;",
+			message: "Expected integer after LIMIT, got 1.5",
+			loc:     Location{Line: 0, Col: 22, Offset: 22},
+		},
+		{
+			source:  "SELECT * FROM t LIMIT;",
+			message: "Expected integer after LIMIT, got ;",
+			loc:     Location{Line: 0, Col: 21, Offset: 21},
+		},
+		{
+			source:  "SELECT * FROM t LIMIT 10 OFFSET 1e3;",
+			message: "Expected integer after OFFSET, got 1e3",
+			loc:     Location{Line: 0, Col: 32, Offset: 32},
+		},
+		{
+			source:  "SELECT * FROM t OFFSET 5 LIMIT 10;",
+			message: "Expected semicolon after statement, got limit",
+			loc:     Location{Line: 0, Col: 25, Offset: 25},
+		},
+		{
+			source:  "CREATE TABLE users (id INT, name TEXT,);",
+			message: "Expected column name, got )",
+			loc:     Location{Line: 0, Col: 38, Offset: 38},
+		},
+		{
+			source:  "CREATE TABLE users id INT;",
+			message: "Expected ( before column definitions, got id",
+			loc:     Location{Line: 0, Col: 19, Offset: 19},
+		},
+		{
+			source:  "CREATE TABLE users (id INT;",
+			message: "Expected ) after column definitions, got ;",
+			loc:     Location{Line: 0, Col: 26, Offset: 26},
+		},
+		{
+			source:  "CREATE TABLE users (id INT, name string);",
+			message: "Expected column type, got string",
+			loc:     Location{Line: 0, Col: 33, Offset: 33},
+		},
+		{
+			source:  "CREATE TABLE users (id);",
+			message: "Expected column type, got )",
+			loc:     Location{Line: 0, Col: 22, Offset: 22},
+		},
+		{
+			source:  "CREATE TABLE users ();",
+			message: "Expected at least one column definition, got )",
+			loc:     Location{Line: 0, Col: 20, Offset: 20},
+		},
+		{
+			source:  "CREATE TABLE users (id INT PRIMARY NOT NULL);",
+			message: "Expected KEY, got not",
+			loc:     Location{Line: 0, Col: 35, Offset: 35},
+		},
+		{
+			source:  "CREATE TABLE users (id INT NOT UNIQUE);",
+			message: "Expected NULL, got unique",
+			loc:     Location{Line: 0, Col: 31, Offset: 31},
+		},
+		{
+			source:  "CREATE TABLE users (id INT UNIQUE NOT NULL UNIQUE);",
+			message: "Duplicate column constraint, got unique",
+			loc:     Location{Line: 0, Col: 43, Offset: 43},
+		},
+		{
+			source:  "CREATE TABLE jobs (status TEXT DEFAULT);",
+			message: "Expected expression, got )",
+			loc:     Location{Line: 0, Col: 38, Offset: 38},
+		},
+		{
+			source:  "CREATE TABLE jobs (id INT DEFAULT 0 DEFAULT 1);",
+			message: "Duplicate DEFAULT, got default",
+			loc:     Location{Line: 0, Col: 36, Offset: 36},
+		},
+		{
+			source:  "CREATE TABLE t (name VARCHAR(abc));",
+			message: "Expected integer size, got abc",
+			loc:     Location{Line: 0, Col: 29, Offset: 29},
+		},
+		{
+			source:  "CREATE TABLE t (name VARCHAR(1.5));",
+			message: "Expected integer size, got 1.5",
+			loc:     Location{Line: 0, Col: 29, Offset: 29},
+		},
+		{
+			source:  "CREATE TABLE t (name VARCHAR(10, 2));",
+			message: "Expected ) after size, got ,",
+			loc:     Location{Line: 0, Col: 31, Offset: 31},
+		},
+		{
+			source:  "CREATE TABLE t (n NUMERIC(10, 2, 1));",
+			message: "Expected ) after size, got ,",
+			loc:     Location{Line: 0, Col: 31, Offset: 31},
+		},
+		{
+			source:  "CREATE TABLE t (n NUMERIC());",
+			message: "Expected integer size, got )",
+			loc:     Location{Line: 0, Col: 26, Offset: 26},
+		},
+		{
+			source:  "CREATE TABLE t (id INT(11));",
+			message: "Expected no size for INT, got (",
+			loc:     Location{Line: 0, Col: 22, Offset: 22},
+		},
+		{
+			source:  "CREATE TABLE (id INT);",
+			message: "Expected table name, got (",
+			loc:     Location{Line: 0, Col: 13, Offset: 13},
+		},
+		{
+			source:  "CREATE users (id INT);",
+			message: "Expected TABLE, got users",
+			loc:     Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:  "CREATE INDEX i users (a);",
+			message: "Expected ON after index name, got users",
+			loc:     Location{Line: 0, Col: 15, Offset: 15},
+		},
+		{
+			source:  "CREATE UNIQUE INDEX i ON users ();",
+			message: "Expected at least one index column, got )",
+			loc:     Location{Line: 0, Col: 32, Offset: 32},
+		},
+		{
+			source:  `CREATE "index" i ON users (a);`,
+			message: "Expected TABLE, got index",
+			loc:     Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:  "CREATE UNIQUE users_a ON users (a);",
+			message: "Expected INDEX, got users_a",
+			loc:     Location{Line: 0, Col: 14, Offset: 14},
+		},
+		{
+			source:  "CREATE INDEX ON users (a);",
+			message: "Expected index name, got on",
+			loc:     Location{Line: 0, Col: 13, Offset: 13},
+		},
+		{
+			source:  "CREATE INDEX i ON users (a,);",
+			message: "Expected column name, got )",
+			loc:     Location{Line: 0, Col: 27, Offset: 27},
+		},
+		{
+			source:  "CREATE INDEX i ON users (a b);",
+			message: "Expected ) after index columns, got b",
+			loc:     Location{Line: 0, Col: 27, Offset: 27},
+		},
+		{
+			source:  "CREATE OR VIEW v AS SELECT 1;",
+			message: "Expected REPLACE after CREATE OR, got view",
+			loc:     Location{Line: 0, Col: 10, Offset: 10},
+		},
+		{
+			source:  "CREATE OR REPLACE TABLE t (a INT);",
+			message: "Expected VIEW, got table",
+			loc:     Location{Line: 0, Col: 18, Offset: 18},
+		},
+		{
+			source:  "CREATE VIEW AS SELECT 1;",
+			message: "Expected view name, got as",
+			loc:     Location{Line: 0, Col: 12, Offset: 12},
+		},
+		{
+			source:  "CREATE VIEW v SELECT 1;",
+			message: "Expected AS after view name, got select",
+			loc:     Location{Line: 0, Col: 14, Offset: 14},
+		},
+		{
+			source:  "CREATE VIEW v AS INSERT INTO t VALUES (1);",
+			message: "Expected SELECT after AS, got insert",
+			loc:     Location{Line: 0, Col: 17, Offset: 17},
+		},
+		{
+			source:  "INSERT users VALUES (1);",
+			message: "Expected INTO, got users",
+			loc:     Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:  "INSERT INTO users (1);",
+			message: "Expected VALUES, got (",
+			loc:     Location{Line: 0, Col: 18, Offset: 18},
+		},
+		{
+			source:  "INSERT INTO users VALUES 1);",
+			message: "Expected ( before values, got 1",
+			loc:     Location{Line: 0, Col: 25, Offset: 25},
+		},
+		{
+			source:  "INSERT INTO users VALUES (1, 2;",
+			message: "Expected ) after values, got ;",
+			loc:     Location{Line: 0, Col: 30, Offset: 30},
+		},
+		{
+			source:  "INSERT INTO users VALUES ();",
+			message: "Expected at least one value, got )",
+			loc:     Location{Line: 0, Col: 26, Offset: 26},
+		},
+		{
+			source:  "INSERT INTO users VALUES (1, 2), (3);",
+			message: "Expected 2 values in each row, got 1",
+			loc:     Location{Line: 0, Col: 33, Offset: 33},
+		},
+		{
+			source:  "INSERT INTO users VALUES (1), (2), (3, 4);",
+			message: "Expected 1 values in each row, got 2",
+			loc:     Location{Line: 0, Col: 35, Offset: 35},
+		},
+		{
+			source:  "INSERT INTO users VALUES (1), 2;",
+			message: "Expected ( before values, got 2",
+			loc:     Location{Line: 0, Col: 30, Offset: 30},
+		},
+		{
+			source:  "INSERT INTO users VALUES (1),;",
+			message: "Expected ( before values, got ;",
+			loc:     Location{Line: 0, Col: 29, Offset: 29},
+		},
+		{
+			source:  "INSERT INTO users VALUES (1,);",
+			message: "Expected expression, got )",
+			loc:     Location{Line: 0, Col: 28, Offset: 28},
+		},
+		{
+			source:  "UPDATE SET a = 1;",
+			message: "Expected table name, got set",
+			loc:     Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:  "UPDATE t a = 1;",
+			message: "Expected SET, got a",
+			loc:     Location{Line: 0, Col: 9, Offset: 9},
+		},
+		{
+			source:  "UPDATE t SET WHERE a = 1;",
+			message: "Expected at least one assignment, got where",
+			loc:     Location{Line: 0, Col: 13, Offset: 13},
+		},
+		{
+			source:  "UPDATE t SET;",
+			message: "Expected at least one assignment, got ;",
+			loc:     Location{Line: 0, Col: 12, Offset: 12},
+		},
+		{
+			source:  "UPDATE t SET a 1;",
+			message: "Expected = after column, got 1",
+			loc:     Location{Line: 0, Col: 15, Offset: 15},
+		},
+		{
+			source:  "UPDATE t SET a = 1, WHERE b;",
+			message: "Expected column name, got where",
+			loc:     Location{Line: 0, Col: 20, Offset: 20},
+		},
+		{
+			source:  "UPDATE t SET a = ;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 17, Offset: 17},
+		},
+		{
+			source:  "UPDATE t SET a = 1 WHERE;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 24, Offset: 24},
+		},
+		{
+			source:  "DELETE t WHERE a = 1;",
+			message: "Expected FROM, got t",
+			loc:     Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:  "DELETE FROM WHERE a = 1;",
+			message: "Expected table name, got where",
+			loc:     Location{Line: 0, Col: 12, Offset: 12},
+		},
+		{
+			source:  "DELETE FROM t WHERE;",
+			message: "Expected expression, got ;",
+			loc:     Location{Line: 0, Col: 19, Offset: 19},
+		},
+		{
+			source:  "DELETE FROM t u;",
+			message: "Expected semicolon after statement, got u",
+			loc:     Location{Line: 0, Col: 14, Offset: 14},
+		},
+		{
+			source:  "users;",
+			message: "Expected statement, got users",
+			loc:     Location{Line: 0, Col: 0, Offset: 0},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		_, err = Parse(tokens)
+		var parseErr *ParseError
+		if assert.True(t, errors.As(err, &parseErr), test.source) {
+			assert.Equal(t, test.message, parseErr.Message, test.source)
+			assert.Equal(t, test.loc, parseErr.Loc, test.source)
+		}
+	}
+}
+
+func TestParse_insertValues(t *testing.T) {
+	tokens, err := Lex("insert into t values (1, 'a b', 2.5e3, c);")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	expected := []Token{
+		{Value: "1", Kind: NumericKind},
+		{Value: "a b", Kind: StringKind},
+		{Value: "2.5e3", Kind: NumericKind},
+		{Value: "c", Kind: IdentifierKind},
+	}
+	assert.Equal(t, 1, len(ast.Statements[0].InsertStatement.Values))
+	values := ast.Statements[0].InsertStatement.Values[0]
+	assert.Equal(t, len(expected), len(values))
+	for i, value := range values {
+		assert.Equal(t, LiteralKind, value.Kind)
+		assert.Equal(t, expected[i].Value, value.Literal.Value)
+		assert.Equal(t, expected[i].Kind, value.Literal.Kind)
+	}
+}
+
+// Render an expression's tree as an s-expression, eg (+ a (* b c))
+func sexp(exp *Expression) string {
+	switch exp.Kind {
+	case LiteralKind:
+		if exp.Qualifier != nil {
+			return exp.Qualifier.Value + "." + exp.Literal.Value
+		}
+		return exp.Literal.Value
+	case SubqueryKind:
+		return "(" + exp.Subquery.String() + ")"
+	case CallKind:
+		tree := "(" + exp.Call.Name.Value + "()"
+		if exp.Call.Asterisk {
+			tree += " *"
+		}
+		for _, arg := range exp.Call.Args {
+			tree += " " + sexp(arg)
+		}
+		return tree + ")"
+	case BinaryKind:
+		return fmt.Sprintf("(%s %s %s)", exp.Binary.Op.Value, sexp(exp.Binary.A), sexp(exp.Binary.B))
+	case UnaryKind:
+		return fmt.Sprintf("(%s %s)", exp.Unary.Op.Value, sexp(exp.Unary.Expression))
+	case IsNullKind:
+		if exp.IsNull.Not {
+			return "(is-not-null " + sexp(exp.IsNull.Expression) + ")"
+		}
+		return "(is-null " + sexp(exp.IsNull.Expression) + ")"
+	case CastKind:
+		return fmt.Sprintf("(:: %s %s)", sexp(exp.Cast.Expression), exp.Cast.Type)
+	case CaseKind:
+		tree := "(case"
+		if exp.Case.Operand != nil {
+			tree += " " + sexp(exp.Case.Operand)
+		}
+		for _, when := range exp.Case.Whens {
+			tree += fmt.Sprintf(" (when %s %s)", sexp(when.Condition), sexp(when.Result))
+		}
+		if exp.Case.Else != nil {
+			tree += " (else " + sexp(exp.Case.Else) + ")"
+		}
+		return tree + ")"
+	case BetweenKind:
+		op := "between"
+		if exp.Between.Not {
+			op = "not-between"
+		}
+		return fmt.Sprintf("(%s %s %s %s)", op, sexp(exp.Between.Expression), sexp(exp.Between.Low), sexp(exp.Between.High))
+	case InKind:
+		tree := "(in " + sexp(exp.In.Expression)
+		if exp.In.Not {
+			tree = "(not-in " + sexp(exp.In.Expression)
+		}
+		if exp.In.Subquery != nil {
+			return tree + " (" + exp.In.Subquery.String() + "))"
+		}
+		for _, value := range exp.In.Values {
+			tree += " " + sexp(value)
+		}
+		return tree + ")"
+	case LikeKind:
+		op := "like"
+		if exp.Like.Not {
+			op = "not-like"
+		}
+		return fmt.Sprintf("(%s %s %s)", op, sexp(exp.Like.Expression), sexp(exp.Like.Pattern))
+	}
+	return "?"
+}
+
+func TestParse_expressionPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		tree   string
+	}{
+		{
+			source: "a + b * c = d",
+			tree:   "(= (+ a (* b c)) d)",
+		},
+		{
+			source: "(a + b) * c",
+			tree:   "(* (+ a b) c)",
+		},
+		{
+			source: "a * (b + c)",
+			tree:   "(* a (+ b c))",
+		},
+		{
+			source: "a - b - c",
+			tree:   "(- (- a b) c)",
+		},
+		{
+			source: "a / b * c",
+			tree:   "(* (/ a b) c)",
+		},
+		{
+			source: "a = 1 or b = 2 and c <> 3",
+			tree:   "(or (= a 1) (and (= b 2) (<> c 3)))",
+		},
+		{
+			source: "(a = 1 or b = 2) and c",
+			tree:   "(and (or (= a 1) (= b 2)) c)",
+		},
+		{
+			source: "((a))",
+			tree:   "a",
+		},
+		{
+			source: "a BETWEEN 1 AND 5",
+			tree:   "(between a 1 5)",
+		},
+		{
+			source: "a NOT BETWEEN 1 AND 5",
+			tree:   "(not-between a 1 5)",
+		},
+		{
+			source: "a BETWEEN b + 1 AND c * 2 AND d",
+			tree:   "(and (between a (+ b 1) (* c 2)) d)",
+		},
+		{
+			source: "a = 1 OR b NOT BETWEEN 'a' AND 'z' AND c = 2",
+			tree:   "(or (= a 1) (and (not-between b a z) (= c 2)))",
+		},
+		{
+			source: "NOT a = b AND c",
+			tree:   "(and (not (= a b)) c)",
+		},
+		{
+			source: "a OR NOT NOT b",
+			tree:   "(or a (not (not b)))",
+		},
+		{
+			source: "NOT a BETWEEN 1 AND 2 OR (NOT b) = c",
+			tree:   "(or (not (between a 1 2)) (= (not b) c))",
+		},
+		{
+			source: "-a * b - -c",
+			tree:   "(- (* (- a) b) (- c))",
+		},
+		{
+			source: "+1 = - -a::int",
+			tree:   "(= (+ 1) (- (- (:: a INT))))",
+		},
+		{
+			source: "-(a + b) < c",
+			tree:   "(< (- (+ a b)) c)",
+		},
+		{
+			source: "a IS NULL",
+			tree:   "(is-null a)",
+		},
+		{
+			source: "a + 1 IS NOT NULL AND NOT b IS NULL",
+			tree:   "(and (is-not-null (+ a 1)) (not (is-null b)))",
+		},
+		{
+			source: "a = b IS NULL",
+			tree:   "(is-null (= a b))",
+		},
+		{
+			source: "(a = b) IS NOT NULL IS NULL",
+			tree:   "(is-null (is-not-null (= a b)))",
+		},
+		{
+			source: "a + 1 BETWEEN low AND (high)",
+			tree:   "(between (+ a 1) low high)",
+		},
+		{
+			source: "a AND b",
+			tree:   "(and a b)",
+		},
+		{
+			source: "CASE WHEN a = 1 THEN 'x' WHEN a = 2 THEN 'y' ELSE 'z' END",
+			tree:   "(case (when (= a 1) x) (when (= a 2) y) (else z))",
+		},
+		{
+			source: "CASE a WHEN 1 THEN 'x' WHEN 2 THEN 'y' END",
+			tree:   "(case a (when 1 x) (when 2 y))",
+		},
+		{
+			source: "CASE a + 1 WHEN b THEN CASE WHEN c THEN d END END || 'e'",
+			tree:   "(|| (case (+ a 1) (when b (case (when c d)))) e)",
+		},
+		{
+			source: "'123'::int",
+			tree:   "(:: 123 INT)",
+		},
+		{
+			source: "a::varchar(10)",
+			tree:   "(:: a VARCHAR(10))",
+		},
+		{
+			source: "a::text || b",
+			tree:   "(|| (:: a TEXT) b)",
+		},
+		{
+			source: "(a + b)::float::int * 2",
+			tree:   "(* (:: (:: (+ a b) FLOAT) INT) 2)",
+		},
+		{
+			source: "count(*)",
+			tree:   "(count() *)",
+		},
+		{
+			source: "lower(name)",
+			tree:   "(lower() name)",
+		},
+		{
+			source: "sum(abs(x))",
+			tree:   "(sum() (abs() x))",
+		},
+		{
+			source: "now()",
+			tree:   "(now())",
+		},
+		{
+			source: "coalesce(a, b + 1, 'c') * 2",
+			tree:   "(* (coalesce() a (+ b 1) c) 2)",
+		},
+		{
+			source: "a || 'x' != b",
+			tree:   "(!= (|| a x) b)",
+		},
+		{
+			source: "a <= 1 and b >= 2",
+			tree:   "(and (<= a 1) (>= b 2))",
+		},
+		{
+			source: "a IN (1, 2, 3)",
+			tree:   "(in a 1 2 3)",
+		},
+		{
+			source: "a NOT IN ('x', b + 1) AND c",
+			tree:   "(and (not-in a x (+ b 1)) c)",
+		},
+		{
+			source: "NOT a IN (1)",
+			tree:   "(not (in a 1))",
+		},
+		{
+			source: "a + 1 IN (b) = c",
+			tree:   "(= (in (+ a 1) b) c)",
+		},
+		{
+			source: "a = b IN (c)",
+			tree:   "(in (= a b) c)",
+		},
+		{
+			source: "name LIKE 'a%'",
+			tree:   "(like name a%)",
+		},
+		{
+			source: "name NOT LIKE '%z' AND id = 1",
+			tree:   "(and (not-like name %z) (= id 1))",
+		},
+		{
+			source: "a LIKE b || '%' OR NOT a LIKE c",
+			tree:   "(or (like a (|| b %)) (not (like a c)))",
+		},
+		{
+			source: "a LIKE b = c",
+			tree:   "(= (like a b) c)",
+		},
+		{
+			source: "a NOT IN (SELECT b FROM t) OR a IN ((SELECT c FROM u), 2)",
+			tree:   "(or (not-in a (SELECT b FROM t)) (in a (SELECT c FROM u) 2))",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		exp, cursor, err := parseExpression(tokens, 0)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, uint(len(tokens)), cursor, test.source)
+		assert.Equal(t, test.tree, sexp(exp), test.source)
+	}
+}
+
+func TestParse_expressionErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		message string
+		loc     Location
+	}{
+		{
+			source:  "(a + b",
+			message: "Expected ) after expression, got end of input",
+			loc:     Location{Line: 0, Col: 6, Offset: 6},
+		},
+		{
+			source:  "a +",
+			message: "Expected expression, got end of input",
+			loc:     Location{Line: 0, Col: 3, Offset: 3},
+		},
+		{
+			source:  "a * and b",
+			message: "Expected expression, got and",
+			loc:     Location{Line: 0, Col: 4, Offset: 4},
+		},
+		{
+			source:  "a BETWEEN 1 OR 5",
+			message: "Expected AND after lower bound of BETWEEN, got or",
+			loc:     Location{Line: 0, Col: 12, Offset: 12},
+		},
+		{
+			source:  "a IS NOT b",
+			message: "Expected NULL after IS NOT, got b",
+			loc:     Location{Line: 0, Col: 9, Offset: 9},
+		},
+		{
+			source:  "a IS 1",
+			message: "Expected NULL after IS, got 1",
+			loc:     Location{Line: 0, Col: 5, Offset: 5},
+		},
+		{
+			source:  "a AND NOT",
+			message: "Expected expression, got end of input",
+			loc:     Location{Line: 0, Col: 9, Offset: 9},
+		},
+		{
+			source:  "a * -",
+			message: "Expected expression, got end of input",
+			loc:     Location{Line: 0, Col: 5, Offset: 5},
+		},
+		{
+			source:  "a NOT BETWEEN 1 AND",
+			message: "Expected expression, got end of input",
+			loc:     Location{Line: 0, Col: 19, Offset: 19},
+		},
+		{
+			source:  "CASE WHEN a = 1 THEN 'x' ELSE 'z'",
+			message: "Expected END after CASE, got end of input",
+			loc:     Location{Line: 0, Col: 33, Offset: 33},
+		},
+		{
+			source:  "CASE WHEN a = 1 THEN 'x' b END",
+			message: "Expected END after CASE, got b",
+			loc:     Location{Line: 0, Col: 25, Offset: 25},
+		},
+		{
+			source:  "CASE a ELSE 'z' END",
+			message: "Expected WHEN in CASE, got else",
+			loc:     Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:  "CASE WHEN a = 1 'x' END",
+			message: "Expected THEN after WHEN condition, got x",
+			loc:     Location{Line: 0, Col: 16, Offset: 16},
+		},
+		{
+			source:  "a::integer",
+			message: "Expected type after ::, got integer",
+			loc:     Location{Line: 0, Col: 3, Offset: 3},
+		},
+		{
+			source:  "a::",
+			message: "Expected type after ::, got end of input",
+			loc:     Location{Line: 0, Col: 3, Offset: 3},
+		},
+		{
+			source:  "()",
+			message: "Expected expression inside parentheses, got )",
+			loc:     Location{Line: 0, Col: 1, Offset: 1},
+		},
+		{
+			source:  "a IN ()",
+			message: "Expected at least one value in IN list, got )",
+			loc:     Location{Line: 0, Col: 6, Offset: 6},
+		},
+		{
+			source:  "a NOT IN 1",
+			message: "Expected ( after IN, got 1",
+			loc:     Location{Line: 0, Col: 9, Offset: 9},
+		},
+		{
+			source:  "a IN (1, 2",
+			message: "Expected ) after IN list, got end of input",
+			loc:     Location{Line: 0, Col: 10, Offset: 10},
+		},
+		{
+			source:  "a NOT LIKE",
+			message: "Expected expression, got end of input",
+			loc:     Location{Line: 0, Col: 10, Offset: 10},
+		},
+		{
+			source:  "a IN (1,)",
+			message: "Expected expression, got )",
+			loc:     Location{Line: 0, Col: 8, Offset: 8},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		_, _, err = parseExpression(tokens, 0)
+		var parseErr *ParseError
+		if assert.True(t, errors.As(err, &parseErr), test.source) {
+			assert.Equal(t, test.message, parseErr.Message, test.source)
+			assert.Equal(t, test.loc, parseErr.Loc, test.source)
+		}
+	}
+}
+
+func TestBinaryOperatorPrecedence(t *testing.T) {
+	assert.Greater(t, BinaryOperatorPrecedence[string(AsteriskSymbol)], BinaryOperatorPrecedence[string(PlusSymbol)])
+	assert.Greater(t, BinaryOperatorPrecedence[string(PlusSymbol)], BinaryOperatorPrecedence[string(EqualsSymbol)])
+	assert.Greater(t, BinaryOperatorPrecedence[string(EqualsSymbol)], BinaryOperatorPrecedence[string(AndKeyword)])
+	assert.Greater(t, BinaryOperatorPrecedence[string(AndKeyword)], BinaryOperatorPrecedence[string(OrKeyword)])
+}
+
+func TestParse_nullSafeEqual(t *testing.T) {
+	tokens, err := LexWithOptions("a <=> b AND c", LexOptions{Dialect: MySQLDialect})
+	assert.Nil(t, err)
+	exp, _, err := parseExpression(tokens, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "(and (<=> a b) c)", sexp(exp))
+}
+
+func TestParse_jsonAccess(t *testing.T) {
+	tokens, err := LexWithOptions("data->'a'->>'b' = 'x' || y", LexOptions{Dialect: PostgresDialect})
+	assert.Nil(t, err)
+	exp, _, err := parseExpression(tokens, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "(= (->> (-> data a) b) (|| x y))", sexp(exp))
+}
+
+func TestParse_where(t *testing.T) {
+	tests := []struct {
+		source string
+		// Empty when there's no WHERE clause
+		where string
+	}{
+		{
+			source: "SELECT * FROM t;",
+		},
+		{
+			source: "SELECT a FROM t WHERE a = 1 AND b <> 'x';",
+			where:  "(and (= a 1) (<> b x))",
+		},
+		{
+			source: "SELECT a FROM t WHERE (a + 1) * 2 > b OR c;",
+			where:  "(or (> (* (+ a 1) 2) b) c)",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		assert.Nil(t, err, test.source)
+
+		where := ast.Statements[0].SelectStatement.Where
+		if test.where == "" {
+			assert.Nil(t, where, test.source)
+		} else if assert.NotNil(t, where, test.source) {
+			assert.Equal(t, test.where, sexp(where), test.source)
+		}
+	}
+}
+
+func TestParse_aliases(t *testing.T) {
+	tokens, err := Lex("SELECT a AS x, b + 1 n, c, *, \"d\" AS \"Quoted\", count(*) AS n FROM t;")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		item string
+		// Empty for no alias
+		as string
+	}{
+		{item: "a", as: "x"},
+		{item: "(+ b 1)", as: "n"},
+		{item: "c"},
+		{item: "*"},
+		{item: "d", as: "Quoted"},
+		{item: "(count() *)", as: "n"},
+	}
+
+	items := ast.Statements[0].SelectStatement.Items
+	assert.Equal(t, len(tests), len(items))
+	for i, test := range tests {
+		item := items[i]
+		if item.Asterisk {
+			assert.Equal(t, test.item, "*")
+		} else {
+			assert.Equal(t, test.item, sexp(item.Expression))
+		}
+
+		if test.as == "" {
+			assert.Nil(t, item.As, test.item)
+		} else if assert.NotNil(t, item.As, test.item) {
+			assert.Equal(t, test.as, item.As.Value, test.item)
+		}
+	}
+}
+
+func TestParse_quotedKeywords(t *testing.T) {
+	tokens, err := Lex(`SELECT "from" FROM "select";`)
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	if assert.Nil(t, err) {
+		slct := ast.Statements[0].SelectStatement
+		assert.Equal(t, IdentifierKind, slct.Items[0].Expression.Literal.Kind)
+		assert.Equal(t, "from", slct.Items[0].Expression.Literal.Value)
+		assert.Equal(t, IdentifierKind, slct.From.Name.Kind)
+		assert.Equal(t, "select", slct.From.Name.Value)
+	}
+
+	// Quoted identifiers may be any keyword wherever an identifier is
+	// expected, and are quoted again when formatted
+	sources := []string{
+		`SELECT "select"."from" AS "as" FROM "select" WHERE "where" = 1 ORDER BY "order";`,
+		`SELECT count("null") FROM "join" AS "on" INNER JOIN "left" ON "on"."and" = "left"."or";`,
+		`CREATE TABLE "table" ("int" INT, "primary" TEXT);`,
+		`INSERT INTO "insert" VALUES (1);`,
+		`UPDATE "update" SET "set" = "values";`,
+		`DELETE FROM "delete" WHERE "not" IS NULL;`,
+	}
+	for _, source := range sources {
+		tokens, err := Lex(source)
+		assert.Nil(t, err, source)
+		ast, err := Parse(tokens)
+		if assert.Nil(t, err, source) {
+			assert.Equal(t, source, ast.String())
+		}
+	}
+
+	// Unquoted, they're still keywords
+	tokens, err = Lex("SELECT from FROM select;")
+	assert.Nil(t, err)
+	_, err = Parse(tokens)
+	assert.NotNil(t, err)
+}
+
+// INDEX isn't reserved, so it may still name things
+func TestParse_indexNotReserved(t *testing.T) {
+	sources := []string{
+		"SELECT index FROM t WHERE index > 1;",
+		"CREATE TABLE t (index INT);",
+		"CREATE INDEX index ON index (index);",
+		"create unique Index i on t (a);",
+	}
+	for _, source := range sources {
+		tokens, err := Lex(source)
+		assert.Nil(t, err, source)
+		_, err = Parse(tokens)
+		assert.Nil(t, err, source)
+	}
+
+	tokens, err := Lex("CREATE INDEX index ON index (index);")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	if assert.Nil(t, err) {
+		crtIdx := ast.Statements[0].CreateIndexStatement
+		assert.Equal(t, "index", crtIdx.Name.Value)
+		assert.Equal(t, "index", crtIdx.Table.Value)
+		assert.Equal(t, "index", crtIdx.Cols[0].Value)
+		assert.Equal(t, "CREATE INDEX index ON index (index)", crtIdx.String())
+	}
+}
+
+func TestParse_selectExpressions(t *testing.T) {
+	tokens, err := Lex("SELECT (a + b) * 2, count(*), ((a)), (a - 1) / (b + 2) AS ratio, a = b OR c FROM t WHERE (a + b) * 2 > 10;")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		item string
+		// Empty for no alias
+		as string
+	}{
+		{item: "(* (+ a b) 2)"},
+		{item: "(count() *)"},
+		{item: "a"},
+		{item: "(/ (- a 1) (+ b 2))", as: "ratio"},
+		{item: "(or (= a b) c)"},
+	}
+
+	slct := ast.Statements[0].SelectStatement
+	assert.Equal(t, len(tests), len(slct.Items))
+	for i, test := range tests {
+		item := slct.Items[i]
+		assert.Equal(t, test.item, sexp(item.Expression))
+		if test.as == "" {
+			assert.Nil(t, item.As, test.item)
+		} else if assert.NotNil(t, item.As, test.item) {
+			assert.Equal(t, test.as, item.As.Value, test.item)
+		}
+	}
+
+	// Items use the same grammar as WHERE
+	assert.Equal(t, "(> (* (+ a b) 2) 10)", sexp(slct.Where))
+}
+
+func TestParseProgram(t *testing.T) {
+	tests := []struct {
+		source string
+		kinds  []AstKind
+	}{
+		{
+			source: "SELECT a FROM t; INSERT INTO t VALUES (1);",
+			kinds:  []AstKind{SelectKind, InsertKind},
+		},
+		{
+			source: "SELECT a FROM t; INSERT INTO t VALUES (1)",
+			kinds:  []AstKind{SelectKind, InsertKind},
+		},
+		{
+			source: "SELECT a FROM t;",
+			kinds:  []AstKind{SelectKind},
+		},
+		{
+			source: "SELECT a FROM t;; CREATE TABLE t (a INT)",
+			kinds:  []AstKind{SelectKind, CreateTableKind},
+		},
+		{
+			source: ";;",
+		},
+		{
+			source: "",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		statements, err := ParseProgram(tokens)
+		assert.Nil(t, err, test.source)
+		assert.Equal(t, len(test.kinds), len(statements), test.source)
+		for i, stmt := range statements {
+			assert.Equal(t, test.kinds[i], stmt.Kind, test.source)
+		}
+	}
+}
+
+func TestParseProgram_errors(t *testing.T) {
+	tests := []struct {
+		source    string
+		statement int
+		loc       Location
+	}{
+		{
+			source:    "SELECT FROM t;",
+			statement: 0,
+			loc:       Location{Line: 0, Col: 7, Offset: 7},
+		},
+		{
+			source:    "SELECT a FROM t;\nSELECT a,;",
+			statement: 1,
+			loc:       Location{Line: 1, Col: 9, Offset: 26},
+		},
+		{
+			source:    "SELECT a FROM t;;\n;INSERT t VALUES (1)",
+			statement: 1,
+			loc:       Location{Line: 1, Col: 8, Offset: 26},
+		},
+		{
+			source:    "SELECT a FROM t; SELECT b FROM u v w;",
+			statement: 1,
+			loc:       Location{Line: 0, Col: 35, Offset: 35},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		_, err = ParseProgram(tokens)
+		var parseErr *ParseError
+		if assert.True(t, errors.As(err, &parseErr), test.source) {
+			assert.Equal(t, test.statement, parseErr.Statement, test.source)
+			assert.Equal(t, test.loc, parseErr.Loc, test.source)
+		}
+	}
+}
+
+func TestParse_limitOffset(t *testing.T) {
+	tests := []struct {
+		source string
+		// Empty when the clause is missing
+		limit  string
+		offset string
+	}{
+		{
+			source: "SELECT * FROM t;",
+		},
+		{
+			source: "SELECT * FROM t LIMIT 10;",
+			limit:  "10",
+		},
+		{
+			source: "SELECT * FROM t WHERE a = 1 LIMIT 10 OFFSET 5;",
+			limit:  "10",
+			offset: "5",
+		},
+		{
+			source: "SELECT * FROM t OFFSET 5;",
+			offset: "5",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		assert.Nil(t, err, test.source)
+
+		slct := ast.Statements[0].SelectStatement
+		for _, clause := range []struct {
+			token    *Token
+			expected string
+		}{{slct.Limit, test.limit}, {slct.Offset, test.offset}} {
+			if clause.expected == "" {
+				assert.Nil(t, clause.token, test.source)
+			} else if assert.NotNil(t, clause.token, test.source) {
+				assert.Equal(t, clause.expected, clause.token.Value, test.source)
+			}
+		}
+	}
+}
+
+func TestParse_orderBy(t *testing.T) {
+	type term struct {
+		exp  string
+		desc bool
+	}
+	tests := []struct {
+		source string
+		terms  []term
+	}{
+		{
+			source: "SELECT * FROM t;",
+		},
+		{
+			source: "SELECT * FROM t ORDER BY a;",
+			terms:  []term{{exp: "a"}},
+		},
+		{
+			source: "SELECT * FROM t ORDER BY a DESC;",
+			terms:  []term{{exp: "a", desc: true}},
+		},
+		{
+			source: "SELECT * FROM t WHERE a = 1 ORDER BY a ASC, b DESC, c + 1 LIMIT 5;",
+			terms:  []term{{exp: "a"}, {exp: "b", desc: true}, {exp: "(+ c 1)"}},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		assert.Nil(t, err, test.source)
+
+		orderBy := ast.Statements[0].SelectStatement.OrderBy
+		assert.Equal(t, len(test.terms), len(orderBy), test.source)
+		for i, term := range orderBy {
+			assert.Equal(t, test.terms[i].exp, sexp(term.Expression), test.source)
+			assert.Equal(t, test.terms[i].desc, term.Desc, test.source)
+		}
+	}
+}
+
+// Render a table reference's tree, eg (left a b (= a.id b.id))
+func sexpTable(table *TableReference) string {
+	switch table.Kind {
+	case NamedTableKind:
+		if table.As != nil {
+			return table.Name.Value + ":" + table.As.Value
+		}
+		return table.Name.Value
+	case SubqueryTableKind:
+		tree := "(" + table.Subquery.String() + ")"
+		if table.Lateral {
+			tree = "lateral" + tree
+		}
+		if table.As != nil {
+			return tree + ":" + table.As.Value
+		}
+		return tree
+	case JoinTableKind:
+		kind := []string{"inner", "left", "right", "full", "cross", "comma"}[table.Join.Kind]
+		tree := fmt.Sprintf("(%s %s %s", kind, sexpTable(table.Join.Left), sexpTable(table.Join.Right))
+		if table.Join.On != nil {
+			tree += " " + sexp(table.Join.On)
+		}
+		return tree + ")"
+	}
+	return "?"
+}
+
+func TestParse_joins(t *testing.T) {
+	tests := []struct {
+		source string
+		from   string
+	}{
+		{
+			source: "SELECT * FROM users;",
+			from:   "users",
+		},
+		{
+			source: "SELECT * FROM users AS u;",
+			from:   "users:u",
+		},
+		{
+			source: "SELECT * FROM a JOIN b ON a.id = b.id;",
+			from:   "(inner a b (= a.id b.id))",
+		},
+		{
+			source: "SELECT * FROM a INNER JOIN b ON a.id = b.id;",
+			from:   "(inner a b (= a.id b.id))",
+		},
+		{
+			source: "SELECT u.name FROM users u LEFT JOIN scores s ON u.id = s.id WHERE s.score = 1;",
+			from:   "(left users:u scores:s (= u.id s.id))",
+		},
+		{
+			source: "SELECT * FROM a LEFT OUTER JOIN b ON a.id = b.id RIGHT JOIN c ON b.id = c.id;",
+			from:   "(right (left a b (= a.id b.id)) c (= b.id c.id))",
+		},
+		{
+			source: "SELECT * FROM a FULL JOIN b ON a.x = b.x AND a.y = b.y;",
+			from:   "(full a b (and (= a.x b.x) (= a.y b.y)))",
+		},
+		{
+			source: "SELECT * FROM a CROSS JOIN b;",
+			from:   "(cross a b)",
+		},
+		{
+			source: "SELECT * FROM a, b x, c;",
+			from:   "(comma (comma a b:x) c)",
+		},
+		{
+			source: "SELECT * FROM a, b JOIN c ON b.id = c.id;",
+			from:   "(comma a (inner b c (= b.id c.id)))",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		if assert.Nil(t, err, test.source) {
+			assert.Equal(t, test.from, sexpTable(ast.Statements[0].SelectStatement.From), test.source)
+		}
+	}
+}
+
+// Render a GROUP BY element's tree, eg (rollup a b)
+func sexpGrouping(element *GroupingElement) string {
+	list := func(exps []*Expression) string {
+		trees := []string{}
+		for _, exp := range exps {
+			trees = append(trees, sexp(exp))
+		}
+		return strings.Join(trees, " ")
+	}
+
+	switch element.Kind {
+	case ExpressionGroupingKind:
+		return sexp(element.Expression)
+	case RollupGroupingKind:
+		return "(rollup " + list(element.Expressions) + ")"
+	case CubeGroupingKind:
+		return "(cube " + list(element.Expressions) + ")"
+	case SetsGroupingKind:
+		tree := "(sets"
+		for _, set := range element.Sets {
+			tree += " (" + list(set) + ")"
+		}
+		return tree + ")"
+	}
+	return "?"
+}
+
+func TestParse_groupBy(t *testing.T) {
+	tests := []struct {
+		source  string
+		groupBy []string
+		// Empty without HAVING
+		having string
+	}{
+		{
+			source: "SELECT a FROM t;",
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY a;",
+			groupBy: []string{"a"},
+		},
+		{
+			source:  "SELECT a, b FROM t WHERE c = 1 GROUP BY a, b + 1 ORDER BY a;",
+			groupBy: []string{"a", "(+ b 1)"},
+		},
+		{
+			source:  "SELECT a, b, count(*) FROM t GROUP BY ROLLUP (a, b);",
+			groupBy: []string{"(rollup a b)"},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY c, CUBE (a, b + 1), ROLLUP (d);",
+			groupBy: []string{"c", "(cube a (+ b 1))", "(rollup d)"},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY GROUPING SETS ((a, b), (a), (), c, (a + b) * 2);",
+			groupBy: []string{"(sets (a b) (a) () (c) ((* (+ a b) 2)))"},
+		},
+		{
+			source:  "SELECT a FROM t GROUP BY a HAVING a > 1 AND a < 5;",
+			groupBy: []string{"a"},
+			having:  "(and (> a 1) (< a 5))",
+		},
+		// Some engines allow HAVING without GROUP BY, treating the whole
+		// table as one group
+		{
+			source: "SELECT a FROM t HAVING a > 1;",
+			having: "(> a 1)",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+
+		slct := ast.Statements[0].SelectStatement
+		assert.Equal(t, len(test.groupBy), len(slct.GroupBy), test.source)
+		for i, element := range slct.GroupBy {
+			assert.Equal(t, test.groupBy[i], sexpGrouping(element), test.source)
+		}
+		if test.having == "" {
+			assert.Nil(t, slct.Having, test.source)
+		} else if assert.NotNil(t, slct.Having, test.source) {
+			assert.Equal(t, test.having, sexp(slct.Having), test.source)
+		}
+	}
+}
+
+func TestParse_distinct(t *testing.T) {
+	tests := []struct {
+		source   string
+		distinct bool
+		items    int
+	}{
+		{
+			source:   "SELECT DISTINCT a, b FROM t;",
+			distinct: true,
+			items:    2,
+		},
+		{
+			source:   "select distinct * from t;",
+			distinct: true,
+			items:    1,
+		},
+		{
+			source: "SELECT a FROM t;",
+			items:  1,
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		if assert.Nil(t, err, test.source) {
+			slct := ast.Statements[0].SelectStatement
+			assert.Equal(t, test.distinct, slct.Distinct, test.source)
+			assert.Equal(t, test.items, len(slct.Items), test.source)
+		}
+	}
+}
+
+func TestParse_subqueries(t *testing.T) {
+	tests := []struct {
+		source string
+		item   string
+		from   string
+		where  string
+	}{
+		{
+			source: "SELECT * FROM (SELECT a FROM t) sub;",
+			item:   "*",
+			from:   "(SELECT a FROM t):sub",
+		},
+		{
+			source: "SELECT sub.a FROM (SELECT a FROM t WHERE a > 1) AS sub JOIN u ON sub.a = u.a;",
+			item:   "sub.a",
+			from:   "(inner (SELECT a FROM t WHERE a > 1):sub u (= sub.a u.a))",
+		},
+		{
+			source: "SELECT * FROM (SELECT * FROM (SELECT a FROM t) x) y;",
+			item:   "*",
+			from:   "(SELECT * FROM (SELECT a FROM t) AS x):y",
+		},
+		{
+			source: "SELECT * FROM a, LATERAL (SELECT * FROM t WHERE x = a.id) s;",
+			item:   "*",
+			from:   "(comma a lateral(SELECT * FROM t WHERE x = a.id):s)",
+		},
+		{
+			source: "SELECT * FROM a CROSS JOIN LATERAL (SELECT a.id) AS s;",
+			item:   "*",
+			from:   "(cross a lateral(SELECT a.id):s)",
+		},
+		{
+			source: "SELECT * FROM users WHERE id IN (SELECT id FROM t);",
+			item:   "*",
+			from:   "users",
+			where:  "(in id (SELECT id FROM t))",
+		},
+		{
+			source: "SELECT (SELECT b FROM u) FROM t WHERE a = 1 AND b IN (SELECT b FROM u);",
+			item:   "(SELECT b FROM u)",
+			from:   "t",
+			where:  "(and (= a 1) (in b (SELECT b FROM u)))",
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+
+		slct := ast.Statements[0].SelectStatement
+		item := "*"
+		if !slct.Items[0].Asterisk {
+			item = sexp(slct.Items[0].Expression)
+		}
+		assert.Equal(t, test.item, item, test.source)
+		assert.Equal(t, test.from, sexpTable(slct.From), test.source)
+		if test.where != "" {
+			assert.Equal(t, test.where, sexp(slct.Where), test.source)
+		}
+	}
+}
+
+func TestParse_columnConstraints(t *testing.T) {
+	tokens, err := Lex("CREATE TABLE users (id INT PRIMARY KEY NOT NULL, email TEXT NOT NULL UNIQUE, name TEXT, code INT UNIQUE);")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	expected := [][]ConstraintKind{
+		{PrimaryKeyConstraint, NotNullConstraint},
+		{NotNullConstraint, UniqueConstraint},
+		nil,
+		{UniqueConstraint},
+	}
+	cols := ast.Statements[0].CreateTableStatement.Cols
+	assert.Equal(t, len(expected), len(cols))
+	for i, col := range cols {
+		assert.Equal(t, expected[i], col.Constraints, col.Name.Value)
+	}
+}
+
+func TestParse_columnDefaults(t *testing.T) {
+	tokens, err := Lex("CREATE TABLE jobs (id INT DEFAULT 0, status TEXT NOT NULL DEFAULT 'pending', tries INT DEFAULT 1 + 2 UNIQUE, name TEXT);")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		// Empty without a default
+		def         string
+		constraints []ConstraintKind
+	}{
+		{def: "0"},
+		{def: "pending", constraints: []ConstraintKind{NotNullConstraint}},
+		{def: "(+ 1 2)", constraints: []ConstraintKind{UniqueConstraint}},
+		{},
+	}
+	cols := ast.Statements[0].CreateTableStatement.Cols
+	assert.Equal(t, len(tests), len(cols))
+	for i, col := range cols {
+		if tests[i].def == "" {
+			assert.Nil(t, col.Default, col.Name.Value)
+		} else if assert.NotNil(t, col.Default, col.Name.Value) {
+			assert.Equal(t, tests[i].def, sexp(col.Default), col.Name.Value)
+		}
+		assert.Equal(t, tests[i].constraints, col.Constraints, col.Name.Value)
+	}
+
+	def := cols[1].Default.Literal
+	assert.Equal(t, StringKind, def.Kind)
+	assert.Equal(t, NumericKind, cols[0].Default.Literal.Kind)
+}
+
+func TestParse_typeSizes(t *testing.T) {
+	tests := []struct {
+		source string
+		types  []string
+	}{
+		{
+			source: "CREATE TABLE t (name VARCHAR(255), code CHAR(2), price NUMERIC(10, 2), n NUMERIC(5), m NUMERIC, id INT);",
+			types:  []string{"VARCHAR(255)", "CHAR(2)", "NUMERIC(10, 2)", "NUMERIC(5)", "NUMERIC", "INT"},
+		},
+		{
+			source: "create table t (name varchar(255) not null default 'x');",
+			types:  []string{"VARCHAR(255)"},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		if !assert.Nil(t, err, test.source) {
+			continue
+		}
+
+		cols := ast.Statements[0].CreateTableStatement.Cols
+		assert.Equal(t, len(test.types), len(cols), test.source)
+		for i, col := range cols {
+			assert.Equal(t, test.types[i], col.Datatype.String(), test.source)
+		}
+	}
+
+	tokens, err := Lex("CREATE TABLE t (price NUMERIC(10,2));")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+	params := ast.Statements[0].CreateTableStatement.Cols[0].Datatype.Params
+	assert.Equal(t, 2, len(params))
+	assert.Equal(t, "10", params[0].Value)
+	assert.Equal(t, "2", params[1].Value)
+}
@@ -0,0 +1,135 @@
+package gosql
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// The JSON form of a MemoryBackend's tables. Cells are JSON numbers or
+// strings, as their column's type says, or null.
+type savedBackend struct {
+	Tables []savedTable `json:"tables"`
+}
+
+type savedTable struct {
+	Name    string              `json:"name"`
+	Columns []savedColumn       `json:"columns"`
+	Rows    [][]json.RawMessage `json:"rows"`
+}
+
+type savedColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// The column types a table can be saved with
+var savedColumnTypes = map[string]ColumnType{
+	TextType.String(): TextType,
+	IntType.String():  IntType,
+}
+
+// Save writes every table, with its columns and rows in order, to the
+// file at path as JSON, replacing the file if it exists
+func (mb *MemoryBackend) Save(path string) error {
+	names := make([]string, 0, len(mb.tables))
+	for name := range mb.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	saved := savedBackend{Tables: []savedTable{}}
+	for _, name := range names {
+		t := mb.tables[name]
+		st := savedTable{Name: name, Columns: []savedColumn{}, Rows: [][]json.RawMessage{}}
+		for i, col := range t.columns {
+			st.Columns = append(st.Columns, savedColumn{Name: col, Type: t.columnTypes[i].String()})
+		}
+
+		for _, row := range t.rows {
+			values := make([]json.RawMessage, 0, len(row))
+			for _, cell := range row {
+				var value interface{} = cell.text
+				switch {
+				case cell.null:
+					value = nil
+				case cell.typ == IntType:
+					value = cell.i
+				}
+				b, err := json.Marshal(value)
+				if err != nil {
+					return err
+				}
+				values = append(values, b)
+			}
+			st.Rows = append(st.Rows, values)
+		}
+		saved.Tables = append(saved.Tables, st)
+	}
+
+	b, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Load replaces every table with those saved to the file at path. The
+// backend is left as it was if the file can't be loaded.
+func (mb *MemoryBackend) Load(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var saved savedBackend
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return err
+	}
+
+	tables := map[string]*table{}
+	for _, st := range saved.Tables {
+		if _, ok := tables[st.Name]; ok {
+			return &ErrTableExists{Name: st.Name}
+		}
+
+		t := table{}
+		for _, col := range st.Columns {
+			typ, ok := savedColumnTypes[col.Type]
+			if !ok {
+				return fmt.Errorf("Unsupported type %s for column %s", col.Type, col.Name)
+			}
+			t.columns = append(t.columns, col.Name)
+			t.columnTypes = append(t.columnTypes, typ)
+		}
+
+		for _, values := range st.Rows {
+			if len(values) != len(t.columns) {
+				return fmt.Errorf("Expected %d values for table %s, got %d", len(t.columns), st.Name, len(values))
+			}
+
+			row := make([]memoryCell, 0, len(values))
+			for i, value := range values {
+				cell := memoryCell{typ: t.columnTypes[i]}
+				if string(value) == "null" {
+					row = append(row, nullCell(cell.typ))
+					continue
+				}
+				target := interface{}(&cell.text)
+				if cell.typ == IntType {
+					target = &cell.i
+				}
+				if err := json.Unmarshal(value, target); err != nil {
+					return fmt.Errorf("Invalid %s %s for column %s", cell.typ, value, t.columns[i])
+				}
+				row = append(row, cell)
+			}
+			t.rows = append(t.rows, row)
+		}
+		tables[st.Name] = &t
+	}
+
+	mb.tables = tables
+	return nil
+}
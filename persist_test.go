@@ -0,0 +1,82 @@
+package gosql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_SaveLoad(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustExecute(t, mb, `
+		CREATE TABLE users (id INT, name TEXT);
+		INSERT INTO users VALUES (2, '1'), (1, 'it''s'), ('-3', ''), (NULL, NULL);
+		CREATE TABLE empty (id INT);
+	`)
+
+	path := filepath.Join(t.TempDir(), "db.json")
+	assert.Nil(t, mb.Save(path))
+
+	loaded := NewMemoryBackend()
+	mustExecute(t, loaded, "CREATE TABLE dropped (id INT);")
+	assert.Nil(t, loaded.Load(path))
+	assert.Equal(t, mb.tables, loaded.tables)
+
+	for _, source := range []string{"SELECT * FROM users;", "SELECT * FROM empty;"} {
+		expected, err := mb.Select(mustParse(t, source).SelectStatement)
+		assert.Nil(t, err, source)
+		results, err := loaded.Select(mustParse(t, source).SelectStatement)
+		assert.Nil(t, err, source)
+		assert.Equal(t, expected, results, source)
+	}
+
+	_, err := loaded.Select(mustParse(t, "SELECT * FROM dropped;").SelectStatement)
+	assert.NotNil(t, err)
+}
+
+func TestMemoryBackend_Load_errors(t *testing.T) {
+	tests := []struct {
+		saved string
+		err   string
+	}{
+		{
+			saved: `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "boolean"}], "rows": []}]}`,
+			err:   "Unsupported type boolean for column a",
+		},
+		{
+			saved: `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "int"}], "rows": [[1, 2]]}]}`,
+			err:   "Expected 1 values for table t, got 2",
+		},
+		{
+			saved: `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "int"}], "rows": [["1"]]}]}`,
+			err:   `Invalid int "1" for column a`,
+		},
+		{
+			saved: `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "text"}], "rows": [[1]]}]}`,
+			err:   "Invalid text 1 for column a",
+		},
+		{
+			saved: `{"tables": [{"name": "t", "columns": []}, {"name": "t", "columns": []}]}`,
+			err:   "Table t already exists",
+		},
+	}
+
+	dir := t.TempDir()
+	for _, test := range tests {
+		path := filepath.Join(dir, "db.json")
+		assert.Nil(t, os.WriteFile(path, []byte(test.saved), 0644))
+
+		mb := NewMemoryBackend()
+		mustExecute(t, mb, "CREATE TABLE kept (id INT);")
+		err := mb.Load(path)
+		if assert.NotNil(t, err, test.saved) {
+			assert.Equal(t, test.err, err.Error(), test.saved)
+		}
+		// Nothing is replaced
+		assert.NotNil(t, mb.tables["kept"], test.saved)
+	}
+
+	assert.NotNil(t, NewMemoryBackend().Load(filepath.Join(dir, "missing.json")))
+}
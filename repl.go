@@ -0,0 +1,83 @@
+package gosql
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Repl reads SQL from in a line at a time, runs it against a new
+// MemoryBackend and writes the results, or the error and where it
+// happened, to out. Each line holds one or more statements, and the
+// semicolon after the last is optional. It returns once in is exhausted.
+func Repl(in io.Reader, out io.Writer) {
+	mb := NewMemoryBackend()
+	scanner := bufio.NewScanner(in)
+
+	for {
+		fmt.Fprint(out, "# ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if err := mb.run(line, out); err != nil {
+			fmt.Fprintln(out, "Error:", err)
+		}
+	}
+}
+
+// Run each statement of source, writing what they produce to out and
+// stopping at the first error
+func (mb *MemoryBackend) run(source string, out io.Writer) error {
+	tokens, err := Lex(source)
+	if err != nil {
+		return err
+	}
+
+	statements, err := ParseProgram(tokens)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range statements {
+		switch stmt.Kind {
+		case CreateTableKind:
+			err = mb.CreateTable(stmt.CreateTableStatement)
+			if err == nil {
+				fmt.Fprintln(out, "ok")
+			}
+		case CreateViewKind:
+			err = mb.CreateView(stmt.CreateViewStatement)
+			if err == nil {
+				fmt.Fprintln(out, "ok")
+			}
+		case InsertKind:
+			err = mb.Insert(stmt.InsertStatement)
+			if err == nil {
+				fmt.Fprintln(out, "ok")
+			}
+		case SelectKind:
+			var results *Results
+			results, err = mb.Select(stmt.SelectStatement)
+			if err == nil {
+				err = results.Render(out)
+			}
+			if err == nil {
+				fmt.Fprintf(out, "(%d rows)\n", len(results.Rows))
+			}
+		default:
+			err = fmt.Errorf("%s statements are not supported", stmt.Kind)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package gosql
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepl(t *testing.T) {
+	in := strings.NewReader(`CREATE TABLE users (id INT, name TEXT);
+
+INSERT INTO users VALUES (1, 'George'); INSERT INTO users VALUES (2, 'Ringo')
+   
+SELECT name, id FROM users WHERE id > 1
+SELECT * FROM nope;
+SELECT 1 +;
+INSERT INTO users VALUES (3);
+SELECT id FROM users`)
+	var out bytes.Buffer
+	Repl(in, &out)
+
+	assert.Equal(t, `# ok
+# # ok
+ok
+# # +-------+----+
+| name  | id |
++-------+----+
+| Ringo |  2 |
++-------+----+
+(1 rows)
+# Error: Table nope does not exist
+# Error: Expected expression, got ; at 0:10
+# Error: Expected 2 values for table users, got 1
+# +----+
+| id |
++----+
+|  1 |
+|  2 |
++----+
+(2 rows)
+# 
+`, out.String())
+}
+
+func TestRepl_unsupported(t *testing.T) {
+	in := strings.NewReader(`CREATE TABLE t (a INT); INSERT INTO t VALUES (1);
+UPDATE t SET a = 2;
+DELETE FROM t;
+CREATE INDEX t_a ON t (a);
+CREATE VIEW v AS SELECT a FROM t;
+SELECT a FROM v`)
+	var out bytes.Buffer
+	Repl(in, &out)
+
+	assert.Equal(t, `# ok
+ok
+# Error: UPDATE statements are not supported
+# Error: DELETE statements are not supported
+# Error: CREATE INDEX statements are not supported
+# ok
+# +---+
+| a |
++---+
+| 1 |
++---+
+(1 rows)
+# 
+`, out.String())
+}
+
+var errWrite = errors.New("write failed")
+
+// Fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestMemoryBackend_run_renderError(t *testing.T) {
+	mb := NewMemoryBackend()
+	assert.Equal(t, errWrite, mb.run("SELECT 1;", failingWriter{}))
+}
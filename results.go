@@ -0,0 +1,105 @@
+package gosql
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+type ResultColumn struct {
+	Name string
+	Type ColumnType
+}
+
+// Results are the columns and rows a query produced. Columns are kept in
+// the order they were selected, even if several share a name.
+type Results struct {
+	Columns []ResultColumn
+	Rows    [][]Cell
+}
+
+// ColumnIndex returns the position of the first column called name
+func (r *Results) ColumnIndex(name string) (int, bool) {
+	for i, col := range r.Columns {
+		if col.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (r *Results) String() string {
+	var b strings.Builder
+	r.Render(&b)
+	return b.String()
+}
+
+// Render writes the results to w as an ASCII table with a header row.
+// Ints are aligned right, everything else left, and NULL cells are
+// written as NULL.
+//
+//	+----+--------+
+//	| id | name   |
+//	+----+--------+
+//	|  1 | George |
+//	+----+--------+
+func (r *Results) Render(w io.Writer) error {
+	rows := make([][]string, 0, len(r.Rows)+1)
+	header := make([]string, 0, len(r.Columns))
+	for _, col := range r.Columns {
+		header = append(header, col.Name)
+	}
+	rows = append(rows, header)
+	for _, row := range r.Rows {
+		cells := make([]string, 0, len(row))
+		for _, cell := range row {
+			if cell == nil || cell.IsNull() {
+				cells = append(cells, "NULL")
+				continue
+			}
+			cells = append(cells, cell.AsText())
+		}
+		rows = append(rows, cells)
+	}
+
+	widths := make([]int, len(r.Columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			if width := utf8.RuneCountInString(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	var b strings.Builder
+	separator := func() {
+		b.WriteString("+")
+		for _, width := range widths {
+			b.WriteString(strings.Repeat("-", width+2) + "+")
+		}
+		b.WriteString("\n")
+	}
+
+	separator()
+	for i, row := range rows {
+		b.WriteString("|")
+		for j, cell := range row {
+			padding := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+			if i > 0 && r.Columns[j].Type == IntType {
+				cell = padding + cell
+			} else {
+				cell += padding
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+
+		if i == 0 {
+			separator()
+		}
+	}
+	separator()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,39 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResults_String(t *testing.T) {
+	results := &Results{
+		Columns: []ResultColumn{{"id", IntType}, {"name", TextType}, {"?column?", BoolType}},
+		Rows: [][]Cell{
+			{intCell(1), textCell("George"), boolCell(true)},
+			{intCell(100), textCell("Zoë"), nil},
+			{nil, textCell(""), boolCell(false)},
+		},
+	}
+
+	assert.Equal(t, `+------+--------+----------+
+| id   | name   | ?column? |
++------+--------+----------+
+|    1 | George | true     |
+|  100 | Zoë    | NULL     |
+| NULL |        | false    |
++------+--------+----------+
+`, results.String())
+}
+
+func TestResults_String_empty(t *testing.T) {
+	results := &Results{
+		Columns: []ResultColumn{{"id", IntType}},
+	}
+
+	assert.Equal(t, `+----+
+| id |
++----+
++----+
+`, results.String())
+}
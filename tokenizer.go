@@ -0,0 +1,332 @@
+package gosql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// The minimum number of bytes read from an io.Reader at once
+const readSize = 4096
+
+// A Tokenizer lexes a source lazily, one token per call to Next. This
+// lets a caller stop consuming (eg at the first semicolon) without
+// lexing the rest of the source.
+type Tokenizer struct {
+	// The source not yet consumed. When reading from an io.Reader this is
+	// only the buffered part of it.
+	source string
+	cur    Cursor
+	opts   LexOptions
+	lexers []lexer
+	// The last token returned other than whitespace or a comment, nil
+	// before the first
+	last *Token
+	// Set once the end of the source has been reported
+	done bool
+	// Comments waiting for the next token to attach to
+	comments []string
+	// The token last returned by Next, nil once backed up
+	returned *Token
+	// A token backed up, for Next to return again
+	backedUp *Token
+	// A token lexed by Peek, for Next to return first
+	peeked *Token
+
+	// Nil when lexing a string
+	reader io.Reader
+	// Set once the reader is exhausted
+	readerDone bool
+}
+
+func NewTokenizer(source string, opts LexOptions) *Tokenizer {
+	return &Tokenizer{
+		source: source,
+		opts:   opts,
+		lexers: opts.lexers(),
+	}
+}
+
+// NewReaderTokenizer returns a Tokenizer that reads its source from r as
+// it goes, rather than requiring the whole source up front
+func NewReaderTokenizer(r io.Reader, opts LexOptions) *Tokenizer {
+	t := NewTokenizer("", opts)
+	t.reader = r
+	return t
+}
+
+// Next returns the next token in the source. Once the source is
+// exhausted (and the EOFKind token, if enabled, has been returned) it
+// returns io.EOF. Errors reading the source are returned as is.
+func (t *Tokenizer) Next() (*Token, error) {
+	if t.backedUp != nil {
+		t.returned, t.backedUp = t.backedUp, nil
+		return t.returned, nil
+	}
+
+	token := t.peeked
+	t.peeked = nil
+	if token == nil {
+		var err error
+		token, err = t.next()
+		if err != nil {
+			return nil, err
+		}
+	}
+	t.returned = token
+	return token, nil
+}
+
+// Peek returns the token Next would return, without consuming it. It
+// doesn't change which token Backup backs up.
+func (t *Tokenizer) Peek() (*Token, error) {
+	if t.backedUp != nil {
+		return t.backedUp, nil
+	}
+	if t.peeked == nil {
+		token, err := t.next()
+		if err != nil {
+			return nil, err
+		}
+		t.peeked = token
+	}
+	return t.peeked, nil
+}
+
+// Backup pushes the token last returned by Next back, so the next call
+// returns it again. Only one token can be backed up: Backup panics if
+// Next hasn't returned a token since the last Backup.
+func (t *Tokenizer) Backup() {
+	if t.returned == nil {
+		panic("gosql: Backup without a token to back up")
+	}
+	t.backedUp, t.returned = t.returned, nil
+}
+
+func (t *Tokenizer) next() (*Token, error) {
+	for {
+		for t.cur.Pointer >= uint(len(t.source)) && t.reader != nil && !t.readerDone {
+			if err := t.read(); err != nil {
+				return nil, err
+			}
+		}
+		if t.cur.Pointer >= uint(len(t.source)) {
+			break
+		}
+
+		source, limited := t.window()
+		token, newCursor, ok := t.lexToken(source)
+		// A token may only be unterminated because the window cut it off
+		if ok && limited && token != nil && token.invalid != "" && newCursor.Pointer >= uint(len(source)) {
+			ok = false
+		}
+		// A lexer that failed, or that stopped close to the end of what has
+		// been read so far, may just not have seen enough of the source
+		// (eg half of a string or a multi-byte rune). Read more and retry.
+		if t.reader != nil && !t.readerDone && !limited && (!ok || newCursor.Pointer+utf8.UTFMax > uint(len(t.source))) {
+			if err := t.read(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !ok {
+			message := "Unable to lex token"
+			if r, size := utf8.DecodeRuneInString(t.source[t.cur.Pointer:]); r == utf8.RuneError && size == 1 {
+				message = "Invalid UTF-8 encoding"
+			} else if limited {
+				message = fmt.Sprintf("Unable to lex token within maximum length of %d bytes", t.opts.MaxTokenLength)
+			}
+			if t.last != nil {
+				message += " after " + t.last.Value
+			}
+			return nil, &LexError{Loc: t.cur.Loc, Message: message}
+		}
+
+		if token != nil && token.invalid != "" {
+			return nil, &LexError{Loc: token.Loc, Message: token.invalid}
+		}
+
+		if max := t.opts.MaxTokenLength; max > 0 && newCursor.Pointer-t.cur.Pointer > max && !isWhitespace(t.source[t.cur.Pointer]) {
+			return nil, &LexError{
+				Loc:     t.cur.Loc,
+				Message: fmt.Sprintf("Token exceeds maximum length of %d bytes", max),
+			}
+		}
+
+		t.cur = newCursor
+		// Skip nil tokens for valid, but empty syntax like newlines
+		if token != nil {
+			if token.Kind == CommentKind && t.opts.AttachComments {
+				t.comments = append(t.comments, token.Value)
+				continue
+			}
+			if token.Kind != WhitespaceKind && token.Kind != CommentKind {
+				t.last = token
+				token.LeadingComments = t.takeComments()
+			}
+			return token, nil
+		}
+	}
+
+	if t.done {
+		return nil, io.EOF
+	}
+	t.done = true
+	if t.opts.EmitEOF {
+		return &Token{
+			Kind:            EOFKind,
+			Loc:             t.cur.Loc,
+			EndLoc:          t.cur.Loc,
+			LeadingComments: t.takeComments(),
+		}, nil
+	}
+	return nil, io.EOF
+}
+
+// Return and clear the comments waiting to be attached
+func (t *Tokenizer) takeComments() []string {
+	comments := t.comments
+	t.comments = nil
+	return comments
+}
+
+// TrailingComments returns the comments after the last token, once Next
+// has returned io.EOF, when LexOptions.AttachComments is set and there
+// was no EOFKind token to attach them to
+func (t *Tokenizer) TrailingComments() []string {
+	if !t.done {
+		return nil
+	}
+	return t.comments
+}
+
+// The part of the source the lexers may look at from the cursor. With a
+// MaxTokenLength that is only enough for the longest token allowed plus a
+// rune of lookahead, so lexing a huge unterminated string gives up early.
+// limited is set when the source was cut short. Whitespace isn't a token
+// and is never cut short.
+func (t *Tokenizer) window() (source string, limited bool) {
+	max := t.opts.MaxTokenLength
+	if max == 0 || isWhitespace(t.source[t.cur.Pointer]) {
+		return t.source, false
+	}
+
+	end := t.cur.Pointer + max + utf8.UTFMax
+	if end >= uint(len(t.source)) {
+		return t.source, false
+	}
+	return t.source[:end], true
+}
+
+// Try each lexer at the cursor, returning the first token lexed
+func (t *Tokenizer) lexToken(source string) (*Token, Cursor, bool) {
+	if t.opts.AllowSignedNumerics && !followsOperand(t.last) {
+		if token, newCursor, ok := lexSignedNumeric(source, t.cur); ok {
+			token.Raw = source[t.cur.Pointer:newCursor.Pointer]
+			return token, newCursor, true
+		}
+	}
+
+	for _, l := range t.lexers {
+		if token, newCursor, ok := l(source, t.cur); ok {
+			// Skipped whitespace has no token
+			if token != nil {
+				token.Raw = source[t.cur.Pointer:newCursor.Pointer]
+			}
+			return token, newCursor, true
+		}
+	}
+	return nil, t.cur, false
+}
+
+// Read more of the source from the reader, dropping what has already
+// been consumed. Locations are unaffected since the cursor carries them.
+func (t *Tokenizer) read() error {
+	rest := t.source[t.cur.Pointer:]
+	// Read at least as much as is buffered so retrying a long token
+	// doesn't copy the buffer over and over
+	size := readSize
+	if len(rest) > size {
+		size = len(rest)
+	}
+
+	buf := make([]byte, size)
+	n, err := t.reader.Read(buf)
+	t.source = rest + string(buf[:n])
+	t.cur.Pointer = 0
+
+	if err == io.EOF {
+		t.readerDone = true
+		return nil
+	}
+	return err
+}
+
+// Consume the next rune (or invalid byte) of the source as an IllegalKind
+// token so lexing can resume after an error
+func (t *Tokenizer) skipIllegal() *Token {
+	ic := t.cur
+	_, size := utf8.DecodeRuneInString(t.source[t.cur.Pointer:])
+	t.cur.Pointer += uint(size)
+	t.cur.Loc.Col++
+	t.cur.Loc.Offset += uint(size)
+
+	token := &Token{
+		Value:  t.source[ic.Pointer:t.cur.Pointer],
+		Raw:    t.source[ic.Pointer:t.cur.Pointer],
+		Kind:   IllegalKind,
+		Loc:    ic.Loc,
+		EndLoc: t.cur.Loc,
+	}
+	t.last = token
+	return token
+}
+
+// The lexers SplitSQL uses, those of the default options
+var splitLexers = LexOptions{}.lexers()
+
+// SplitSQL is a bufio.SplitFunc that splits SQL into tokens, so a
+// bufio.Scanner can lex any reader. Each scan returns the source bytes
+// of one token, as Lex with the default options would lex it, with
+// whitespace and comments skipped. The scanner doesn't track locations,
+// so errors only quote the source that couldn't be lexed.
+func SplitSQL(data []byte, atEOF bool) (int, []byte, error) {
+	source := string(data)
+	cur := Cursor{}
+	for cur.Pointer < uint(len(source)) {
+		var token *Token
+		newCursor, ok := cur, false
+		for _, l := range splitLexers {
+			if token, newCursor, ok = l(source, cur); ok {
+				break
+			}
+		}
+
+		// The token may continue past what has been read so far, as in
+		// half of a string, so ask for more before deciding
+		if !atEOF && (!ok || newCursor.Pointer+utf8.UTFMax > uint(len(source))) {
+			return int(cur.Pointer), nil, nil
+		}
+
+		if !ok {
+			rest := source[cur.Pointer:]
+			if len(rest) > 10 {
+				rest = rest[:10] + "..."
+			}
+			return 0, nil, fmt.Errorf("Unable to lex token at %q", rest)
+		}
+
+		if token != nil && token.invalid != "" {
+			return 0, nil, errors.New(token.invalid)
+		}
+		// Whitespace and discarded comments have no token
+		if token != nil {
+			return int(newCursor.Pointer), data[cur.Pointer:newCursor.Pointer], nil
+		}
+		cur = newCursor
+	}
+
+	return int(cur.Pointer), nil, nil
+}
@@ -0,0 +1,372 @@
+package gosql
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizer_Next(t *testing.T) {
+	tests := []struct {
+		input   string
+		options LexOptions
+	}{
+		{
+			input: "SELECT id, name FROM users;",
+		},
+		{
+			input: "insert into users values (105, 'a b');\nselect * from users",
+		},
+		{
+			input:   "select +1 + 2",
+			options: LexOptions{AllowSignedNumerics: true},
+		},
+		{
+			input:   "select a",
+			options: LexOptions{EmitEOF: true},
+		},
+		{
+			input: "",
+		},
+	}
+
+	for _, test := range tests {
+		expected, err := LexWithOptions(test.input, test.options)
+		assert.Nil(t, err, test.input)
+
+		tokenizer := NewTokenizer(test.input, test.options)
+		var tokens []*Token
+		for {
+			tok, err := tokenizer.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(t, err, test.input)
+			tokens = append(tokens, tok)
+		}
+		assert.Equal(t, len(expected), len(tokens), test.input)
+		for i, tok := range tokens {
+			assert.Equal(t, expected[i], tok, test.input)
+		}
+
+		// Exhausted tokenizers keep returning io.EOF
+		tok, err := tokenizer.Next()
+		assert.Nil(t, tok, test.input)
+		assert.Equal(t, io.EOF, err, test.input)
+	}
+}
+
+func TestTokenizer_NextStopsEarly(t *testing.T) {
+	// The bad character after the first statement is never reached
+	tokenizer := NewTokenizer("select a; select ^", LexOptions{})
+	var tokens []*Token
+	for {
+		tok, err := tokenizer.Next()
+		assert.Nil(t, err)
+		tokens = append(tokens, tok)
+		if tok.Value == string(SemicolonSymbol) {
+			break
+		}
+	}
+	assert.Equal(t, 3, len(tokens))
+
+	// Errors only surface once the tokenizer reaches them
+	tokenizer = NewTokenizer("select ^", LexOptions{})
+	tok, err := tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, string(SelectKeyword), tok.Value)
+	_, err = tokenizer.Next()
+	assert.NotNil(t, err)
+}
+
+func TestTokenizer_Peek(t *testing.T) {
+	tokenizer := NewTokenizer("select a", LexOptions{})
+
+	peeked, err := tokenizer.Peek()
+	assert.Nil(t, err)
+	assert.Equal(t, string(SelectKeyword), peeked.Value)
+	// Peeking again doesn't move on
+	again, err := tokenizer.Peek()
+	assert.Nil(t, err)
+	assert.Same(t, peeked, again)
+
+	tok, err := tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Same(t, peeked, tok)
+
+	tok, err = tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "a", tok.Value)
+
+	_, err = tokenizer.Peek()
+	assert.Equal(t, io.EOF, err)
+	_, err = tokenizer.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTokenizer_PeekThenBackup(t *testing.T) {
+	tokenizer := NewTokenizer("select a, b", LexOptions{})
+
+	first, err := tokenizer.Next()
+	assert.Nil(t, err)
+	peeked, err := tokenizer.Peek()
+	assert.Nil(t, err)
+	assert.Equal(t, "a", peeked.Value)
+
+	// Peeking doesn't replace the token to back up
+	tokenizer.Backup()
+	peekedAgain, err := tokenizer.Peek()
+	assert.Nil(t, err)
+	assert.Same(t, first, peekedAgain)
+
+	tok, err := tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Same(t, first, tok)
+	tok, err = tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Same(t, peeked, tok)
+
+	tokenizer.Backup()
+	tok, err = tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Same(t, peeked, tok)
+	tok, err = tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, string(CommaSymbol), tok.Value)
+}
+
+func TestTokenizer_Backup(t *testing.T) {
+	tokenizer := NewTokenizer("select a, b", LexOptions{})
+	assert.Panics(t, tokenizer.Backup)
+
+	first, err := tokenizer.Next()
+	assert.Nil(t, err)
+	second, err := tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "a", second.Value)
+
+	tokenizer.Backup()
+	// Only one token can be backed up
+	assert.Panics(t, tokenizer.Backup)
+
+	tok, err := tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Same(t, second, tok)
+	assert.NotSame(t, first, tok)
+
+	tok, err = tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, string(CommaSymbol), tok.Value)
+
+	// Backing up the last token works at the end of the source too
+	tok, err = tokenizer.Next()
+	assert.Nil(t, err)
+	_, err = tokenizer.Next()
+	assert.Equal(t, io.EOF, err)
+	tokenizer.Backup()
+	again, err := tokenizer.Next()
+	assert.Nil(t, err)
+	assert.Same(t, tok, again)
+}
+
+func TestTokenizer_TrailingComments(t *testing.T) {
+	tokenizer := NewTokenizer("select a /* x */ -- y", LexOptions{AttachComments: true})
+	var tokens []*Token
+	for {
+		tok, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		// Not known to be trailing until the end is reached
+		assert.Nil(t, tokenizer.TrailingComments())
+		tokens = append(tokens, tok)
+	}
+	assert.Equal(t, 2, len(tokens))
+	assert.Equal(t, []string{"/* x */", "-- y"}, tokenizer.TrailingComments())
+
+	// Attached to the EOF token instead
+	tokenizer = NewTokenizer("select a -- y", LexOptions{AttachComments: true, EmitEOF: true})
+	for {
+		tok, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		if tok.Kind == EOFKind {
+			assert.Equal(t, []string{"-- y"}, tok.LeadingComments)
+		}
+	}
+	assert.Nil(t, tokenizer.TrailingComments())
+}
+
+func TestTokenizer_EOF(t *testing.T) {
+	tests := []struct {
+		input string
+		end   Location
+	}{
+		{input: "", end: Location{Line: 0, Col: 0, Offset: 0}},
+		{input: "select a;", end: Location{Line: 0, Col: 9, Offset: 9}},
+		{input: "select a\n\t", end: Location{Line: 1, Col: 1, Offset: 10}},
+		{input: "a\r\nb", end: Location{Line: 1, Col: 1, Offset: 4}},
+		// Columns count runes, offsets count bytes
+		{input: "select 'é'", end: Location{Line: 0, Col: 10, Offset: 11}},
+		{input: "a -- c", end: Location{Line: 0, Col: 6, Offset: 6}},
+		{input: "a /* x\ny */", end: Location{Line: 1, Col: 4, Offset: 11}},
+	}
+
+	for _, test := range tests {
+		tokenizers := []*Tokenizer{
+			NewTokenizer(test.input, LexOptions{EmitEOF: true}),
+			NewReaderTokenizer(iotest.OneByteReader(strings.NewReader(test.input)), LexOptions{EmitEOF: true}),
+		}
+		for _, tokenizer := range tokenizers {
+			var last *Token
+			for {
+				tok, err := tokenizer.Next()
+				if err == io.EOF || !assert.Nil(t, err, test.input) {
+					break
+				}
+				last = tok
+			}
+			if assert.NotNil(t, last, test.input) {
+				assert.Equal(t, EOFKind, last.Kind, test.input)
+				assert.Equal(t, test.end, last.Loc, test.input)
+				assert.Equal(t, test.end, last.EndLoc, test.input)
+			}
+		}
+
+		// Off by default
+		tokens, err := Lex(test.input)
+		assert.Nil(t, err, test.input)
+		for _, tok := range tokens {
+			assert.NotEqual(t, EOFKind, tok.Kind, test.input)
+		}
+	}
+}
+
+func TestLexReader(t *testing.T) {
+	tests := []string{
+		"SELECT id, name FROM users;",
+		"insert into users values (105, 'it''s', 1.5e+3);\r\nselect *\r\n\tfrom users",
+		"select café, 用户名 from \"Users\"\rwhere a = 'multi\nline' || 'x'",
+		"grouping groupings rollup",
+		"",
+		strings.Repeat("select a, 'b' from c;\n", 1000),
+	}
+
+	for _, test := range tests {
+		expected, err := Lex(test)
+		assert.Nil(t, err, test)
+
+		tokens, err := LexReader(strings.NewReader(test))
+		assert.Nil(t, err, test)
+		assert.Equal(t, expected, tokens, test)
+
+		// Force a buffer boundary between every byte
+		tokens, err = LexReader(iotest.OneByteReader(strings.NewReader(test)))
+		assert.Nil(t, err, test)
+		assert.Equal(t, expected, tokens, test)
+	}
+}
+
+func TestLexReader_errors(t *testing.T) {
+	_, err := LexReader(iotest.OneByteReader(strings.NewReader("select a,\n  b ^ c")))
+	var lexErr *LexError
+	assert.True(t, errors.As(err, &lexErr))
+	assert.Equal(t, Location{Line: 1, Col: 4, Offset: 14}, lexErr.Loc)
+
+	_, err = LexReader(iotest.OneByteReader(strings.NewReader("select 'unterminated")))
+	assert.True(t, errors.As(err, &lexErr))
+	assert.Equal(t, Location{Line: 0, Col: 7, Offset: 7}, lexErr.Loc)
+
+	// Read errors are passed through
+	_, err = LexReader(iotest.TimeoutReader(strings.NewReader(strings.Repeat("select a ", 1000))))
+	assert.Equal(t, iotest.ErrTimeout, err)
+}
+
+func TestSplitSQL(t *testing.T) {
+	source := `CREATE TABLE users (id INT, "Name" TEXT);
+-- a comment
+INSERT INTO users VALUES (1, 'it''s a
+multi-line string');
+SELECT id, "Name" /* inline */ FROM users WHERE id >= 1.5e3 OR "Name" <> 'x';`
+
+	expected, err := Lex(source)
+	assert.Nil(t, err)
+
+	// A tiny buffer makes tokens span several reads
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(source)))
+	scanner.Buffer(make([]byte, 4), 1024)
+	scanner.Split(SplitSQL)
+
+	i := 0
+	for scanner.Scan() {
+		// Each scanned token lexes back to the token Lex found there
+		tokens, err := Lex(scanner.Text())
+		if assert.Nil(t, err, scanner.Text()) && assert.Equal(t, 1, len(tokens), scanner.Text()) && assert.Less(t, i, len(expected)) {
+			assert.Equal(t, expected[i].Value, tokens[0].Value)
+			assert.Equal(t, expected[i].Kind, tokens[0].Kind)
+		}
+		i++
+	}
+	assert.Nil(t, scanner.Err())
+	assert.Equal(t, len(expected), i)
+}
+
+func TestSplitSQL_commentsAtEOF(t *testing.T) {
+	for _, source := range []string{"SELECT 1; -- trailing note", "SELECT 1; /* note */", "SELECT 1;--"} {
+		scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(source)))
+		scanner.Split(SplitSQL)
+
+		tokens := []string{}
+		for scanner.Scan() {
+			tokens = append(tokens, scanner.Text())
+		}
+		assert.Nil(t, scanner.Err(), source)
+		assert.Equal(t, []string{"SELECT", "1", ";"}, tokens, source)
+	}
+}
+
+func TestSplitSQL_errors(t *testing.T) {
+	tests := []struct {
+		source string
+		tokens []string
+		err    string
+	}{
+		{
+			source: "select a ^ b",
+			tokens: []string{"select", "a"},
+			err:    `Unable to lex token at "^ b"`,
+		},
+		{
+			source: "select 'unterminated string here",
+			tokens: []string{"select"},
+			err:    "Unterminated string",
+		},
+		{
+			source: "select X'1G'",
+			tokens: []string{"select"},
+			err:    "Invalid hex digit 'G' in binary string",
+		},
+	}
+
+	for _, test := range tests {
+		scanner := bufio.NewScanner(strings.NewReader(test.source))
+		scanner.Split(SplitSQL)
+
+		tokens := []string{}
+		for scanner.Scan() {
+			tokens = append(tokens, scanner.Text())
+		}
+		assert.Equal(t, test.tokens, tokens, test.source)
+		if assert.NotNil(t, scanner.Err(), test.source) {
+			assert.Equal(t, test.err, scanner.Err().Error(), test.source)
+		}
+	}
+}
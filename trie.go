@@ -0,0 +1,50 @@
+package gosql
+
+// A prefix trie over a fixed set of options (keywords or symbols), built
+// once so lexers can find the longest option at a position in a single
+// pass over the source.
+type trie struct {
+	children map[byte]*trie
+	// The option ending at this node, empty if none does
+	option string
+}
+
+func newTrie(options []string) *trie {
+	root := &trie{children: map[byte]*trie{}}
+	for _, option := range options {
+		node := root
+		for i := 0; i < len(option); i++ {
+			child, ok := node.children[option[i]]
+			if !ok {
+				child = &trie{children: map[byte]*trie{}}
+				node.children[option[i]] = child
+			}
+			node = child
+		}
+		node.option = option
+	}
+	return root
+}
+
+// Walk the source starting at the given cursor to find the longest option
+// it begins with (empty if none). ASCII letters in the source match
+// case-insensitively, since options are lowercase.
+func (t *trie) longestMatch(source string, ic Cursor) string {
+	var match string
+	node := t
+	for pointer := ic.Pointer; pointer < uint(len(source)); pointer++ {
+		c := source[pointer]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+
+		node = node.children[c]
+		if node == nil {
+			break
+		}
+		if node.option != "" {
+			match = node.option
+		}
+	}
+	return match
+}
@@ -0,0 +1,60 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrie_longestMatch(t *testing.T) {
+	trie := newTrie([]string{"in", "int", "into", "|", "||"})
+	tests := []struct {
+		source string
+		match  string
+	}{
+		{
+			source: "in",
+			match:  "in",
+		},
+		{
+			source: "int",
+			match:  "int",
+		},
+		{
+			source: "INTO x",
+			match:  "into",
+		},
+		{
+			source: "intx",
+			match:  "int",
+		},
+		{
+			source: "||",
+			match:  "||",
+		},
+		{
+			source: "|a",
+			match:  "|",
+		},
+		// no match
+		{
+			source: "i",
+			match:  "",
+		},
+		{
+			source: "",
+			match:  "",
+		},
+		{
+			source: " in",
+			match:  "",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, trie.longestMatch(test.source, Cursor{}), test.source)
+	}
+
+	// Matching starts at the cursor
+	assert.Equal(t, "into", trie.longestMatch("x into", Cursor{Pointer: 2}))
+}
@@ -0,0 +1,328 @@
+package gosql
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Return SkipChildren from a Visitor method to skip the children of the
+// node being visited, or StopWalk to end the walk early. Walk returns nil
+// for either.
+var (
+	SkipChildren = errors.New("skip children")
+	StopWalk     = errors.New("stop walk")
+)
+
+// A Visitor is called by Walk for each node of an AST, parents before
+// their children. Embed BaseVisitor to only implement the methods needed.
+type Visitor interface {
+	VisitSelectStatement(s *SelectStatement) error
+	VisitCreateTableStatement(s *CreateTableStatement) error
+	VisitInsertStatement(s *InsertStatement) error
+	VisitUpdateStatement(s *UpdateStatement) error
+	VisitDeleteStatement(s *DeleteStatement) error
+	VisitCreateIndexStatement(s *CreateIndexStatement) error
+	VisitCreateViewStatement(s *CreateViewStatement) error
+	VisitSelectItem(item *SelectItem) error
+	VisitColumnDefinition(col *ColumnDefinition) error
+	VisitAssignment(a *Assignment) error
+	VisitExpression(e *Expression) error
+	// The name of a table a statement reads or writes
+	VisitTable(table *Token) error
+	// The name of a column an index is on
+	VisitIndexColumn(col *Token) error
+}
+
+// BaseVisitor visits every node without doing anything
+type BaseVisitor struct{}
+
+func (BaseVisitor) VisitSelectStatement(*SelectStatement) error           { return nil }
+func (BaseVisitor) VisitCreateTableStatement(*CreateTableStatement) error { return nil }
+func (BaseVisitor) VisitInsertStatement(*InsertStatement) error           { return nil }
+func (BaseVisitor) VisitUpdateStatement(*UpdateStatement) error           { return nil }
+func (BaseVisitor) VisitDeleteStatement(*DeleteStatement) error           { return nil }
+func (BaseVisitor) VisitCreateIndexStatement(*CreateIndexStatement) error { return nil }
+func (BaseVisitor) VisitCreateViewStatement(*CreateViewStatement) error   { return nil }
+func (BaseVisitor) VisitSelectItem(*SelectItem) error                     { return nil }
+func (BaseVisitor) VisitColumnDefinition(*ColumnDefinition) error         { return nil }
+func (BaseVisitor) VisitAssignment(*Assignment) error                     { return nil }
+func (BaseVisitor) VisitExpression(*Expression) error                     { return nil }
+func (BaseVisitor) VisitTable(*Token) error                               { return nil }
+func (BaseVisitor) VisitIndexColumn(*Token) error                         { return nil }
+
+// Walk traverses node, which is an *Ast, a *Statement or any node within
+// them, calling v for it and each of its descendants in source order.
+// Errors other than SkipChildren and StopWalk end the walk and are
+// returned.
+func Walk(node interface{}, v Visitor) error {
+	err := walk(node, v)
+	if err == StopWalk {
+		return nil
+	}
+	return err
+}
+
+func walk(node interface{}, v Visitor) error {
+	// Visit the node, then its children unless told not to
+	visit := func(err error, children func() error) error {
+		if err == SkipChildren {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return children()
+	}
+
+	switch n := node.(type) {
+	case *Ast:
+		for _, stmt := range n.Statements {
+			if err := walk(stmt, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Statement:
+		switch n.Kind {
+		case SelectKind:
+			return walk(n.SelectStatement, v)
+		case CreateTableKind:
+			return walk(n.CreateTableStatement, v)
+		case InsertKind:
+			return walk(n.InsertStatement, v)
+		case UpdateKind:
+			return walk(n.UpdateStatement, v)
+		case DeleteKind:
+			return walk(n.DeleteStatement, v)
+		case CreateIndexKind:
+			return walk(n.CreateIndexStatement, v)
+		case CreateViewKind:
+			return walk(n.CreateViewStatement, v)
+		}
+		return nil
+	case *SelectStatement:
+		return visit(v.VisitSelectStatement(n), func() error {
+			for _, item := range n.Items {
+				if err := walk(item, v); err != nil {
+					return err
+				}
+			}
+			if n.From != nil {
+				if err := walk(n.From, v); err != nil {
+					return err
+				}
+			}
+			if n.Where != nil {
+				if err := walk(n.Where, v); err != nil {
+					return err
+				}
+			}
+			for _, element := range n.GroupBy {
+				if err := walk(element, v); err != nil {
+					return err
+				}
+			}
+			if n.Having != nil {
+				if err := walk(n.Having, v); err != nil {
+					return err
+				}
+			}
+			for _, term := range n.OrderBy {
+				if err := walk(term.Expression, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case *CreateTableStatement:
+		return visit(v.VisitCreateTableStatement(n), func() error {
+			if err := visit(v.VisitTable(n.Name), noChildren); err != nil {
+				return err
+			}
+			for _, col := range n.Cols {
+				if err := walk(col, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case *InsertStatement:
+		return visit(v.VisitInsertStatement(n), func() error {
+			if err := visit(v.VisitTable(n.Table), noChildren); err != nil {
+				return err
+			}
+			for _, row := range n.Values {
+				for _, value := range row {
+					if err := walk(value, v); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	case *UpdateStatement:
+		return visit(v.VisitUpdateStatement(n), func() error {
+			if err := visit(v.VisitTable(n.Table), noChildren); err != nil {
+				return err
+			}
+			for _, assignment := range n.Set {
+				if err := walk(assignment, v); err != nil {
+					return err
+				}
+			}
+			if n.Where != nil {
+				return walk(n.Where, v)
+			}
+			return nil
+		})
+	case *DeleteStatement:
+		return visit(v.VisitDeleteStatement(n), func() error {
+			if err := visit(v.VisitTable(n.Table), noChildren); err != nil {
+				return err
+			}
+			if n.Where != nil {
+				return walk(n.Where, v)
+			}
+			return nil
+		})
+	case *CreateIndexStatement:
+		return visit(v.VisitCreateIndexStatement(n), func() error {
+			if err := visit(v.VisitTable(n.Table), noChildren); err != nil {
+				return err
+			}
+			for _, col := range n.Cols {
+				if err := visit(v.VisitIndexColumn(col), noChildren); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case *CreateViewStatement:
+		return visit(v.VisitCreateViewStatement(n), func() error {
+			if err := visit(v.VisitTable(n.Name), noChildren); err != nil {
+				return err
+			}
+			return walk(n.Query, v)
+		})
+	case *TableReference:
+		switch n.Kind {
+		case NamedTableKind:
+			return visit(v.VisitTable(n.Name), noChildren)
+		case SubqueryTableKind:
+			return walk(n.Subquery, v)
+		case JoinTableKind:
+			if err := walk(n.Join.Left, v); err != nil {
+				return err
+			}
+			if err := walk(n.Join.Right, v); err != nil {
+				return err
+			}
+			if n.Join.On != nil {
+				return walk(n.Join.On, v)
+			}
+		}
+		return nil
+	case *GroupingElement:
+		exps := n.Expressions
+		if n.Expression != nil {
+			exps = []*Expression{n.Expression}
+		}
+		for _, set := range n.Sets {
+			exps = append(exps, set...)
+		}
+		for _, exp := range exps {
+			if err := walk(exp, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *SelectItem:
+		return visit(v.VisitSelectItem(n), func() error {
+			if n.Expression != nil {
+				return walk(n.Expression, v)
+			}
+			return nil
+		})
+	case *ColumnDefinition:
+		return visit(v.VisitColumnDefinition(n), func() error {
+			if n.Default != nil {
+				return walk(n.Default, v)
+			}
+			return nil
+		})
+	case *Assignment:
+		return visit(v.VisitAssignment(n), func() error {
+			return walk(n.Value, v)
+		})
+	case *Expression:
+		return visit(v.VisitExpression(n), func() error {
+			switch n.Kind {
+			case BinaryKind:
+				if err := walk(n.Binary.A, v); err != nil {
+					return err
+				}
+				return walk(n.Binary.B, v)
+			case SubqueryKind:
+				return walk(n.Subquery, v)
+			case BetweenKind:
+				for _, exp := range []*Expression{n.Between.Expression, n.Between.Low, n.Between.High} {
+					if err := walk(exp, v); err != nil {
+						return err
+					}
+				}
+			case CastKind:
+				return walk(n.Cast.Expression, v)
+			case UnaryKind:
+				return walk(n.Unary.Expression, v)
+			case IsNullKind:
+				return walk(n.IsNull.Expression, v)
+			case InKind:
+				if err := walk(n.In.Expression, v); err != nil {
+					return err
+				}
+				for _, value := range n.In.Values {
+					if err := walk(value, v); err != nil {
+						return err
+					}
+				}
+				if n.In.Subquery != nil {
+					return walk(n.In.Subquery, v)
+				}
+			case LikeKind:
+				if err := walk(n.Like.Expression, v); err != nil {
+					return err
+				}
+				return walk(n.Like.Pattern, v)
+			case CaseKind:
+				exps := []*Expression{}
+				if n.Case.Operand != nil {
+					exps = append(exps, n.Case.Operand)
+				}
+				for _, when := range n.Case.Whens {
+					exps = append(exps, when.Condition, when.Result)
+				}
+				if n.Case.Else != nil {
+					exps = append(exps, n.Case.Else)
+				}
+				for _, exp := range exps {
+					if err := walk(exp, v); err != nil {
+						return err
+					}
+				}
+			case CallKind:
+				for _, arg := range n.Call.Args {
+					if err := walk(arg, v); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	}
+
+	return fmt.Errorf("Cannot walk %T", node)
+}
+
+func noChildren() error {
+	return nil
+}
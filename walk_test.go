@@ -0,0 +1,158 @@
+package gosql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Collects the names of the tables and columns a query refers to
+type identifierCollector struct {
+	BaseVisitor
+	names []string
+}
+
+func (c *identifierCollector) VisitExpression(e *Expression) error {
+	if e.Kind == LiteralKind && e.Literal.Kind == IdentifierKind {
+		c.names = append(c.names, e.Literal.Value)
+	}
+	return nil
+}
+
+func (c *identifierCollector) VisitTable(table *Token) error {
+	c.names = append(c.names, table.Value)
+	return nil
+}
+
+func (c *identifierCollector) VisitColumnDefinition(col *ColumnDefinition) error {
+	c.names = append(c.names, col.Name.Value)
+	return nil
+}
+
+func (c *identifierCollector) VisitIndexColumn(col *Token) error {
+	c.names = append(c.names, col.Value)
+	return nil
+}
+
+func (c *identifierCollector) VisitAssignment(a *Assignment) error {
+	c.names = append(c.names, a.Column.Value)
+	return nil
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		source string
+		names  []string
+	}{
+		{
+			source: "SELECT a, b + 1 AS x, *, 'c' FROM users WHERE (d = 2 OR e) AND NOT f <> 'g' ORDER BY h DESC;",
+			names:  []string{"a", "b", "users", "d", "e", "f", "h"},
+		},
+		{
+			source: "SELECT u.a FROM users u JOIN scores s ON u.id = s.id GROUP BY u.a HAVING u.b = 1;",
+			names:  []string{"a", "users", "scores", "id", "id", "a", "b"},
+		},
+		{
+			source: "SELECT a FROM (SELECT b FROM t) s WHERE c IN (SELECT sum(abs(d)) FROM u);",
+			names:  []string{"a", "b", "t", "c", "d", "u"},
+		},
+		{
+			source: "SELECT CASE a WHEN b THEN c ELSE d END, CASE WHEN e BETWEEN f AND g THEN h IS NULL END;",
+			names:  []string{"a", "b", "c", "d", "e", "f", "g", "h"},
+		},
+		{
+			source: "SELECT a FROM t WHERE b NOT IN (c, d + 1) AND e IN (SELECT f FROM u);",
+			names:  []string{"a", "t", "b", "c", "d", "e", "f", "u"},
+		},
+		{
+			source: "SELECT a FROM t WHERE b LIKE c || '%' OR d NOT LIKE 'e';",
+			names:  []string{"a", "t", "b", "c", "d"},
+		},
+		{
+			source: "CREATE TABLE users (id INT, name TEXT);",
+			names:  []string{"users", "id", "name"},
+		},
+		{
+			source: "CREATE UNIQUE INDEX users_name ON users (name, id);",
+			names:  []string{"users", "name", "id"},
+		},
+		{
+			source: "CREATE OR REPLACE VIEW v AS SELECT a FROM users WHERE b = 1;",
+			names:  []string{"v", "a", "users", "b"},
+		},
+		{
+			source: "INSERT INTO users VALUES (1, a || b);\nSELECT 1;",
+			names:  []string{"users", "a", "b"},
+		},
+		{
+			source: "UPDATE users SET a = b + 1, c = 'd' WHERE e = 5;",
+			names:  []string{"users", "a", "b", "c", "e"},
+		},
+		{
+			source: "DELETE FROM users WHERE a = b;",
+			names:  []string{"users", "a", "b"},
+		},
+	}
+
+	for _, test := range tests {
+		tokens, err := Lex(test.source)
+		assert.Nil(t, err, test.source)
+		ast, err := Parse(tokens)
+		assert.Nil(t, err, test.source)
+
+		c := identifierCollector{}
+		assert.Nil(t, Walk(ast, &c), test.source)
+		assert.Equal(t, test.names, c.names, test.source)
+	}
+}
+
+// Stops at the first WHERE clause, or at the first identifier named stop
+type shortCircuiter struct {
+	identifierCollector
+}
+
+func (s *shortCircuiter) VisitSelectStatement(slct *SelectStatement) error {
+	if slct.Where != nil {
+		return SkipChildren
+	}
+	return nil
+}
+
+func (s *shortCircuiter) VisitExpression(e *Expression) error {
+	if e.Kind == LiteralKind && e.Literal.Value == "stop" {
+		return StopWalk
+	}
+	return s.identifierCollector.VisitExpression(e)
+}
+
+func TestWalk_shortCircuit(t *testing.T) {
+	tokens, err := Lex("SELECT a FROM t WHERE b = 1; SELECT c, stop, d FROM u; SELECT e;")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	s := shortCircuiter{}
+	assert.Nil(t, Walk(ast, &s))
+	assert.Equal(t, []string{"c"}, s.names)
+}
+
+type failingVisitor struct {
+	BaseVisitor
+}
+
+var errVisit = errors.New("visit failed")
+
+func (failingVisitor) VisitTable(*Token) error {
+	return errVisit
+}
+
+func TestWalk_errors(t *testing.T) {
+	tokens, err := Lex("SELECT a FROM t;")
+	assert.Nil(t, err)
+	ast, err := Parse(tokens)
+	assert.Nil(t, err)
+
+	assert.Equal(t, errVisit, Walk(ast, failingVisitor{}))
+	assert.NotNil(t, Walk("not a node", BaseVisitor{}))
+}